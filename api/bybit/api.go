@@ -0,0 +1,335 @@
+package bybit
+
+import (
+	"../../utils/limiter"
+	"../../utils/log"
+	"../../utils/values"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// Order placement is limited to 20 requests per second.
+	reqInterval = 50 * time.Millisecond
+)
+
+func NewBybitApi(key string, secret string) *Config {
+	return &Config{
+		Key:               key,
+		Secret:            secret,
+		RestEndpoint:      "https://api.bybit.com",
+		WebsocketEndpoint: "wss://stream.bybit.com/v5/private",
+		RecvWindow:        time.Second * 5,
+		Timeout:           time.Second * 30,
+		Instruments:       make(map[string]*Instrument),
+		client: &http.Client{
+			Timeout: time.Second * 30,
+		},
+		Limiter: limiter.New(limiter.Config{Name: "bybit", Interval: reqInterval}),
+	}
+}
+
+func (c *Config) Setup() error {
+	instruments := make(map[string]*Instrument)
+	params := url.Values{}
+	params.Set("category", "spot")
+	params.Set("limit", "1000")
+
+	for {
+		r := &InstrumentList{}
+		if err := c.do("GET", "/v5/market/instruments-info", params, nil, false, r); err != nil {
+			return err
+		}
+		for _, i := range r.List {
+			instruments[i.Symbol] = i
+		}
+		if r.NextPageCursor == "" || len(r.List) == 0 {
+			break
+		}
+		params.Set("cursor", r.NextPageCursor)
+	}
+
+	c.Instruments = instruments
+	return nil
+}
+
+// GetServerTime returns the server time in milliseconds.
+func (c *Config) GetServerTime() (int64, error) {
+	r := &ServerTime{}
+	if err := c.do("GET", "/v5/market/time", nil, nil, false, r); err != nil {
+		return 0, err
+	}
+	ns, err := strconv.ParseInt(r.TimeNano, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return ns / int64(time.Millisecond), nil
+}
+
+func (c *Config) GetInstrument(symbol string) *Instrument {
+	if i, ok := c.Instruments[symbol]; ok {
+		return i
+	}
+	return nil
+}
+
+// GetBalances returns the available (not locked) balance of every coin of
+// the unified trading account.
+func (c *Config) GetBalances() (map[string]*values.Float, error) {
+	params := url.Values{}
+	params.Set("accountType", "UNIFIED")
+
+	r := &WalletList{}
+	if err := c.do("GET", "/v5/account/wallet-balance", params, nil, true, r); err != nil {
+		return nil, err
+	}
+
+	balances := make(map[string]*values.Float)
+	for _, w := range r.List {
+		for _, coin := range w.Coin {
+			balances[coin.Coin] = coin.WalletBalance.Sub(&coin.Locked)
+		}
+	}
+	return balances, nil
+}
+
+func (c *Config) GetOpenOrders(symbol string) ([]*Order, error) {
+	orders := make([]*Order, 0)
+	params := url.Values{}
+	params.Set("category", "spot")
+	params.Set("symbol", symbol)
+	params.Set("limit", "50")
+
+	for {
+		r := &OrderList{}
+		if err := c.do("GET", "/v5/order/realtime", params, nil, true, r); err != nil {
+			return nil, err
+		}
+		orders = append(orders, r.List...)
+		if r.NextPageCursor == "" || len(r.List) == 0 {
+			return orders, nil
+		}
+		params.Set("cursor", r.NextPageCursor)
+	}
+}
+
+// GetOrder returns the order with the given id, including closed orders.
+func (c *Config) GetOrder(symbol string, orderId string) (*Order, error) {
+	params := url.Values{}
+	params.Set("orderId", orderId)
+
+	o, err := c.findOrder(symbol, params)
+	if err == nil && o == nil {
+		return nil, errors.New(fmt.Sprintf("order not found: %s", orderId))
+	}
+	return o, err
+}
+
+// GetOrderByLinkId returns the order with the given order link id, including
+// closed orders. Nil is returned if there is none.
+func (c *Config) GetOrderByLinkId(symbol string, linkId string) (*Order, error) {
+	params := url.Values{}
+	params.Set("orderLinkId", linkId)
+
+	return c.findOrder(symbol, params)
+}
+
+// findOrder returns the first order matching the given filter. Closed orders
+// are taken from the order history, since open orders aren't part of it
+// right away.
+func (c *Config) findOrder(symbol string, params url.Values) (*Order, error) {
+	params.Set("category", "spot")
+	params.Set("symbol", symbol)
+
+	for _, resource := range []string{"/v5/order/realtime", "/v5/order/history"} {
+		r := &OrderList{}
+		if err := c.do("GET", resource, params, nil, true, r); err != nil {
+			return nil, err
+		}
+		if len(r.List) > 0 {
+			return r.List[0], nil
+		}
+	}
+	return nil, nil
+}
+
+// GetExecutions returns all executions of the given symbol since the given
+// time. Bybit limits the time range to seven days.
+func (c *Config) GetExecutions(symbol string, since time.Time) ([]*Execution, error) {
+	executions := make([]*Execution, 0)
+	params := url.Values{}
+	params.Set("category", "spot")
+	params.Set("symbol", symbol)
+	params.Set("startTime", strconv.FormatInt(since.UnixNano()/int64(time.Millisecond), 10))
+	params.Set("limit", "100")
+
+	for {
+		r := &ExecutionList{}
+		if err := c.do("GET", "/v5/execution/list", params, nil, true, r); err != nil {
+			return nil, err
+		}
+		executions = append(executions, r.List...)
+		if r.NextPageCursor == "" || len(r.List) == 0 {
+			return executions, nil
+		}
+		params.Set("cursor", r.NextPageCursor)
+	}
+}
+
+// PlaceOrder places a good till canceled limit order. A random order link id
+// gets used if linkId is empty.
+func (c *Config) PlaceOrder(symbol string, side string, price *values.Float, qty *values.Float, linkId string) (string, error) {
+	i := c.GetInstrument(symbol)
+	if i == nil {
+		return "", errors.New(fmt.Sprintf("unknown symbol: %s", symbol))
+	}
+
+	if linkId == "" {
+		b := make([]byte, 16)
+		_, _ = rand.Read(b)
+		linkId = hex.EncodeToString(b)
+	}
+
+	if side == "sell" {
+		side = "Sell"
+	} else {
+		side = "Buy"
+	}
+
+	body := map[string]string{
+		"category":    "spot",
+		"symbol":      symbol,
+		"side":        side,
+		"orderType":   "Limit",
+		"timeInForce": "GTC",
+		"orderLinkId": linkId,
+		"price":       price.ToPrecision(precision(&i.PriceFilter.TickSize)),
+		"qty":         qty.ToPrecision(precision(&i.LotSizeFilter.BasePrecision)),
+	}
+
+	r := &OrderResult{}
+	if err := c.do("POST", "/v5/order/create", nil, body, true, r); err != nil {
+		return "", err
+	}
+	return r.OrderId, nil
+}
+
+func (c *Config) CancelOrder(symbol string, id string) error {
+	body := map[string]string{
+		"category": "spot",
+		"symbol":   symbol,
+		"orderId":  id,
+	}
+	return c.do("POST", "/v5/order/cancel", nil, body, true, nil)
+}
+
+// SetTimeOffset sets the offset of the local clock to the server time in
+// milliseconds, which gets subtracted from the timestamp of signed requests.
+func (c *Config) SetTimeOffset(offset int64) {
+	atomic.StoreInt64(&c.offset, offset)
+}
+
+// now returns the local time corrected by the time offset.
+func (c *Config) now() time.Time {
+	return time.Now().Add(-time.Duration(atomic.LoadInt64(&c.offset)) * time.Millisecond)
+}
+
+func (c *Config) do(method string, resource string, params url.Values, payload interface{}, authNeeded bool, result interface{}) error {
+	if err := c.Limiter.Wait(context.Background(), 1, false); err != nil {
+		return err
+	}
+
+	query := params.Encode()
+	if query != "" {
+		resource = resource + "?" + query
+	}
+
+	body := ""
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = string(b)
+	}
+
+	req, err := http.NewRequest(method, c.RestEndpoint+resource, bytes.NewBufferString(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Content-Type", "application/json")
+
+	if authNeeded {
+		if len(c.Key) == 0 || len(c.Secret) == 0 {
+			return errors.New("You need to set API Key and API Secret to call this method")
+		}
+
+		ts := strconv.FormatInt(c.now().UnixNano()/int64(time.Millisecond), 10)
+		window := strconv.FormatInt(int64(c.RecvWindow/time.Millisecond), 10)
+		signed := query
+		if method == "POST" {
+			signed = body
+		}
+
+		req.Header.Add("X-BAPI-API-KEY", c.Key)
+		req.Header.Add("X-BAPI-TIMESTAMP", ts)
+		req.Header.Add("X-BAPI-RECV-WINDOW", window)
+		req.Header.Add("X-BAPI-SIGN", c.sign(ts+c.Key+window+signed))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	c.Limiter.Observe(resp)
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	r := &Response{}
+	if err := json.Unmarshal(b, r); err != nil {
+		log.Debug(string(b))
+		return errors.New(fmt.Sprintf("%s: %s", resp.Status, string(b)))
+	}
+	if r.RetCode != 0 {
+		return errors.New(fmt.Sprintf("%d: %s", r.RetCode, r.RetMsg))
+	}
+	if result == nil || len(r.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(r.Result, result)
+}
+
+// sign returns the hex encoded HMAC-SHA256 of the given payload.
+func (c *Config) sign(payload string) string {
+	mac := hmac.New(sha256.New, []byte(c.Secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// precision returns the number of decimals of a precision such as "0.0001".
+func precision(inc *values.Float) int {
+	s := inc.Text('f', -1)
+	if i := strings.Index(s, "."); i >= 0 {
+		return len(s) - i - 1
+	}
+	return 0
+}
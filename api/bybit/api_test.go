@@ -0,0 +1,290 @@
+package bybit
+
+import (
+	"../../utils/values"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockServer is a stand-in of the Bybit v5 rest and websocket api. Every
+// private request has to carry a valid signature.
+type mockServer struct {
+	*httptest.Server
+	t *testing.T
+
+	requests []string
+	mx       sync.Mutex
+}
+
+func newMockServer(t *testing.T, routes map[string]http.HandlerFunc) (*mockServer, *Config) {
+	m := &mockServer{t: t}
+
+	c := NewBybitApi("key", "secret")
+	// The server keeps its own copy of the credentials
+	signer := &Config{Secret: c.Secret}
+
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.mx.Lock()
+		m.requests = append(m.requests, r.Method+" "+r.URL.RequestURI())
+		m.mx.Unlock()
+
+		if !strings.HasPrefix(r.URL.Path, "/v5/market") && !strings.HasPrefix(r.URL.Path, "/v5/private") {
+			b, _ := ioutil.ReadAll(r.Body)
+			ts := r.Header.Get("X-BAPI-TIMESTAMP")
+			if ms, err := strconv.ParseInt(ts, 10, 64); err != nil || time.Since(time.Unix(0, ms*int64(time.Millisecond))) > time.Minute {
+				t.Errorf("%s: invalid timestamp %q", r.URL.Path, ts)
+			}
+			// GET requests sign the query, POST requests the body
+			signed := r.URL.RawQuery
+			if r.Method == "POST" {
+				signed = string(b)
+			}
+			window := r.Header.Get("X-BAPI-RECV-WINDOW")
+			if window != "5000" || r.Header.Get("X-BAPI-API-KEY") != "key" || r.Header.Get("X-BAPI-SIGN") != signer.sign(ts+"key"+window+signed) {
+				fmt.Fprint(w, `{"retCode":10004,"retMsg":"error sign! origin_string[...]","result":{},"time":1672211918471}`)
+				return
+			}
+			r.Body = ioutil.NopCloser(strings.NewReader(string(b)))
+		}
+
+		h, ok := routes[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"retCode":10001,"retMsg":"not found","result":{}}`)
+			return
+		}
+		h(w, r)
+	}))
+
+	c.RestEndpoint = m.URL
+	c.WebsocketEndpoint = "ws" + strings.TrimPrefix(m.URL, "http") + "/v5/private"
+	c.Instruments["DOGEBTC"] = &Instrument{
+		Symbol:    "DOGEBTC",
+		BaseCoin:  "DOGE",
+		QuoteCoin: "BTC",
+		LotSizeFilter: LotSizeFilter{
+			BasePrecision: *values.NewFloatFromString("0.1"),
+			MinOrderAmt:   *values.NewFloatFromString("0.00001"),
+		},
+		PriceFilter: PriceFilter{TickSize: *values.NewFloatFromString("0.00000001")},
+	}
+
+	return m, c
+}
+
+func (m *mockServer) Requests() []string {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	return append([]string{}, m.requests...)
+}
+
+func TestSetup(t *testing.T) {
+	m, c := newMockServer(t, map[string]http.HandlerFunc{
+		"/v5/market/instruments-info": func(w http.ResponseWriter, r *http.Request) {
+			symbol, next := "DOGEBTC", "c1"
+			if r.URL.Query().Get("cursor") == "c1" {
+				symbol, next = "ETHBTC", ""
+			}
+			fmt.Fprintf(w, `{"retCode":0,"retMsg":"OK","result":{"category":"spot","list":[{"symbol":"%s","baseCoin":"DOGE","quoteCoin":"BTC","status":"Trading","lotSizeFilter":{"basePrecision":"0.1","quotePrecision":"0.00000001","minOrderQty":"1","minOrderAmt":"0.00001"},"priceFilter":{"tickSize":"0.00000001"}}],"nextPageCursor":"%s"}}`, symbol, next)
+		},
+	})
+	defer m.Close()
+	c.Instruments = make(map[string]*Instrument)
+
+	if err := c.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	i := c.GetInstrument("DOGEBTC")
+	if i == nil || c.GetInstrument("ETHBTC") == nil {
+		t.Fatal("instrument missing")
+	}
+	if precision(&i.LotSizeFilter.BasePrecision) != 1 || precision(&i.PriceFilter.TickSize) != 8 || i.LotSizeFilter.MinOrderAmt.ToString() != "0.00001000" {
+		t.Errorf("instrument = %s %s %s", i.LotSizeFilter.BasePrecision.ToString(), i.PriceFilter.TickSize.ToString(), i.LotSizeFilter.MinOrderAmt.ToString())
+	}
+	if r := m.Requests(); len(r) != 2 || !strings.Contains(r[1], "cursor=c1") {
+		t.Errorf("requests = %v", r)
+	}
+}
+
+func TestPlaceOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		side     string
+		response string
+		id       string
+		err      string
+	}{
+		{"buy", "buy", `{"retCode":0,"retMsg":"OK","result":{"orderId":"1321003749386327552","orderLinkId":"abc"},"time":1672211918471}`, "1321003749386327552", ""},
+		{"sell", "sell", `{"retCode":0,"retMsg":"OK","result":{"orderId":"1321003749386327552","orderLinkId":"abc"},"time":1672211918471}`, "1321003749386327552", ""},
+		{"rejected", "buy", `{"retCode":170131,"retMsg":"Insufficient balance.","result":{},"time":1672211918471}`, "", "170131: Insufficient balance."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]string
+			m, c := newMockServer(t, map[string]http.HandlerFunc{
+				"/v5/order/create": func(w http.ResponseWriter, r *http.Request) {
+					_ = json.NewDecoder(r.Body).Decode(&body)
+					fmt.Fprint(w, tt.response)
+				},
+			})
+			defer m.Close()
+
+			id, err := c.PlaceOrder("DOGEBTC", tt.side, values.NewFloatFromString("0.00000301"), values.NewFloatFromString("30.15"), "abc")
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("error = %v, want %s", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != tt.id {
+				t.Errorf("id = %s, want %s", id, tt.id)
+			}
+			side := "Buy"
+			if tt.side == "sell" {
+				side = "Sell"
+			}
+			want := map[string]string{"category": "spot", "symbol": "DOGEBTC", "side": side, "orderType": "Limit", "timeInForce": "GTC", "orderLinkId": "abc", "price": "0.00000301", "qty": "30.1"}
+			for k, v := range want {
+				if body[k] != v {
+					t.Errorf("%s = %q, want %q", k, body[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestGetOpenOrdersPages(t *testing.T) {
+	m, c := newMockServer(t, map[string]http.HandlerFunc{
+		"/v5/order/realtime": func(w http.ResponseWriter, r *http.Request) {
+			id, next := "1", "c1"
+			if r.URL.Query().Get("cursor") == "c1" {
+				id, next = "2", ""
+			}
+			fmt.Fprintf(w, `{"retCode":0,"retMsg":"OK","result":{"category":"spot","list":[{"orderId":"%s","orderLinkId":"abc","symbol":"DOGEBTC","side":"Buy","orderType":"Limit","price":"0.00000301","qty":"30","cumExecQty":"0","leavesQty":"30","orderStatus":"New","createdTime":"1672211918471"}],"nextPageCursor":"%s"}}`, id, next)
+		},
+	})
+	defer m.Close()
+
+	orders, err := c.GetOpenOrders("DOGEBTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 2 || orders[1].OrderId != "2" || orders[1].OrderLinkId != "abc" || orders[1].Qty.ToString() != "30.00000000" {
+		t.Fatalf("orders = %d", len(orders))
+	}
+	if r := m.Requests(); len(r) != 2 || !strings.Contains(r[0], "category=spot") || !strings.Contains(r[1], "cursor=c1") {
+		t.Errorf("requests = %v", r)
+	}
+}
+
+func TestGetOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		realtime string
+		history  string
+		status   string
+		requests int
+	}{
+		{"open", `[{"orderId":"1","orderStatus":"PartiallyFilled","cumExecQty":"10"}]`, `[]`, "PartiallyFilled", 1},
+		{"closed", `[]`, `[{"orderId":"1","orderStatus":"Cancelled","cumExecQty":"10"}]`, "Cancelled", 2},
+		{"unknown", `[]`, `[]`, "", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := func(l string) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Query().Get("orderId") != "1" {
+						t.Errorf("query = %s", r.URL.RawQuery)
+					}
+					fmt.Fprintf(w, `{"retCode":0,"retMsg":"OK","result":{"category":"spot","list":%s,"nextPageCursor":""}}`, l)
+				}
+			}
+			m, c := newMockServer(t, map[string]http.HandlerFunc{
+				"/v5/order/realtime": list(tt.realtime),
+				"/v5/order/history":  list(tt.history),
+			})
+			defer m.Close()
+
+			o, err := c.GetOrder("DOGEBTC", "1")
+			if tt.status == "" {
+				if err == nil {
+					t.Error("an unknown order got returned")
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if o.OrderStatus != tt.status || o.CumExecQty.ToString() != "10.00000000" {
+				t.Errorf("order = %s %s", o.OrderStatus, o.CumExecQty.ToString())
+			}
+			if r := m.Requests(); len(r) != tt.requests {
+				t.Errorf("requests = %v", r)
+			}
+		})
+	}
+}
+
+func TestInvalidSignature(t *testing.T) {
+	m, c := newMockServer(t, map[string]http.HandlerFunc{
+		"/v5/account/wallet-balance": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"retCode":0,"retMsg":"OK","result":{"list":[{"accountType":"UNIFIED","coin":[{"coin":"BTC","walletBalance":"1.5","locked":"0.3"}]}]}}`)
+		},
+	})
+	defer m.Close()
+
+	balances, err := c.GetBalances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balances["BTC"].ToString() != "1.20000000" {
+		t.Errorf("balance = %s", balances["BTC"].ToString())
+	}
+
+	c.Secret = "wrong"
+	if _, err := c.GetBalances(); err == nil || !strings.HasPrefix(err.Error(), "10004: error sign!") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestGetServerTime(t *testing.T) {
+	var ts string
+	m, c := newMockServer(t, map[string]http.HandlerFunc{
+		"/v5/market/time": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"retCode":0,"retMsg":"OK","result":{"timeSecond":"1688639403","timeNano":"1688639403423213947"},"time":1688639403423}`)
+		},
+		"/v5/account/wallet-balance": func(w http.ResponseWriter, r *http.Request) {
+			ts = r.Header.Get("X-BAPI-TIMESTAMP")
+			fmt.Fprint(w, `{"retCode":0,"retMsg":"OK","result":{"list":[]}}`)
+		},
+	})
+	defer m.Close()
+
+	ms, err := c.GetServerTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ms != 1688639403423 {
+		t.Errorf("server time = %d", ms)
+	}
+
+	// The local clock is 30 seconds ahead
+	c.SetTimeOffset(30000)
+	if _, err := c.GetBalances(); err != nil {
+		t.Fatal(err)
+	}
+	sent, _ := strconv.ParseInt(ts, 10, 64)
+	if d := time.Since(time.Unix(0, sent*int64(time.Millisecond))); d < 29*time.Second || d > 31*time.Second {
+		t.Errorf("timestamp is %s behind, want 30s", d)
+	}
+}
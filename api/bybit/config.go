@@ -0,0 +1,139 @@
+package bybit
+
+import (
+	"../../utils/limiter"
+	"../../utils/values"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+type Config struct {
+	Key    string `json:"key"`
+	Secret string `json:"secret"`
+
+	RestEndpoint      string        `json:"rest-endpoint"`
+	WebsocketEndpoint string        `json:"wss-endpoint"`
+	RecvWindow        time.Duration `json:"recv-window"`
+	Timeout           time.Duration `json:"timeout"`
+
+	Instruments map[string]*Instrument
+
+	client  *http.Client
+	Limiter *limiter.Limiter
+	// Offset of the local clock in milliseconds, see SetTimeOffset
+	offset int64
+}
+
+type Response struct {
+	RetCode int             `json:"retCode"`
+	RetMsg  string          `json:"retMsg"`
+	Result  json.RawMessage `json:"result"`
+}
+
+type LotSizeFilter struct {
+	BasePrecision  values.Float `json:"basePrecision"`
+	QuotePrecision values.Float `json:"quotePrecision"`
+	MinOrderQty    values.Float `json:"minOrderQty"`
+	MinOrderAmt    values.Float `json:"minOrderAmt"`
+}
+
+type PriceFilter struct {
+	TickSize values.Float `json:"tickSize"`
+}
+
+type Instrument struct {
+	Symbol        string        `json:"symbol"`
+	BaseCoin      string        `json:"baseCoin"`
+	QuoteCoin     string        `json:"quoteCoin"`
+	Status        string        `json:"status"`
+	LotSizeFilter LotSizeFilter `json:"lotSizeFilter"`
+	PriceFilter   PriceFilter   `json:"priceFilter"`
+}
+
+type InstrumentList struct {
+	List           []*Instrument `json:"list"`
+	NextPageCursor string        `json:"nextPageCursor"`
+}
+
+type Coin struct {
+	Coin          string       `json:"coin"`
+	WalletBalance values.Float `json:"walletBalance"`
+	Locked        values.Float `json:"locked"`
+}
+
+type Wallet struct {
+	AccountType string  `json:"accountType"`
+	Coin        []*Coin `json:"coin"`
+}
+
+type WalletList struct {
+	List []*Wallet `json:"list"`
+}
+
+// Order is used by the REST api as well as by the "order" websocket topic.
+// OrderStatus is one of "New", "PartiallyFilled", "Filled", "Cancelled",
+// "PartiallyFilledCanceled" or "Rejected".
+type Order struct {
+	Category    string       `json:"category"`
+	OrderId     string       `json:"orderId"`
+	OrderLinkId string       `json:"orderLinkId"`
+	Symbol      string       `json:"symbol"`
+	Side        string       `json:"side"`
+	OrderType   string       `json:"orderType"`
+	Price       values.Float `json:"price"`
+	Qty         values.Float `json:"qty"`
+	CumExecQty  values.Float `json:"cumExecQty"`
+	LeavesQty   values.Float `json:"leavesQty"`
+	OrderStatus string       `json:"orderStatus"`
+	CreatedTime string       `json:"createdTime"`
+	UpdatedTime string       `json:"updatedTime"`
+}
+
+type OrderList struct {
+	List           []*Order `json:"list"`
+	NextPageCursor string   `json:"nextPageCursor"`
+}
+
+type OrderResult struct {
+	OrderId     string `json:"orderId"`
+	OrderLinkId string `json:"orderLinkId"`
+}
+
+// Execution is a single trade of the "execution" websocket topic.
+type Execution struct {
+	Category  string       `json:"category"`
+	Symbol    string       `json:"symbol"`
+	OrderId   string       `json:"orderId"`
+	ExecId    string       `json:"execId"`
+	Side      string       `json:"side"`
+	ExecPrice values.Float `json:"execPrice"`
+	ExecQty   values.Float `json:"execQty"`
+	ExecFee   values.Float `json:"execFee"`
+	LeavesQty values.Float `json:"leavesQty"`
+	ExecTime  string       `json:"execTime"`
+}
+
+type ExecutionList struct {
+	List           []*Execution `json:"list"`
+	NextPageCursor string       `json:"nextPageCursor"`
+}
+
+// AccountUpd is a private websocket update of the "order" or "execution" topic.
+type AccountUpd struct {
+	Orders     []*Order
+	Executions []*Execution
+}
+
+type wsMessage struct {
+	Op      string          `json:"op"`
+	Success *bool           `json:"success"`
+	RetMsg  string          `json:"ret_msg"`
+	Topic   string          `json:"topic"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type ServerTime struct {
+	TimeSecond string `json:"timeSecond"`
+	TimeNano   string `json:"timeNano"`
+}
@@ -0,0 +1,129 @@
+package bybit
+
+import (
+	"../../utils/log"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"strconv"
+	"time"
+)
+
+// Bybit recommends a ping every 20 seconds to keep the connection alive.
+const (
+	wsTimeout      = 30 * time.Second
+	wsPingInterval = 20 * time.Second
+)
+
+// SubscribeAccount authenticates and subscribes to the private "order" and
+// "execution" topics. It blocks until the connection fails or stopCh
+// receives a value.
+func (c *Config) SubscribeAccount(updatesCh chan<- AccountUpd, stopCh <-chan bool) error {
+	conn, _, err := websocket.DefaultDialer.Dial(c.WebsocketEndpoint, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := c.auth(conn); err != nil {
+		return err
+	}
+
+	err = conn.WriteJSON(map[string]interface{}{
+		"op":   "subscribe",
+		"args": []string{"order", "execution"},
+	})
+	if err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		for {
+			_ = conn.SetReadDeadline(time.Now().Add(wsTimeout))
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				errCh <- err
+				return
+			}
+			if err := handleMessage(message, updatesCh); err != nil {
+				log.Error(err)
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return nil
+		case err := <-errCh:
+			return err
+		case <-ticker.C:
+			if err := conn.WriteJSON(map[string]string{"op": "ping"}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// auth authenticates the connection and waits for the confirmation.
+func (c *Config) auth(conn *websocket.Conn) error {
+	expires := strconv.FormatInt(c.now().Add(10*time.Second).UnixNano()/int64(time.Millisecond), 10)
+	err := conn.WriteJSON(map[string]interface{}{
+		"op":   "auth",
+		"args": []string{c.Key, expires, c.sign("GET/realtime" + expires)},
+	})
+	if err != nil {
+		return err
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(wsTimeout))
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		return err
+	}
+
+	msg := &wsMessage{}
+	if err := json.Unmarshal(message, msg); err != nil {
+		return err
+	}
+	if msg.Op != "auth" || msg.Success == nil || !*msg.Success {
+		return errors.New(fmt.Sprintf("websocket authentication failed: %s", msg.RetMsg))
+	}
+	return nil
+}
+
+func handleMessage(message []byte, updatesCh chan<- AccountUpd) error {
+	msg := &wsMessage{}
+	if err := json.Unmarshal(message, msg); err != nil {
+		return err
+	}
+	if msg.Success != nil && !*msg.Success {
+		return errors.New(fmt.Sprintf("websocket %s failed: %s", msg.Op, msg.RetMsg))
+	}
+
+	upd := AccountUpd{
+		Orders:     make([]*Order, 0),
+		Executions: make([]*Execution, 0),
+	}
+	switch msg.Topic {
+	case "order":
+		if err := json.Unmarshal(msg.Data, &upd.Orders); err != nil {
+			return err
+		}
+	case "execution":
+		if err := json.Unmarshal(msg.Data, &upd.Executions); err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+
+	updatesCh <- upd
+	return nil
+}
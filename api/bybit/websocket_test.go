@@ -0,0 +1,164 @@
+package bybit
+
+import (
+	"github.com/gorilla/websocket"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Frames as pushed by the Bybit v5 "order" and "execution" topics
+const (
+	frameNew       = `{"id":"5923240c6880ab-c59f-420b-9adb-3639adc9dd90","topic":"order","creationTime":1672364262474,"data":[{"category":"spot","symbol":"DOGEBTC","orderId":"1321003749386327552","orderLinkId":"abc","side":"Buy","orderType":"Limit","price":"0.00000301","qty":"30","cumExecQty":"0","leavesQty":"30","orderStatus":"New","createdTime":"1672364262444","updatedTime":"1672364262457"}]}`
+	frameFilled    = `{"id":"5923240c6880ab-c59f-420b-9adb-3639adc9dd91","topic":"order","creationTime":1672364263474,"data":[{"category":"spot","symbol":"DOGEBTC","orderId":"1321003749386327552","orderLinkId":"abc","side":"Buy","orderType":"Limit","price":"0.00000301","qty":"30","cumExecQty":"30","leavesQty":"0","orderStatus":"Filled","createdTime":"1672364262444","updatedTime":"1672364263457"}]}`
+	frameExecution = `{"id":"592324803b2785-26fa-4214-9963-bdd4727f07be","topic":"execution","creationTime":1672364174455,"data":[{"category":"spot","symbol":"DOGEBTC","orderId":"1321003749386327552","execId":"e0cbe81d-0f18-5866-9415-cf319b5dab3b","side":"Buy","execPrice":"0.00000301","execQty":"30","execFee":"0.03","leavesQty":"0","execTime":"1672364174443"}]}`
+	frameAuth      = `{"success":true,"ret_msg":"","op":"auth","conn_id":"cejreaspqfh3sjdnldmg-p"}`
+	frameSubscribe = `{"success":true,"ret_msg":"","op":"subscribe","conn_id":"cejreaspqfh3sjdnldmg-p"}`
+)
+
+func TestHandleMessage(t *testing.T) {
+	tests := []struct {
+		name       string
+		message    string
+		orders     int
+		executions int
+		err        bool
+	}{
+		{"new", frameNew, 1, 0, false},
+		{"filled", frameFilled, 1, 0, false},
+		{"execution", frameExecution, 0, 1, false},
+		{"subscribed", frameSubscribe, 0, 0, false},
+		{"pong", `{"success":true,"ret_msg":"pong","conn_id":"0970e817","op":"ping"}`, 0, 0, false},
+		{"failed", `{"success":false,"ret_msg":"Invalid topic","op":"subscribe","conn_id":"cejreaspqfh3sjdnldmg-p"}`, 0, 0, true},
+		{"other topic", `{"topic":"wallet","data":[{"accountType":"UNIFIED"}]}`, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updatesCh := make(chan AccountUpd, 1)
+			err := handleMessage([]byte(tt.message), updatesCh)
+			if (err != nil) != tt.err {
+				t.Fatalf("error = %v", err)
+			}
+
+			orders, executions := 0, 0
+			select {
+			case upd := <-updatesCh:
+				orders, executions = len(upd.Orders), len(upd.Executions)
+				for _, o := range upd.Orders {
+					if o.OrderId != "1321003749386327552" || o.OrderLinkId != "abc" || o.Price.ToString() != "0.00000301" {
+						t.Errorf("order = %s %s %s", o.OrderId, o.OrderLinkId, o.Price.ToString())
+					}
+				}
+				for _, x := range upd.Executions {
+					if x.OrderId != "1321003749386327552" || x.ExecQty.ToString() != "30.00000000" {
+						t.Errorf("execution = %s %s", x.OrderId, x.ExecQty.ToString())
+					}
+				}
+			default:
+			}
+			if orders != tt.orders || executions != tt.executions {
+				t.Errorf("orders = %d, executions = %d, want %d, %d", orders, executions, tt.orders, tt.executions)
+			}
+		})
+	}
+}
+
+// wsRoute accepts the authentication if it's signed with the given secret
+// and pushes the given frames after the subscription.
+func wsRoute(t *testing.T, frames ...string) http.HandlerFunc {
+	signer := &Config{Secret: "secret"}
+	upgrader := websocket.Upgrader{}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		auth := struct {
+			Op   string   `json:"op"`
+			Args []string `json:"args"`
+		}{}
+		if err := conn.ReadJSON(&auth); err != nil || auth.Op != "auth" || len(auth.Args) != 3 {
+			t.Errorf("auth = %+v, %v", auth, err)
+			return
+		}
+		if auth.Args[0] != "key" || auth.Args[2] != signer.sign("GET/realtime"+auth.Args[1]) {
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"success":false,"ret_msg":"Params Error","op":"auth","conn_id":"cejreaspqfh3sjdnldmg-p"}`))
+			return
+		}
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(frameAuth))
+
+		sub := struct {
+			Op   string   `json:"op"`
+			Args []string `json:"args"`
+		}{}
+		if err := conn.ReadJSON(&sub); err != nil || sub.Op != "subscribe" || len(sub.Args) != 2 || sub.Args[0] != "order" || sub.Args[1] != "execution" {
+			t.Errorf("subscription = %+v, %v", sub, err)
+			return
+		}
+
+		for _, f := range frames {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(f)); err != nil {
+				return
+			}
+		}
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func TestSubscribeAccount(t *testing.T) {
+	m, c := newMockServer(t, map[string]http.HandlerFunc{
+		"/v5/private": wsRoute(t, frameSubscribe, frameNew, frameExecution, frameFilled),
+	})
+	defer m.Close()
+
+	updatesCh := make(chan AccountUpd, 8)
+	stopCh := make(chan bool)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.SubscribeAccount(updatesCh, stopCh)
+	}()
+
+	want := []struct {
+		orders     int
+		executions int
+	}{{1, 0}, {0, 1}, {1, 0}}
+	for i, w := range want {
+		select {
+		case upd := <-updatesCh:
+			if len(upd.Orders) != w.orders || len(upd.Executions) != w.executions {
+				t.Errorf("update %d: orders = %d, executions = %d, want %d, %d", i, len(upd.Orders), len(upd.Executions), w.orders, w.executions)
+			}
+		case err := <-errCh:
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("update %d not received", i)
+		}
+	}
+
+	stopCh <- true
+	if err := <-errCh; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSubscribeAccountAuthFailed(t *testing.T) {
+	m, c := newMockServer(t, map[string]http.HandlerFunc{
+		"/v5/private": wsRoute(t),
+	})
+	defer m.Close()
+	c.Secret = "wrong"
+
+	err := c.SubscribeAccount(make(chan AccountUpd, 1), make(chan bool))
+	if err == nil || err.Error() != "websocket authentication failed: Params Error" {
+		t.Errorf("error = %v", err)
+	}
+}
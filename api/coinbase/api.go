@@ -0,0 +1,300 @@
+package coinbase
+
+import (
+	"../../utils/limiter"
+	"../../utils/log"
+	"../../utils/values"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// Advanced Trade allows 30 private requests per second.
+	reqInterval = 50 * time.Millisecond
+)
+
+const brokerage = "/api/v3/brokerage"
+
+func NewCoinbaseApi(key string, secret string) *Config {
+	return &Config{
+		Key:               key,
+		Secret:            secret,
+		RestEndpoint:      "https://api.coinbase.com",
+		WebsocketEndpoint: "wss://advanced-trade-ws-user.coinbase.com",
+		Timeout:           time.Second * 30,
+		Products:          make(map[string]*Product),
+		client: &http.Client{
+			Timeout: time.Second * 30,
+		},
+		Limiter: limiter.New(limiter.Config{Name: "coinbase", Interval: reqInterval}),
+	}
+}
+
+func (c *Config) Setup() error {
+	params := url.Values{}
+	params.Set("product_type", "SPOT")
+
+	r := &ProductList{}
+	if err := c.do("GET", "/products", params, nil, r); err != nil {
+		return err
+	}
+
+	products := make(map[string]*Product)
+	for _, p := range r.Products {
+		products[p.ProductId] = p
+	}
+	c.Products = products
+	return nil
+}
+
+// GetServerTime returns the server time in milliseconds.
+func (c *Config) GetServerTime() (int64, error) {
+	r := &ServerTime{}
+	if err := c.do("GET", "/time", nil, nil, r); err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(r.EpochMillis, 10, 64)
+}
+
+func (c *Config) GetProduct(id string) *Product {
+	if p, ok := c.Products[id]; ok {
+		return p
+	}
+	return nil
+}
+
+// GetBalances returns the available balance of every account.
+func (c *Config) GetBalances() (map[string]*values.Float, error) {
+	balances := make(map[string]*values.Float)
+	params := url.Values{}
+	params.Set("limit", "250")
+
+	for {
+		r := &AccountList{}
+		if err := c.do("GET", "/accounts", params, nil, r); err != nil {
+			return nil, err
+		}
+		for _, a := range r.Accounts {
+			balances[a.Currency] = values.NewFloat(&a.AvailableBalance.Value.Float)
+		}
+		if !r.HasNext {
+			return balances, nil
+		}
+		params.Set("cursor", r.Cursor)
+	}
+}
+
+func (c *Config) GetOpenOrders(productId string) ([]*Order, error) {
+	orders := make([]*Order, 0)
+	params := url.Values{}
+	params.Set("order_status", "OPEN")
+	params.Set("product_ids", productId)
+
+	for {
+		r := &OrderList{}
+		if err := c.do("GET", "/orders/historical/batch", params, nil, r); err != nil {
+			return nil, err
+		}
+		orders = append(orders, r.Orders...)
+		if !r.HasNext {
+			return orders, nil
+		}
+		params.Set("cursor", r.Cursor)
+	}
+}
+
+// GetOrder returns the order with the given id, including closed orders.
+func (c *Config) GetOrder(id string) (*Order, error) {
+	r := &OrderResponse{}
+	if err := c.do("GET", "/orders/historical/"+id, nil, nil, r); err != nil {
+		return nil, err
+	}
+	if r.Order == nil {
+		return nil, errors.New(fmt.Sprintf("order not found: %s", id))
+	}
+	return r.Order, nil
+}
+
+// GetFills returns all fills of the given product since the given time.
+func (c *Config) GetFills(productId string, since time.Time) ([]*Fill, error) {
+	fills := make([]*Fill, 0)
+	params := url.Values{}
+	params.Set("product_ids", productId)
+	params.Set("start_sequence_timestamp", since.UTC().Format(time.RFC3339))
+	params.Set("limit", "250")
+
+	for {
+		r := &FillList{}
+		if err := c.do("GET", "/orders/historical/fills", params, nil, r); err != nil {
+			return nil, err
+		}
+		fills = append(fills, r.Fills...)
+		if r.Cursor == "" || len(r.Fills) == 0 {
+			return fills, nil
+		}
+		params.Set("cursor", r.Cursor)
+	}
+}
+
+// PlaceOrder places a good till canceled limit order. Price and size are
+// rounded to the product increments. A random client order id gets used if
+// clientOrderId is empty.
+func (c *Config) PlaceOrder(productId string, side string, price *values.Float, size *values.Float, clientOrderId string) (string, error) {
+	p := c.GetProduct(productId)
+	if p == nil {
+		return "", errors.New(fmt.Sprintf("unknown product: %s", productId))
+	}
+
+	tick := &p.PriceIncrement
+	if tick.Sign() == 0 {
+		tick = &p.QuoteIncrement
+	}
+
+	if clientOrderId == "" {
+		b := make([]byte, 16)
+		_, _ = rand.Read(b)
+		clientOrderId = hex.EncodeToString(b)
+	}
+
+	req := &CreateOrderRequest{
+		ClientOrderId: clientOrderId,
+		ProductId:     productId,
+		Side:          strings.ToUpper(side),
+		OrderConfiguration: OrderConfiguration{
+			LimitGtc: &LimitGtc{
+				BaseSize:   size.ToPrecision(precision(&p.BaseIncrement)),
+				LimitPrice: price.ToPrecision(precision(tick)),
+			},
+		},
+	}
+
+	r := &CreateOrderResponse{}
+	if err := c.do("POST", "/orders", nil, req, r); err != nil {
+		return "", err
+	}
+	if !r.Success {
+		reason := r.ErrorResponse.Message
+		if reason == "" {
+			reason = r.ErrorResponse.Error
+		}
+		if reason == "" {
+			reason = r.FailureReason
+		}
+		return "", errors.New(reason)
+	}
+	return r.SuccessResponse.OrderId, nil
+}
+
+func (c *Config) CancelOrder(id string) error {
+	r := &CancelResponse{}
+	err := c.do("POST", "/orders/batch_cancel", nil, map[string][]string{"order_ids": {id}}, r)
+	if err != nil {
+		return err
+	}
+	for _, res := range r.Results {
+		if res.OrderId == id && !res.Success {
+			return errors.New(res.FailureReason)
+		}
+	}
+	return nil
+}
+
+// SetTimeOffset sets the offset of the local clock to the server time in
+// milliseconds, which gets subtracted from the timestamp of signed requests.
+func (c *Config) SetTimeOffset(offset int64) {
+	atomic.StoreInt64(&c.offset, offset)
+}
+
+// now returns the local time corrected by the time offset.
+func (c *Config) now() time.Time {
+	return time.Now().Add(-time.Duration(atomic.LoadInt64(&c.offset)) * time.Millisecond)
+}
+
+func (c *Config) do(method string, resource string, params url.Values, payload interface{}, result interface{}) error {
+	if err := c.Limiter.Wait(context.Background(), 1, false); err != nil {
+		return err
+	}
+
+	if len(c.Key) == 0 || len(c.Secret) == 0 {
+		return errors.New("You need to set API Key and API Secret to call this method")
+	}
+
+	var body []byte
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = b
+	}
+
+	endpoint := c.RestEndpoint + brokerage + resource
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	token, err := c.newJWT(fmt.Sprintf("%s %s%s", method, u.Host, u.Path))
+	if err != nil {
+		return err
+	}
+
+	if len(params) > 0 {
+		endpoint = endpoint + "?" + params.Encode()
+	}
+	req, err := http.NewRequest(method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", "Bearer "+token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	c.Limiter.Observe(resp)
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != 200 {
+		e := &ErrorResponse{}
+		if err := json.Unmarshal(b, e); err == nil && e.Message != "" {
+			return errors.New(fmt.Sprintf("%s: %s", resp.Status, e.Message))
+		}
+		return errors.New(fmt.Sprintf("%s: %s", resp.Status, string(b)))
+	}
+
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(b, result); err != nil {
+		log.Debug(string(b))
+		return err
+	}
+	return nil
+}
+
+// precision returns the number of decimals of an increment such as "0.01".
+func precision(inc *values.Float) int {
+	s := inc.Text('f', -1)
+	if i := strings.Index(s, "."); i >= 0 {
+		return len(s) - i - 1
+	}
+	return 0
+}
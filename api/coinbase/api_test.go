@@ -0,0 +1,321 @@
+package coinbase
+
+import (
+	"../../utils/values"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockServer is a stand-in of the Coinbase Advanced Trade rest and websocket
+// api. Every request has to carry a token signed by the api key.
+type mockServer struct {
+	*httptest.Server
+	t *testing.T
+
+	key      *ecdsa.PublicKey
+	requests []string
+	mx       sync.Mutex
+}
+
+// newTestKey returns a new EC private key as PEM with escaped line breaks, as
+// found in json configuration files.
+func newTestKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b})
+	return key, strings.ReplaceAll(string(p), "\n", `\n`)
+}
+
+// verify checks the signature and the claims of a token.
+func (m *mockServer) verify(token string, uri string) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return errors.New("malformed token")
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(sig) != 64 {
+		return errors.New("malformed signature")
+	}
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if !ecdsa.Verify(m.key, hash[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+		return errors.New("invalid signature")
+	}
+
+	header, claims := map[string]interface{}{}, map[string]interface{}{}
+	for i, v := range []interface{}{&header, &claims} {
+		b, _ := base64.RawURLEncoding.DecodeString(parts[i])
+		if err := json.Unmarshal(b, v); err != nil {
+			return err
+		}
+	}
+	if header["alg"] != "ES256" || header["kid"] != "organizations/1/apiKeys/2" || header["nonce"] == "" {
+		return errors.New(fmt.Sprintf("invalid header: %v", header))
+	}
+	exp, _ := claims["exp"].(float64)
+	if claims["sub"] != "organizations/1/apiKeys/2" || claims["iss"] != "cdp" || int64(exp) < time.Now().Unix() {
+		return errors.New(fmt.Sprintf("invalid claims: %v", claims))
+	}
+	if got, _ := claims["uri"].(string); got != uri {
+		return errors.New(fmt.Sprintf("uri = %q, want %q", got, uri))
+	}
+	return nil
+}
+
+func newMockServer(t *testing.T, routes map[string]http.HandlerFunc) (*mockServer, *Config) {
+	key, secret := newTestKey(t)
+	m := &mockServer{t: t, key: &key.PublicKey}
+
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.mx.Lock()
+		m.requests = append(m.requests, r.Method+" "+r.URL.RequestURI())
+		m.mx.Unlock()
+
+		if strings.HasPrefix(r.URL.Path, brokerage) {
+			uri := fmt.Sprintf("%s %s%s", r.Method, r.Host, r.URL.Path)
+			if err := m.verify(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), uri); err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprintf(w, `{"error":"UNAUTHENTICATED","message":"%s"}`, err.Error())
+				return
+			}
+		}
+
+		h, ok := routes[strings.TrimPrefix(r.URL.Path, brokerage)]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":"NOT_FOUND","message":"not found"}`)
+			return
+		}
+		h(w, r)
+	}))
+
+	c := NewCoinbaseApi("organizations/1/apiKeys/2", secret)
+	c.RestEndpoint = m.URL
+	c.WebsocketEndpoint = "ws" + strings.TrimPrefix(m.URL, "http") + "/ws"
+	c.Products["DOGE-BTC"] = &Product{
+		ProductId:      "DOGE-BTC",
+		BaseCurrency:   "DOGE",
+		QuoteCurrency:  "BTC",
+		BaseIncrement:  *values.NewFloatFromString("0.1"),
+		QuoteIncrement: *values.NewFloatFromString("0.00000001"),
+		PriceIncrement: *values.NewFloatFromString("0.00000001"),
+	}
+
+	return m, c
+}
+
+func (m *mockServer) Requests() []string {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	return append([]string{}, m.requests...)
+}
+
+func TestSetup(t *testing.T) {
+	m, c := newMockServer(t, map[string]http.HandlerFunc{
+		"/products": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"products":[{"product_id":"DOGE-BTC","base_currency_id":"DOGE","quote_currency_id":"BTC","base_increment":"0.1","quote_increment":"0.00000001","price_increment":"0.00000001","base_min_size":"1","quote_min_size":"0.00001","trading_disabled":false}],"num_products":1}`)
+		},
+	})
+	defer m.Close()
+	c.Products = make(map[string]*Product)
+
+	if err := c.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	p := c.GetProduct("DOGE-BTC")
+	if p == nil {
+		t.Fatal("product missing")
+	}
+	if precision(&p.BaseIncrement) != 1 || precision(&p.PriceIncrement) != 8 || p.QuoteMinSize.ToString() != "0.00001000" {
+		t.Errorf("product = %s %s %s", p.BaseIncrement.ToString(), p.PriceIncrement.ToString(), p.QuoteMinSize.ToString())
+	}
+	if r := m.Requests(); len(r) != 1 || r[0] != "GET /api/v3/brokerage/products?product_type=SPOT" {
+		t.Errorf("requests = %v", r)
+	}
+}
+
+func TestPlaceOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		id       string
+		err      string
+	}{
+		{"placed", `{"success":true,"success_response":{"order_id":"11111-00000-000000","product_id":"DOGE-BTC","side":"BUY","client_order_id":"abc"}}`, "11111-00000-000000", ""},
+		{"rejected", `{"success":false,"failure_reason":"UNKNOWN_FAILURE_REASON","error_response":{"error":"INSUFFICIENT_FUND","message":"Insufficient balance in source account"}}`, "", "Insufficient balance in source account"},
+		{"rejected without message", `{"success":false,"failure_reason":"UNKNOWN_FAILURE_REASON","error_response":{"error":"INVALID_LIMIT_PRICE_POST_ONLY"}}`, "", "INVALID_LIMIT_PRICE_POST_ONLY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &CreateOrderRequest{}
+			m, c := newMockServer(t, map[string]http.HandlerFunc{
+				"/orders": func(w http.ResponseWriter, r *http.Request) {
+					_ = json.NewDecoder(r.Body).Decode(req)
+					fmt.Fprint(w, tt.response)
+				},
+			})
+			defer m.Close()
+
+			id, err := c.PlaceOrder("DOGE-BTC", "buy", values.NewFloatFromString("0.00000301"), values.NewFloatFromString("30.15"), "abc")
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("error = %v, want %s", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != tt.id {
+				t.Errorf("id = %s, want %s", id, tt.id)
+			}
+			limit := req.OrderConfiguration.LimitGtc
+			if req.ClientOrderId != "abc" || req.ProductId != "DOGE-BTC" || req.Side != "BUY" || limit == nil {
+				t.Fatalf("request = %+v", req)
+			}
+			if limit.BaseSize != "30.1" || limit.LimitPrice != "0.00000301" {
+				t.Errorf("limit = %s @ %s", limit.BaseSize, limit.LimitPrice)
+			}
+		})
+	}
+}
+
+func TestGetOpenOrdersPages(t *testing.T) {
+	m, c := newMockServer(t, map[string]http.HandlerFunc{
+		"/orders/historical/batch": func(w http.ResponseWriter, r *http.Request) {
+			next, id := "true", "1"
+			if r.URL.Query().Get("cursor") == "c1" {
+				next, id = "false", "2"
+			}
+			fmt.Fprintf(w, `{"orders":[{"order_id":"%s","client_order_id":"abc","product_id":"DOGE-BTC","side":"BUY","status":"OPEN","created_time":"2021-05-31T09:59:59Z","order_configuration":{"limit_limit_gtc":{"base_size":"30","limit_price":"0.00000301","post_only":false}}}],"has_next":%s,"cursor":"c1"}`, id, next)
+		},
+	})
+	defer m.Close()
+
+	orders, err := c.GetOpenOrders("DOGE-BTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 2 || orders[1].OrderId != "2" || orders[1].OrderConfiguration.LimitGtc.BaseSize != "30" {
+		t.Fatalf("orders = %d", len(orders))
+	}
+	if r := m.Requests(); len(r) != 2 || !strings.Contains(r[0], "order_status=OPEN") || !strings.Contains(r[1], "cursor=c1") {
+		t.Errorf("requests = %v", r)
+	}
+}
+
+func TestGetOrder(t *testing.T) {
+	m, c := newMockServer(t, map[string]http.HandlerFunc{
+		"/orders/historical/0000-000000-000000": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"order":{"order_id":"0000-000000-000000","client_order_id":"abc","product_id":"DOGE-BTC","side":"BUY","status":"CANCELLED","filled_size":"10","created_time":"2021-05-31T09:59:59Z","order_configuration":{"limit_limit_gtc":{"base_size":"30","limit_price":"0.00000301","post_only":false}}}}`)
+		},
+	})
+	defer m.Close()
+
+	o, err := c.GetOrder("0000-000000-000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Status != "CANCELLED" || o.ClientOrderId != "abc" || o.FilledSize.ToString() != "10.00000000" {
+		t.Errorf("order = %s %s %s", o.Status, o.ClientOrderId, o.FilledSize.ToString())
+	}
+
+	if _, err := c.GetOrder("1111-111111-111111"); err == nil {
+		t.Error("an unknown order got returned")
+	}
+}
+
+func TestInvalidSignature(t *testing.T) {
+	m, c := newMockServer(t, map[string]http.HandlerFunc{
+		"/accounts": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"accounts":[{"uuid":"8bfc20d7","currency":"BTC","available_balance":{"value":"1.5","currency":"BTC"},"hold":{"value":"0","currency":"BTC"}}],"has_next":false}`)
+		},
+	})
+	defer m.Close()
+
+	balances, err := c.GetBalances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balances["BTC"].ToString() != "1.50000000" {
+		t.Errorf("balance = %s", balances["BTC"].ToString())
+	}
+
+	// A token signed by another key gets rejected
+	_, c.Secret = newTestKey(t)
+	c.privateKey = nil
+	if _, err := c.GetBalances(); err == nil || !strings.HasPrefix(err.Error(), "401 Unauthorized: invalid signature") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	key, secret := newTestKey(t)
+	b, _ := x509.MarshalPKCS8PrivateKey(key)
+	pkcs8 := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: b}))
+
+	tests := []struct {
+		name   string
+		secret string
+		err    bool
+	}{
+		{"escaped", secret, false},
+		{"line breaks", strings.ReplaceAll(secret, `\n`, "\n"), false},
+		{"pkcs8", pkcs8, false},
+		{"no pem", "secret", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := parsePrivateKey(tt.secret)
+			if (err != nil) != tt.err {
+				t.Fatalf("error = %v", err)
+			}
+			if err == nil && !k.Equal(key) {
+				t.Error("a different key got parsed")
+			}
+		})
+	}
+}
+
+func TestGetServerTime(t *testing.T) {
+	m, c := newMockServer(t, map[string]http.HandlerFunc{
+		"/time": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"iso":"2024-11-08T09:50:53Z","epochSeconds":"1731059453","epochMillis":"1731059453178"}`)
+		},
+	})
+	defer m.Close()
+
+	ms, err := c.GetServerTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ms != 1731059453178 {
+		t.Errorf("server time = %d", ms)
+	}
+	if r := m.Requests(); len(r) != 1 || r[0] != "GET /api/v3/brokerage/time" {
+		t.Errorf("requests = %v", r)
+	}
+}
@@ -0,0 +1,176 @@
+package coinbase
+
+import (
+	"../../utils/limiter"
+	"../../utils/values"
+	"crypto/ecdsa"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+type Config struct {
+	// Key is the api key name "organizations/{org_id}/apiKeys/{key_id}" and
+	// Secret the PEM encoded EC private key.
+	Key    string `json:"key"`
+	Secret string `json:"secret"`
+
+	RestEndpoint      string        `json:"rest-endpoint"`
+	WebsocketEndpoint string        `json:"wss-endpoint"`
+	Timeout           time.Duration `json:"timeout"`
+
+	Products map[string]*Product
+
+	client     *http.Client
+	Limiter    *limiter.Limiter
+	privateKey *ecdsa.PrivateKey
+	// Offset of the local clock in milliseconds, see SetTimeOffset
+	offset int64
+}
+
+type Product struct {
+	ProductId       string       `json:"product_id"`
+	BaseCurrency    string       `json:"base_currency_id"`
+	QuoteCurrency   string       `json:"quote_currency_id"`
+	BaseIncrement   values.Float `json:"base_increment"`
+	QuoteIncrement  values.Float `json:"quote_increment"`
+	PriceIncrement  values.Float `json:"price_increment"`
+	BaseMinSize     values.Float `json:"base_min_size"`
+	QuoteMinSize    values.Float `json:"quote_min_size"`
+	TradingDisabled bool         `json:"trading_disabled"`
+}
+
+type ProductList struct {
+	Products []*Product `json:"products"`
+}
+
+type Amount struct {
+	Value    values.Float `json:"value"`
+	Currency string       `json:"currency"`
+}
+
+type Account struct {
+	Uuid             string `json:"uuid"`
+	Currency         string `json:"currency"`
+	AvailableBalance Amount `json:"available_balance"`
+	Hold             Amount `json:"hold"`
+}
+
+type AccountList struct {
+	Accounts []*Account `json:"accounts"`
+	HasNext  bool       `json:"has_next"`
+	Cursor   string     `json:"cursor"`
+}
+
+type LimitGtc struct {
+	BaseSize   string `json:"base_size"`
+	LimitPrice string `json:"limit_price"`
+	PostOnly   bool   `json:"post_only"`
+}
+
+type OrderConfiguration struct {
+	LimitGtc *LimitGtc `json:"limit_limit_gtc,omitempty"`
+}
+
+type Order struct {
+	OrderId            string             `json:"order_id"`
+	ClientOrderId      string             `json:"client_order_id"`
+	ProductId          string             `json:"product_id"`
+	Side               string             `json:"side"`
+	Status             string             `json:"status"`
+	FilledSize         values.Float       `json:"filled_size"`
+	CreatedTime        time.Time          `json:"created_time"`
+	OrderConfiguration OrderConfiguration `json:"order_configuration"`
+}
+
+type OrderResponse struct {
+	Order *Order `json:"order"`
+}
+
+type OrderList struct {
+	Orders  []*Order `json:"orders"`
+	HasNext bool     `json:"has_next"`
+	Cursor  string   `json:"cursor"`
+}
+
+type Fill struct {
+	TradeId   string       `json:"trade_id"`
+	OrderId   string       `json:"order_id"`
+	ProductId string       `json:"product_id"`
+	Side      string       `json:"side"`
+	Price     values.Float `json:"price"`
+	Size      values.Float `json:"size"`
+	TradeTime time.Time    `json:"trade_time"`
+}
+
+type FillList struct {
+	Fills  []*Fill `json:"fills"`
+	Cursor string  `json:"cursor"`
+}
+
+type CreateOrderRequest struct {
+	ClientOrderId      string             `json:"client_order_id"`
+	ProductId          string             `json:"product_id"`
+	Side               string             `json:"side"`
+	OrderConfiguration OrderConfiguration `json:"order_configuration"`
+}
+
+type CreateOrderResponse struct {
+	Success         bool   `json:"success"`
+	FailureReason   string `json:"failure_reason"`
+	SuccessResponse struct {
+		OrderId string `json:"order_id"`
+	} `json:"success_response"`
+	ErrorResponse struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	} `json:"error_response"`
+}
+
+type CancelResult struct {
+	Success       bool   `json:"success"`
+	FailureReason string `json:"failure_reason"`
+	OrderId       string `json:"order_id"`
+}
+
+type CancelResponse struct {
+	Results []*CancelResult `json:"results"`
+}
+
+type ErrorResponse struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// UserOrder is an order update of the "user" websocket channel.
+type UserOrder struct {
+	OrderId            string       `json:"order_id"`
+	ClientOrderId      string       `json:"client_order_id"`
+	ProductId          string       `json:"product_id"`
+	OrderSide          string       `json:"order_side"`
+	OrderType          string       `json:"order_type"`
+	Status             string       `json:"status"`
+	LimitPrice         values.Float `json:"limit_price"`
+	AvgPrice           values.Float `json:"avg_price"`
+	CumulativeQuantity values.Float `json:"cumulative_quantity"`
+	LeavesQuantity     values.Float `json:"leaves_quantity"`
+	TotalFees          values.Float `json:"total_fees"`
+	CreationTime       time.Time    `json:"creation_time"`
+}
+
+type UserEvent struct {
+	Type   string       `json:"type"`
+	Orders []*UserOrder `json:"orders"`
+}
+
+type wsMessage struct {
+	Channel string          `json:"channel"`
+	Type    string          `json:"type"`
+	Message string          `json:"message"`
+	Events  json.RawMessage `json:"events"`
+}
+
+type ServerTime struct {
+	Iso         string `json:"iso"`
+	EpochMillis string `json:"epochMillis"`
+}
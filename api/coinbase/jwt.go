@@ -0,0 +1,91 @@
+package coinbase
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"strings"
+)
+
+// parsePrivateKey parses the PEM encoded EC private key of a cloud api key.
+// Escaped line breaks, as found in json configuration files, are accepted.
+func parsePrivateKey(secret string) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(strings.ReplaceAll(secret, `\n`, "\n")))
+	if block == nil {
+		return nil, errors.New("api secret is not a PEM encoded private key")
+	}
+
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if ec, ok := key.(*ecdsa.PrivateKey); ok {
+		return ec, nil
+	}
+	return nil, errors.New("api secret is not an EC private key")
+}
+
+// newJWT creates an ES256 signed token which is valid for two minutes. The
+// uri claim ("GET api.coinbase.com/api/v3/brokerage/accounts") is required
+// for REST requests and must be empty for websocket subscriptions.
+func (c *Config) newJWT(uri string) (string, error) {
+	if c.privateKey == nil {
+		key, err := parsePrivateKey(c.Secret)
+		if err != nil {
+			return "", err
+		}
+		c.privateKey = key
+	}
+
+	nonce := make([]byte, 16)
+	_, _ = rand.Read(nonce)
+
+	header := map[string]interface{}{
+		"alg":   "ES256",
+		"typ":   "JWT",
+		"kid":   c.Key,
+		"nonce": hex.EncodeToString(nonce),
+	}
+	now := c.now().Unix()
+	claims := map[string]interface{}{
+		"sub": c.Key,
+		"iss": "cdp",
+		"nbf": now,
+		"exp": now + 120,
+	}
+	if uri != "" {
+		claims["uri"] = uri
+	}
+
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	p, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(p)
+
+	hash := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, c.privateKey, hash[:])
+	if err != nil {
+		return "", err
+	}
+
+	// ES256 signatures are the fixed size concatenation of r and s
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
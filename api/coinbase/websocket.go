@@ -0,0 +1,87 @@
+package coinbase
+
+import (
+	"../../utils/log"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/websocket"
+	"time"
+)
+
+// A heartbeat is sent every second on the subscribed "heartbeats" channel.
+const wsTimeout = 10 * time.Second
+
+// SubscribeUser subscribes to the "user" channel of the given products, or of
+// all products if none are given, and blocks until the connection fails or
+// stopCh receives a value. The first event is a snapshot of all open orders.
+func (c *Config) SubscribeUser(productIds []string, updatesCh chan<- UserEvent, stopCh <-chan bool) error {
+	conn, _, err := websocket.DefaultDialer.Dial(c.WebsocketEndpoint, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for _, channel := range []string{"heartbeats", "user"} {
+		token, err := c.newJWT("")
+		if err != nil {
+			return err
+		}
+		msg := map[string]interface{}{
+			"type":    "subscribe",
+			"channel": channel,
+			"jwt":     token,
+		}
+		if len(productIds) > 0 {
+			msg["product_ids"] = productIds
+		}
+		err = conn.WriteJSON(msg)
+		if err != nil {
+			return err
+		}
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		for {
+			_ = conn.SetReadDeadline(time.Now().Add(wsTimeout))
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				errCh <- err
+				return
+			}
+			if err := handleMessage(message, updatesCh); err != nil {
+				log.Error(err)
+			}
+		}
+	}()
+
+	select {
+	case <-stopCh:
+		_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		return nil
+	case err := <-errCh:
+		return err
+	}
+}
+
+func handleMessage(message []byte, updatesCh chan<- UserEvent) error {
+	msg := &wsMessage{}
+	if err := json.Unmarshal(message, msg); err != nil {
+		return err
+	}
+	if msg.Type == "error" {
+		return errors.New(msg.Message)
+	}
+	if msg.Channel != "user" {
+		return nil
+	}
+
+	events := make([]UserEvent, 0)
+	if err := json.Unmarshal(msg.Events, &events); err != nil {
+		return err
+	}
+	for _, evt := range events {
+		updatesCh <- evt
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package coinbase
+
+import (
+	"github.com/gorilla/websocket"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Frames as pushed by the Advanced Trade "user" and "heartbeats" channels
+const (
+	frameSnapshot  = `{"channel":"user","client_id":"","timestamp":"2023-02-09T20:33:57.609931463Z","sequence_num":0,"events":[{"type":"snapshot","orders":[{"order_id":"11111-00000-000000","client_order_id":"abc","cumulative_quantity":"0","leaves_quantity":"30","avg_price":"0","total_fees":"0","status":"OPEN","product_id":"DOGE-BTC","creation_time":"2022-12-07T19:42:18.719312Z","order_side":"BUY","order_type":"Limit","limit_price":"0.00000301"}]}]}`
+	frameUpdate    = `{"channel":"user","client_id":"","timestamp":"2023-02-09T20:34:57.609931463Z","sequence_num":1,"events":[{"type":"update","orders":[{"order_id":"11111-00000-000000","client_order_id":"abc","cumulative_quantity":"30","leaves_quantity":"0","avg_price":"0.00000301","total_fees":"0.0000001","status":"FILLED","product_id":"DOGE-BTC","creation_time":"2022-12-07T19:42:18.719312Z","order_side":"BUY","order_type":"Limit","limit_price":"0.00000301"}]}]}`
+	frameHeartbeat = `{"channel":"heartbeats","client_id":"","timestamp":"2023-06-23T20:31:26.122969572Z","sequence_num":2,"events":[{"current_time":"2023-06-23 20:31:56.121961769 +0000 UTC m=+91717.525857105","heartbeat_counter":"3049"}]}`
+	frameWsError   = `{"type":"error","message":"authentication failure"}`
+)
+
+func TestHandleMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		kind    string
+		status  string
+		err     bool
+	}{
+		{"snapshot", frameSnapshot, "snapshot", "OPEN", false},
+		{"update", frameUpdate, "update", "FILLED", false},
+		{"heartbeat", frameHeartbeat, "", "", false},
+		{"subscriptions", `{"channel":"subscriptions","events":[{"subscriptions":{"user":["DOGE-BTC"]}}]}`, "", "", false},
+		{"error", frameWsError, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updatesCh := make(chan UserEvent, 1)
+			err := handleMessage([]byte(tt.message), updatesCh)
+			if (err != nil) != tt.err {
+				t.Fatalf("error = %v", err)
+			}
+
+			select {
+			case evt := <-updatesCh:
+				if evt.Type != tt.kind || len(evt.Orders) != 1 {
+					t.Fatalf("event = %s with %d orders, want %s", evt.Type, len(evt.Orders), tt.kind)
+				}
+				o := evt.Orders[0]
+				if o.Status != tt.status || o.OrderId != "11111-00000-000000" || o.ClientOrderId != "abc" || o.LimitPrice.ToString() != "0.00000301" {
+					t.Errorf("order = %s %s %s %s", o.Status, o.OrderId, o.ClientOrderId, o.LimitPrice.ToString())
+				}
+				if o.CreationTime.Unix() != 1670442138 {
+					t.Errorf("creation time = %s", o.CreationTime)
+				}
+			default:
+				if tt.kind != "" {
+					t.Errorf("no update received")
+				}
+			}
+		})
+	}
+}
+
+// wsRoute accepts the subscriptions if they are signed and pushes the given
+// frames afterwards.
+func wsRoute(t *testing.T, m **mockServer, frames ...string) http.HandlerFunc {
+	upgrader := websocket.Upgrader{}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		for _, channel := range []string{"heartbeats", "user"} {
+			sub := map[string]interface{}{}
+			if err := conn.ReadJSON(&sub); err != nil || sub["type"] != "subscribe" || sub["channel"] != channel {
+				t.Errorf("subscription = %v, %v", sub, err)
+				return
+			}
+			// Websocket tokens carry no uri
+			token, _ := sub["jwt"].(string)
+			if err := (*m).verify(token, ""); err != nil {
+				_ = conn.WriteMessage(websocket.TextMessage, []byte(frameWsError))
+				return
+			}
+		}
+
+		for _, f := range frames {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(f)); err != nil {
+				return
+			}
+		}
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func TestSubscribeUser(t *testing.T) {
+	var m *mockServer
+	m, c := newMockServer(t, map[string]http.HandlerFunc{
+		"/ws": wsRoute(t, &m, frameSnapshot, frameHeartbeat, frameUpdate),
+	})
+	defer m.Close()
+
+	updatesCh := make(chan UserEvent, 8)
+	stopCh := make(chan bool)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.SubscribeUser(nil, updatesCh, stopCh)
+	}()
+
+	for _, kind := range []string{"snapshot", "update"} {
+		select {
+		case evt := <-updatesCh:
+			if evt.Type != kind {
+				t.Errorf("type = %s, want %s", evt.Type, kind)
+			}
+		case err := <-errCh:
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s event not received", kind)
+		}
+	}
+
+	stopCh <- true
+	if err := <-errCh; err != nil {
+		t.Error(err)
+	}
+}
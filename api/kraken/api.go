@@ -0,0 +1,332 @@
+package kraken
+
+import (
+	"../../utils/limiter"
+	"../../utils/log"
+	"../../utils/values"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// Kraken allows a burst of 15 private calls which decays by one every 3 seconds.
+	reqInterval = 500 * time.Millisecond
+	nonceMx     = sync.Mutex{}
+)
+
+func NewKrakenApi(key string, secret string) *Config {
+	return &Config{
+		Key:               key,
+		Secret:            secret,
+		RestEndpoint:      "https://api.kraken.com",
+		WebsocketEndpoint: "wss://ws-auth.kraken.com",
+		Timeout:           time.Second * 30,
+		Assets:            make(map[string]*Asset),
+		Pairs:             make(map[string]*AssetPair),
+		client: &http.Client{
+			Timeout: time.Second * 30,
+		},
+		Limiter: limiter.New(limiter.Config{Name: "kraken", Interval: reqInterval}),
+	}
+}
+
+func (c *Config) Setup() error {
+	assets, err := c.GetAssets()
+	if err != nil {
+		return err
+	}
+	pairs, err := c.GetAssetPairs()
+	if err != nil {
+		return err
+	}
+	c.Assets = assets
+	c.Pairs = pairs
+	return nil
+}
+
+func (c *Config) GetAssets() (map[string]*Asset, error) {
+	r := make(map[string]*Asset)
+	if err := c.public("Assets", &r); err != nil {
+		return nil, err
+	}
+	for name, a := range r {
+		a.Name = name
+	}
+	return r, nil
+}
+
+func (c *Config) GetAssetPairs() (map[string]*AssetPair, error) {
+	r := make(map[string]*AssetPair)
+	if err := c.public("AssetPairs", &r); err != nil {
+		return nil, err
+	}
+	for name, p := range r {
+		p.Name = name
+	}
+	return r, nil
+}
+
+// GetPair looks up an asset pair by its name, altname ("XDGXBT") or
+// websocket name ("XDG/XBT").
+func (c *Config) GetPair(symbol string) *AssetPair {
+	for _, p := range c.Pairs {
+		if p.Name == symbol || p.AltName == symbol || p.WsName == symbol {
+			return p
+		}
+	}
+	return nil
+}
+
+// AssetName returns the common name of an asset, such as "XBT" for "XXBT".
+func (c *Config) AssetName(asset string) string {
+	if a, ok := c.Assets[asset]; ok {
+		return a.AltName
+	}
+	return asset
+}
+
+func (c *Config) GetBalances() (map[string]*values.Float, error) {
+	r := make(map[string]*values.Float)
+	if err := c.private("Balance", nil, &r); err != nil {
+		return nil, err
+	}
+
+	balances := make(map[string]*values.Float)
+	for asset, b := range r {
+		balances[c.AssetName(asset)] = b
+	}
+	return balances, nil
+}
+
+func (c *Config) GetOpenOrders() ([]*Order, error) {
+	r := &OpenOrders{}
+	if err := c.private("OpenOrders", nil, r); err != nil {
+		return nil, err
+	}
+
+	orders := make([]*Order, 0)
+	for id, o := range r.Open {
+		o.Id = id
+		orders = append(orders, o)
+	}
+	return orders, nil
+}
+
+// QueryOrder returns the order with the given id, including closed orders.
+func (c *Config) QueryOrder(id string) (*Order, error) {
+	params := url.Values{}
+	params.Set("txid", id)
+
+	r := make(map[string]*Order)
+	if err := c.private("QueryOrders", params, &r); err != nil {
+		return nil, err
+	}
+	o, ok := r[id]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("order not found: %s", id))
+	}
+	o.Id = id
+	return o, nil
+}
+
+// GetOrderByClientId returns the open or closed order with the given client
+// order id. Nil is returned if there is none.
+func (c *Config) GetOrderByClientId(clOrdId string) (*Order, error) {
+	params := url.Values{}
+	params.Set("cl_ord_id", clOrdId)
+
+	open := &OpenOrders{}
+	if err := c.private("OpenOrders", params, open); err != nil {
+		return nil, err
+	}
+	closed := &ClosedOrders{}
+	if len(open.Open) == 0 {
+		if err := c.private("ClosedOrders", params, closed); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, orders := range []map[string]*Order{open.Open, closed.Closed} {
+		for id, o := range orders {
+			o.Id = id
+			return o, nil
+		}
+	}
+	return nil, nil
+}
+
+// AddOrder places a limit order. The optional client order id has to be a
+// UUID or a free text of up to 18 characters.
+func (c *Config) AddOrder(pair *AssetPair, side string, price *values.Float, volume *values.Float, clOrdId string) (string, error) {
+	params := url.Values{}
+	params.Set("pair", pair.AltName)
+	params.Set("type", side)
+	params.Set("ordertype", "limit")
+	params.Set("price", price.ToPrecision(pair.PairDecimals))
+	params.Set("volume", volume.ToPrecision(pair.LotDecimals))
+	if clOrdId != "" {
+		params.Set("cl_ord_id", clOrdId)
+	}
+
+	r := &AddOrderResult{}
+	if err := c.private("AddOrder", params, r); err != nil {
+		return "", err
+	}
+	if len(r.TxId) == 0 {
+		return "", errors.New("no order id returned")
+	}
+	return r.TxId[0], nil
+}
+
+func (c *Config) CancelOrder(id string) error {
+	params := url.Values{}
+	params.Set("txid", id)
+
+	return c.private("CancelOrder", params, nil)
+}
+
+// GetTrades returns all trades executed since the given time.
+func (c *Config) GetTrades(since time.Time) ([]*Trade, error) {
+	trades := make([]*Trade, 0)
+	for {
+		params := url.Values{}
+		params.Set("start", strconv.FormatInt(since.Unix(), 10))
+		params.Set("ofs", strconv.Itoa(len(trades)))
+
+		r := &TradesHistory{}
+		if err := c.private("TradesHistory", params, r); err != nil {
+			return nil, err
+		}
+		for id, t := range r.Trades {
+			t.Id = id
+			trades = append(trades, t)
+		}
+		if len(r.Trades) == 0 || len(trades) >= r.Count {
+			return trades, nil
+		}
+	}
+}
+
+func (c *Config) GetWebsocketToken() (string, error) {
+	r := &WebsocketToken{}
+	if err := c.private("GetWebSocketsToken", nil, r); err != nil {
+		return "", err
+	}
+	return r.Token, nil
+}
+
+func (c *Config) public(method string, result interface{}) error {
+	return c.do("GET", "/0/public/"+method, nil, false, result)
+}
+
+func (c *Config) private(method string, params url.Values, result interface{}) error {
+	return c.do("POST", "/0/private/"+method, params, true, result)
+}
+
+func (c *Config) do(method string, resource string, params url.Values, authNeeded bool, result interface{}) error {
+	if err := c.Limiter.Wait(context.Background(), 1, false); err != nil {
+		return err
+	}
+
+	if params == nil {
+		params = url.Values{}
+	}
+
+	var req *http.Request
+	var err error
+	if authNeeded {
+		if len(c.Key) == 0 || len(c.Secret) == 0 {
+			return errors.New("You need to set API Key and API Secret to call this method")
+		}
+
+		params.Set("nonce", c.nextNonce())
+		body := params.Encode()
+
+		sig, err := c.sign(resource, params.Get("nonce"), body)
+		if err != nil {
+			return err
+		}
+
+		req, err = http.NewRequest(method, c.RestEndpoint+resource, strings.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Add("API-Key", c.Key)
+		req.Header.Add("API-Sign", sig)
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		req, err = http.NewRequest(method, c.RestEndpoint+resource+"?"+params.Encode(), nil)
+		if err != nil {
+			return err
+		}
+	}
+	req.Header.Add("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	c.Limiter.Observe(resp)
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != 200 {
+		return errors.New(fmt.Sprintf("%s: %s", resp.Status, string(b)))
+	}
+
+	r := &Response{Result: result}
+	if err := json.Unmarshal(b, r); err != nil {
+		log.Debug(string(b))
+		return err
+	}
+	if len(r.Error) > 0 {
+		return errors.New(strings.Join(r.Error, ", "))
+	}
+	return nil
+}
+
+// sign creates the API-Sign header:
+// HMAC-SHA512 of (URI path + SHA256(nonce + POST data)) and base64 decoded secret API key
+func (c *Config) sign(resource string, nonce string, body string) (string, error) {
+	secret, err := base64.StdEncoding.DecodeString(c.Secret)
+	if err != nil {
+		return "", err
+	}
+
+	sha := sha256.New()
+	sha.Write([]byte(nonce + body))
+
+	mac := hmac.New(sha512.New, secret)
+	mac.Write(append([]byte(resource), sha.Sum(nil)...))
+
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (c *Config) nextNonce() string {
+	nonceMx.Lock()
+	defer nonceMx.Unlock()
+
+	n := time.Now().UnixNano() / int64(time.Microsecond)
+	if n <= c.nonce {
+		n = c.nonce + 1
+	}
+	c.nonce = n
+
+	return strconv.FormatInt(n, 10)
+}
@@ -0,0 +1,113 @@
+package kraken
+
+import (
+	"../../utils/limiter"
+	"../../utils/values"
+	"net/http"
+	"time"
+)
+
+type Config struct {
+	Key    string `json:"key"`
+	Secret string `json:"secret"`
+
+	RestEndpoint      string        `json:"rest-endpoint"`
+	WebsocketEndpoint string        `json:"wss-endpoint"`
+	Timeout           time.Duration `json:"timeout"`
+
+	Assets map[string]*Asset
+	Pairs  map[string]*AssetPair
+
+	client  *http.Client
+	Limiter *limiter.Limiter
+	nonce   int64
+}
+
+type Response struct {
+	Error  []string    `json:"error"`
+	Result interface{} `json:"result"`
+}
+
+type Asset struct {
+	Name     string `json:"-"`
+	AltName  string `json:"altname"`
+	Decimals int    `json:"decimals"`
+}
+
+type AssetPair struct {
+	Name         string       `json:"-"`
+	AltName      string       `json:"altname"`
+	WsName       string       `json:"wsname"`
+	Base         string       `json:"base"`
+	Quote        string       `json:"quote"`
+	PairDecimals int          `json:"pair_decimals"`
+	LotDecimals  int          `json:"lot_decimals"`
+	OrderMin     values.Float `json:"ordermin"`
+	CostMin      values.Float `json:"costmin"`
+	TickSize     values.Float `json:"tick_size"`
+}
+
+type OrderDescription struct {
+	Pair      string       `json:"pair"`
+	Type      string       `json:"type"`
+	OrderType string       `json:"ordertype"`
+	Price     values.Float `json:"price"`
+}
+
+type Order struct {
+	Id         string           `json:"-"`
+	ClOrdId    string           `json:"cl_ord_id"`
+	Status     string           `json:"status"`
+	OpenTime   values.Float     `json:"opentm"`
+	Descr      OrderDescription `json:"descr"`
+	Volume     values.Float     `json:"vol"`
+	VolumeExec values.Float     `json:"vol_exec"`
+	Cost       values.Float     `json:"cost"`
+	Fee        values.Float     `json:"fee"`
+	AvgPrice   values.Float     `json:"avg_price"`
+}
+
+type OpenOrders struct {
+	Open map[string]*Order `json:"open"`
+}
+
+type ClosedOrders struct {
+	Closed map[string]*Order `json:"closed"`
+	Count  int               `json:"count"`
+}
+
+type AddOrderResult struct {
+	TxId []string `json:"txid"`
+}
+
+type Trade struct {
+	Id        string       `json:"-"`
+	OrderTxId string       `json:"ordertxid"`
+	Pair      string       `json:"pair"`
+	Time      values.Float `json:"time"`
+	Type      string       `json:"type"`
+	OrderType string       `json:"ordertype"`
+	Price     values.Float `json:"price"`
+	Cost      values.Float `json:"cost"`
+	Fee       values.Float `json:"fee"`
+	Volume    values.Float `json:"vol"`
+}
+
+type TradesHistory struct {
+	Trades map[string]*Trade `json:"trades"`
+	Count  int               `json:"count"`
+}
+
+type WebsocketToken struct {
+	Token   string `json:"token"`
+	Expires int    `json:"expires"`
+}
+
+// AccountUpd is a private websocket update. Orders contains every changed
+// order of an "openOrders" message, Trades every trade of an "ownTrades" message.
+// Subscribed indicates, that both subscriptions got acknowledged.
+type AccountUpd struct {
+	Orders     []*Order
+	Trades     []*Trade
+	Subscribed bool
+}
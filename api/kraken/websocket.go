@@ -0,0 +1,134 @@
+package kraken
+
+import (
+	"../../utils/log"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"time"
+)
+
+// Kraken sends a heartbeat every second if no other message got sent.
+const wsTimeout = 10 * time.Second
+
+// SubscribeAccount subscribes to the private "openOrders" and "ownTrades"
+// feeds and blocks until the connection fails or stopCh receives a value.
+func (c *Config) SubscribeAccount(updatesCh chan<- AccountUpd, stopCh <-chan bool) error {
+	token, err := c.GetWebsocketToken()
+	if err != nil {
+		return err
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(c.WebsocketEndpoint, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Trades executed before the subscription are already part of the
+	// order snapshot, hence the trade snapshot isn't required.
+	subscriptions := []map[string]interface{}{
+		{"name": "openOrders", "token": token},
+		{"name": "ownTrades", "token": token, "snapshot": false},
+	}
+	for _, sub := range subscriptions {
+		err := conn.WriteJSON(map[string]interface{}{
+			"event":        "subscribe",
+			"subscription": sub,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		subscribed := make(map[string]bool)
+		for {
+			_ = conn.SetReadDeadline(time.Now().Add(wsTimeout))
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				errCh <- err
+				return
+			}
+			if err := c.handleMessage(message, updatesCh, subscribed); err != nil {
+				log.Error(err)
+			}
+		}
+	}()
+
+	select {
+	case <-stopCh:
+		_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		return nil
+	case err := <-errCh:
+		return err
+	}
+}
+
+// handleMessage decodes a websocket message. The subscribed channels of the
+// connection get collected, an update flagged as Subscribed is sent once
+// both feeds got acknowledged.
+func (c *Config) handleMessage(message []byte, updatesCh chan<- AccountUpd, subscribed map[string]bool) error {
+	if len(message) > 0 && message[0] == '{' {
+		evt := struct {
+			Event        string `json:"event"`
+			Status       string `json:"status"`
+			ChannelName  string `json:"channelName"`
+			ErrorMessage string `json:"errorMessage"`
+		}{}
+		if err := json.Unmarshal(message, &evt); err != nil {
+			return err
+		}
+		if evt.Status == "error" {
+			return errors.New(fmt.Sprintf("%s: %s", evt.Event, evt.ErrorMessage))
+		}
+		if evt.Event == "subscriptionStatus" && evt.Status == "subscribed" && !subscribed[evt.ChannelName] {
+			subscribed[evt.ChannelName] = true
+			if subscribed["openOrders"] && subscribed["ownTrades"] {
+				updatesCh <- AccountUpd{Subscribed: true}
+			}
+		}
+		return nil
+	}
+
+	// [[{"ID": {...}}, ...], "channelName", {"sequence": 1}]
+	var data []map[string]json.RawMessage
+	var channel string
+	var seq interface{}
+	arr := []interface{}{&data, &channel, &seq}
+	if err := json.Unmarshal(message, &arr); err != nil {
+		return err
+	}
+
+	upd := AccountUpd{
+		Orders: make([]*Order, 0),
+		Trades: make([]*Trade, 0),
+	}
+	for _, entries := range data {
+		for id, raw := range entries {
+			switch channel {
+			case "openOrders":
+				o := &Order{}
+				if err := json.Unmarshal(raw, o); err != nil {
+					return err
+				}
+				o.Id = id
+				upd.Orders = append(upd.Orders, o)
+			case "ownTrades":
+				t := &Trade{}
+				if err := json.Unmarshal(raw, t); err != nil {
+					return err
+				}
+				t.Id = id
+				upd.Trades = append(upd.Trades, t)
+			}
+		}
+	}
+
+	if len(upd.Orders)+len(upd.Trades) > 0 {
+		updatesCh <- upd
+	}
+	return nil
+}
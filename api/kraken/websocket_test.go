@@ -0,0 +1,237 @@
+package kraken
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Frames as sent by the Kraken websocket api v1, times are decimal strings
+const (
+	frameOpenOrders       = `[[{"OGTT3Y-C6I3P-XRI6HX":{"avg_price":"0.00000","cost":"0.00000","descr":{"close":"","leverage":"0:1","order":"sell 10.00345345 XDG/XBT @ limit 0.00000340 with 0:1 leverage","ordertype":"limit","pair":"XDG/XBT","price":"0.00000340","price2":"0.00000000","type":"sell"},"expiretm":"0.000000","fee":"0.00000","limitprice":"0.00000000","misc":"","oflags":"fciq","opentm":"1560516023.070651","refid":null,"starttm":"0.000000","status":"open","stopprice":"0.000000","timeinforce":"GTC","userref":0,"vol":"10.00345345","vol_exec":"0.00000000","cl_ord_id":"2f4c0b1c"}}],"openOrders",{"sequence":1}]`
+	frameOwnTrades        = `[[{"TDLH43-DVQXD-2KHVYY":{"cost":"0.00001700","fee":"0.00000003","margin":"0.00000000","ordertxid":"OGTT3Y-C6I3P-XRI6HX","ordertype":"limit","pair":"XDG/XBT","postxid":"TKH2SE-M7IF5-CFI7LT","price":"0.00000340","time":"1560516023.070651","type":"sell","vol":"5.00000000"}}],"ownTrades",{"sequence":2}]`
+	frameClosed           = `[[{"OGTT3Y-C6I3P-XRI6HX":{"lastupdated":"1560516024.070651","status":"closed","vol_exec":"10.00345345","cost":"0.00003401","fee":"0.00000005","avg_price":"0.00000340"}}],"openOrders",{"sequence":3}]`
+	frameHeartbeat        = `{"event":"heartbeat"}`
+	frameSubscribed       = `{"channelName":"openOrders","event":"subscriptionStatus","status":"subscribed","subscription":{"name":"openOrders"}}`
+	frameTradesSubscribed = `{"channelName":"ownTrades","event":"subscriptionStatus","status":"subscribed","subscription":{"name":"ownTrades"}}`
+	frameError            = `{"errorMessage":"EGeneral:Invalid arguments","event":"subscriptionStatus","status":"error"}`
+)
+
+func TestHandleMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		orders  int
+		trades  int
+		err     bool
+	}{
+		{"open orders", frameOpenOrders, 1, 0, false},
+		{"own trades", frameOwnTrades, 0, 1, false},
+		{"closed", frameClosed, 1, 0, false},
+		{"heartbeat", frameHeartbeat, 0, 0, false},
+		{"subscribed", frameSubscribed, 0, 0, false},
+		{"subscription error", frameError, 0, 0, true},
+		{"empty snapshot", `[[],"ownTrades",{"sequence":1}]`, 0, 0, false},
+	}
+
+	c := NewKrakenApi("", "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updatesCh := make(chan AccountUpd, 1)
+			err := c.handleMessage([]byte(tt.message), updatesCh, make(map[string]bool))
+			if (err != nil) != tt.err {
+				t.Fatalf("error = %v", err)
+			}
+
+			orders, trades := 0, 0
+			select {
+			case upd := <-updatesCh:
+				orders, trades = len(upd.Orders), len(upd.Trades)
+			default:
+			}
+			if orders != tt.orders || trades != tt.trades {
+				t.Errorf("orders = %d, trades = %d, want %d, %d", orders, trades, tt.orders, tt.trades)
+			}
+		})
+	}
+}
+
+func TestHandleMessageFields(t *testing.T) {
+	c := NewKrakenApi("", "")
+	updatesCh := make(chan AccountUpd, 2)
+
+	if err := c.handleMessage([]byte(frameOpenOrders), updatesCh, make(map[string]bool)); err != nil {
+		t.Fatal(err)
+	}
+	o := (<-updatesCh).Orders[0]
+	if o.Id != "OGTT3Y-C6I3P-XRI6HX" || o.ClOrdId != "2f4c0b1c" || o.Status != "open" {
+		t.Errorf("order = %s %s %s", o.Id, o.ClOrdId, o.Status)
+	}
+	if int64(o.OpenTime.ToFloat()) != 1560516023 || o.Volume.ToString() != "10.00345345" || o.Descr.Price.ToString() != "0.00000340" {
+		t.Errorf("order = %s %s @ %s", o.OpenTime.ToString(), o.Volume.ToString(), o.Descr.Price.ToString())
+	}
+
+	if err := c.handleMessage([]byte(frameOwnTrades), updatesCh, make(map[string]bool)); err != nil {
+		t.Fatal(err)
+	}
+	tr := (<-updatesCh).Trades[0]
+	if tr.Id != "TDLH43-DVQXD-2KHVYY" || tr.OrderTxId != "OGTT3Y-C6I3P-XRI6HX" || tr.Type != "sell" {
+		t.Errorf("trade = %s %s %s", tr.Id, tr.OrderTxId, tr.Type)
+	}
+	if int64(tr.Time.ToFloat()) != 1560516023 || tr.Volume.ToString() != "5.00000000" {
+		t.Errorf("trade = %s %s", tr.Time.ToString(), tr.Volume.ToString())
+	}
+}
+
+func TestHandleMessageSubscribed(t *testing.T) {
+	tests := []struct {
+		name       string
+		messages   []string
+		subscribed int
+	}{
+		{"orders only", []string{frameSubscribed}, 0},
+		{"both", []string{frameSubscribed, frameTradesSubscribed}, 1},
+		{"both reversed", []string{frameTradesSubscribed, frameOpenOrders, frameSubscribed}, 1},
+		{"acknowledged twice", []string{frameSubscribed, frameTradesSubscribed, frameSubscribed}, 1},
+		{"error", []string{frameSubscribed, frameError}, 0},
+	}
+
+	c := NewKrakenApi("", "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updatesCh := make(chan AccountUpd, 8)
+			subscribed := make(map[string]bool)
+			for _, m := range tt.messages {
+				_ = c.handleMessage([]byte(m), updatesCh, subscribed)
+			}
+			close(updatesCh)
+
+			n := 0
+			for upd := range updatesCh {
+				if upd.Subscribed {
+					n++
+				}
+			}
+			if n != tt.subscribed {
+				t.Errorf("subscribed = %d, want %d", n, tt.subscribed)
+			}
+		})
+	}
+}
+
+// newTestServer starts a stand-in of the rest and the websocket api. The
+// websocket replays the given frames once both subscriptions got received.
+func newTestServer(t *testing.T, frames ...string) (*Config, func()) {
+	upgrader := websocket.Upgrader{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/0/private/GetWebSocketsToken", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("API-Sign") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"error":[],"result":{"token":"1Dwc4lzSwNWOAwkMdqhssNNFhs1ed606d1WcF3XfEMw","expires":900}}`)
+	})
+	mux.HandleFunc("/0/private/OpenOrders", func(w http.ResponseWriter, r *http.Request) {
+		// The rest api sends times as numbers
+		fmt.Fprint(w, `{"error":[],"result":{"open":{"OQCLML-BW3P3-BUCMWZ":{"refid":null,"userref":0,"status":"open","opentm":1688666559.8974,"starttm":0,"expiretm":0,"descr":{"pair":"XDGXBT","type":"buy","ordertype":"limit","price":"0.00000300","price2":"0","leverage":"none","order":"buy 30.00000000 XDGXBT @ limit 0.00000300","close":""},"vol":"30.00000000","vol_exec":"0.00000000","cost":"0.00000","fee":"0.00000","price":"0.00000","misc":"","oflags":"fciq"}}}}`)
+	})
+	mux.HandleFunc("/0/private/TradesHistory", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"error":[],"result":{"trades":{"THVRQM-33VKH-UCI7BS":{"ordertxid":"OQCLML-BW3P3-BUCMWZ","postxid":"TKH2SE-M7IF5-CFI7LT","pair":"XDGXBT","time":1688667796.8802,"type":"buy","ordertype":"limit","price":"0.00000300","cost":"0.00009000","fee":"0.00000014","vol":"30.00000000","margin":"0.00000","misc":""}},"count":1}}`)
+	})
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		for i := 0; i < 2; i++ {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+		for _, f := range frames {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(f)); err != nil {
+				return
+			}
+		}
+		// Keep the connection open until the client closes it
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	srv := httptest.NewServer(mux)
+
+	c := NewKrakenApi("key", base64.StdEncoding.EncodeToString([]byte("secret")))
+	c.RestEndpoint = srv.URL
+	c.WebsocketEndpoint = "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+	return c, srv.Close
+}
+
+func TestSubscribeAccount(t *testing.T) {
+	c, stop := newTestServer(t, frameSubscribed, frameTradesSubscribed, frameOpenOrders, frameHeartbeat, frameOwnTrades, frameClosed)
+	defer stop()
+
+	updatesCh := make(chan AccountUpd, 8)
+	stopCh := make(chan bool)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.SubscribeAccount(updatesCh, stopCh)
+	}()
+
+	// The acknowledgement of both subscriptions precedes the updates
+	want := []struct {
+		subscribed bool
+		orders     int
+		trades     int
+	}{{true, 0, 0}, {false, 1, 0}, {false, 0, 1}, {false, 1, 0}}
+	for i, w := range want {
+		select {
+		case upd := <-updatesCh:
+			if upd.Subscribed != w.subscribed || len(upd.Orders) != w.orders || len(upd.Trades) != w.trades {
+				t.Errorf("update %d: subscribed = %v, orders = %d, trades = %d, want %v, %d, %d", i, upd.Subscribed, len(upd.Orders), len(upd.Trades), w.subscribed, w.orders, w.trades)
+			}
+		case err := <-errCh:
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("update %d not received", i)
+		}
+	}
+
+	stopCh <- true
+	if err := <-errCh; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRestTimes(t *testing.T) {
+	c, stop := newTestServer(t)
+	defer stop()
+
+	orders, err := c.GetOpenOrders()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 1 || int64(orders[0].OpenTime.ToFloat()) != 1688666559 {
+		t.Errorf("orders = %d", len(orders))
+	}
+
+	trades, err := c.GetTrades(time.Unix(1688666559, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 1 || int64(trades[0].Time.ToFloat()) != 1688667796 {
+		t.Errorf("trades = %d", len(trades))
+	}
+}
@@ -0,0 +1,305 @@
+package kucoin
+
+import (
+	"../../utils/limiter"
+	"../../utils/log"
+	"../../utils/values"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// KuCoin allows about 30 private requests within 3 seconds.
+	reqInterval = 100 * time.Millisecond
+)
+
+func NewKucoinApi(key string, secret string, passphrase string) *Config {
+	return &Config{
+		Key:          key,
+		Secret:       secret,
+		Passphrase:   passphrase,
+		RestEndpoint: "https://api.kucoin.com",
+		Timeout:      time.Second * 30,
+		Symbols:      make(map[string]*Symbol),
+		client: &http.Client{
+			Timeout: time.Second * 30,
+		},
+		Limiter: limiter.New(limiter.Config{Name: "kucoin", Interval: reqInterval}),
+	}
+}
+
+func (c *Config) Setup() error {
+	symbols, err := c.GetSymbols()
+	if err != nil {
+		return err
+	}
+	c.Symbols = symbols
+	return nil
+}
+
+// GetServerTime returns the server time in milliseconds.
+func (c *Config) GetServerTime() (int64, error) {
+	var ts int64
+	if err := c.do("GET", "/api/v1/timestamp", nil, nil, false, &ts); err != nil {
+		return 0, err
+	}
+	return ts, nil
+}
+
+func (c *Config) GetSymbols() (map[string]*Symbol, error) {
+	r := make([]*Symbol, 0)
+	if err := c.do("GET", "/api/v1/symbols", nil, nil, false, &r); err != nil {
+		return nil, err
+	}
+
+	symbols := make(map[string]*Symbol)
+	for _, s := range r {
+		symbols[s.Symbol] = s
+	}
+	return symbols, nil
+}
+
+func (c *Config) GetSymbol(symbol string) *Symbol {
+	if s, ok := c.Symbols[symbol]; ok {
+		return s
+	}
+	return nil
+}
+
+// GetBalances returns the available balance of every trading account.
+func (c *Config) GetBalances() (map[string]*values.Float, error) {
+	params := url.Values{}
+	params.Set("type", "trade")
+
+	r := make([]*Account, 0)
+	if err := c.do("GET", "/api/v1/accounts", params, nil, true, &r); err != nil {
+		return nil, err
+	}
+
+	balances := make(map[string]*values.Float)
+	for _, a := range r {
+		balances[a.Currency] = values.NewFloat(&a.Available.Float)
+	}
+	return balances, nil
+}
+
+func (c *Config) GetOpenOrders(symbol string) ([]*Order, error) {
+	orders := make([]*Order, 0)
+	for page := 1; ; page++ {
+		params := url.Values{}
+		params.Set("status", "active")
+		params.Set("symbol", symbol)
+		params.Set("pageSize", "500")
+		params.Set("currentPage", strconv.Itoa(page))
+
+		r := &OrderPage{}
+		if err := c.do("GET", "/api/v1/orders", params, nil, true, r); err != nil {
+			return nil, err
+		}
+		orders = append(orders, r.Items...)
+
+		if page >= r.TotalPage {
+			return orders, nil
+		}
+	}
+}
+
+// GetOrder returns the order with the given id, including closed orders.
+func (c *Config) GetOrder(id string) (*Order, error) {
+	r := &Order{}
+	if err := c.do("GET", "/api/v1/orders/"+id, nil, nil, true, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetOrderByClientOid returns the order with the given client order id,
+// including closed orders. Nil is returned if there is none.
+func (c *Config) GetOrderByClientOid(clientOid string) (*Order, error) {
+	r := &Order{}
+	if err := c.do("GET", "/api/v1/order/client-order/"+clientOid, nil, nil, true, r); err != nil {
+		return nil, err
+	}
+	if r.Id == "" {
+		return nil, nil
+	}
+	return r, nil
+}
+
+// GetFills returns all fills of the given symbol since the given time. KuCoin
+// limits the time range to seven days.
+func (c *Config) GetFills(symbol string, since time.Time) ([]*Fill, error) {
+	fills := make([]*Fill, 0)
+	for page := 1; ; page++ {
+		params := url.Values{}
+		params.Set("symbol", symbol)
+		params.Set("startAt", strconv.FormatInt(since.UnixNano()/int64(time.Millisecond), 10))
+		params.Set("pageSize", "500")
+		params.Set("currentPage", strconv.Itoa(page))
+
+		r := &FillPage{}
+		if err := c.do("GET", "/api/v1/fills", params, nil, true, r); err != nil {
+			return nil, err
+		}
+		fills = append(fills, r.Items...)
+
+		if page >= r.TotalPage {
+			return fills, nil
+		}
+	}
+}
+
+// PlaceOrder places a limit order. A random client order id gets used if
+// clientOid is empty.
+func (c *Config) PlaceOrder(symbol string, side string, price *values.Float, size *values.Float, clientOid string) (string, error) {
+	s := c.GetSymbol(symbol)
+	if s == nil {
+		return "", errors.New(fmt.Sprintf("unknown symbol: %s", symbol))
+	}
+	if clientOid == "" {
+		clientOid = newClientOid()
+	}
+
+	body := map[string]string{
+		"clientOid": clientOid,
+		"symbol":    symbol,
+		"side":      side,
+		"type":      "limit",
+		"price":     price.ToPrecision(precision(&s.PriceIncrement)),
+		"size":      size.ToPrecision(precision(&s.BaseIncrement)),
+	}
+
+	r := &PlaceOrderResult{}
+	if err := c.do("POST", "/api/v1/orders", nil, body, true, r); err != nil {
+		return "", err
+	}
+	return r.OrderId, nil
+}
+
+func (c *Config) CancelOrder(id string) error {
+	return c.do("DELETE", "/api/v1/orders/"+id, nil, nil, true, nil)
+}
+
+func (c *Config) GetBulletToken() (*BulletToken, error) {
+	r := &BulletToken{}
+	if err := c.do("POST", "/api/v1/bullet-private", nil, nil, true, r); err != nil {
+		return nil, err
+	}
+	if len(r.InstanceServers) == 0 {
+		return nil, errors.New("no websocket instance server available")
+	}
+	return r, nil
+}
+
+// SetTimeOffset sets the offset of the local clock to the server time in
+// milliseconds, which gets subtracted from the timestamp of signed requests.
+func (c *Config) SetTimeOffset(offset int64) {
+	atomic.StoreInt64(&c.offset, offset)
+}
+
+// now returns the local time corrected by the time offset.
+func (c *Config) now() time.Time {
+	return time.Now().Add(-time.Duration(atomic.LoadInt64(&c.offset)) * time.Millisecond)
+}
+
+func (c *Config) do(method string, resource string, params url.Values, payload interface{}, authNeeded bool, result interface{}) error {
+	if err := c.Limiter.Wait(context.Background(), 1, false); err != nil {
+		return err
+	}
+
+	if len(params) > 0 {
+		resource = resource + "?" + params.Encode()
+	}
+
+	body := ""
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = string(b)
+	}
+
+	req, err := http.NewRequest(method, c.RestEndpoint+resource, bytes.NewBufferString(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Content-Type", "application/json")
+
+	if authNeeded {
+		if len(c.Key) == 0 || len(c.Secret) == 0 || len(c.Passphrase) == 0 {
+			return errors.New("You need to set API Key, API Secret and API Passphrase to call this method")
+		}
+
+		ts := strconv.FormatInt(c.now().UnixNano()/int64(time.Millisecond), 10)
+		req.Header.Add("KC-API-KEY", c.Key)
+		req.Header.Add("KC-API-SIGN", c.sign(ts+method+resource+body))
+		req.Header.Add("KC-API-TIMESTAMP", ts)
+		req.Header.Add("KC-API-PASSPHRASE", c.sign(c.Passphrase))
+		req.Header.Add("KC-API-KEY-VERSION", "2")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	c.Limiter.Observe(resp)
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	r := &Response{}
+	if err := json.Unmarshal(b, r); err != nil {
+		log.Debug(string(b))
+		return errors.New(fmt.Sprintf("%s: %s", resp.Status, string(b)))
+	}
+	if r.Code != "200000" {
+		return errors.New(fmt.Sprintf("%s: %s", r.Code, r.Msg))
+	}
+	if result == nil || len(r.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(r.Data, result)
+}
+
+// sign returns the base64 encoded HMAC-SHA256 of the given payload.
+// Key version 2 requires the passphrase to be signed the same way.
+func (c *Config) sign(payload string) string {
+	mac := hmac.New(sha256.New, []byte(c.Secret))
+	mac.Write([]byte(payload))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func newClientOid() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// precision returns the number of decimals of an increment such as "0.0001".
+func precision(inc *values.Float) int {
+	s := inc.Text('f', -1)
+	if i := strings.Index(s, "."); i >= 0 {
+		return len(s) - i - 1
+	}
+	return 0
+}
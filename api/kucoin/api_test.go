@@ -0,0 +1,230 @@
+package kucoin
+
+import (
+	"../../utils/values"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockServer is a stand-in of the KuCoin rest api. Every private request has
+// to carry a valid signature and passphrase of key version 2.
+type mockServer struct {
+	*httptest.Server
+	t *testing.T
+
+	requests []string
+	mx       sync.Mutex
+}
+
+func newMockServer(t *testing.T, routes map[string]http.HandlerFunc) (*mockServer, *Config) {
+	m := &mockServer{t: t}
+
+	c := NewKucoinApi("key", "secret", "passphrase")
+	// The server keeps its own copy of the credentials
+	signer := &Config{Secret: c.Secret}
+
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.mx.Lock()
+		m.requests = append(m.requests, r.Method+" "+r.URL.RequestURI())
+		m.mx.Unlock()
+
+		if strings.HasPrefix(r.URL.Path, "/api/") && r.URL.Path != "/api/v1/symbols" && r.URL.Path != "/api/v1/timestamp" {
+			b, _ := ioutil.ReadAll(r.Body)
+			ts := r.Header.Get("KC-API-TIMESTAMP")
+			if ms, err := strconv.ParseInt(ts, 10, 64); err != nil || time.Since(time.Unix(0, ms*int64(time.Millisecond))) > time.Minute {
+				t.Errorf("%s: invalid timestamp %q", r.URL.Path, ts)
+			}
+			want := signer.sign(ts + r.Method + r.URL.RequestURI() + string(b))
+			if r.Header.Get("KC-API-KEY") != "key" || r.Header.Get("KC-API-SIGN") != want ||
+				r.Header.Get("KC-API-PASSPHRASE") != signer.sign("passphrase") || r.Header.Get("KC-API-KEY-VERSION") != "2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprint(w, `{"code":"400005","msg":"Invalid KC-API-SIGN"}`)
+				return
+			}
+			r.Body = ioutil.NopCloser(strings.NewReader(string(b)))
+		}
+
+		h, ok := routes[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"code":"404000","msg":"Not Found"}`)
+			return
+		}
+		h(w, r)
+	}))
+
+	c.RestEndpoint = m.URL
+	c.Symbols["DOGE-BTC"] = &Symbol{
+		Symbol:         "DOGE-BTC",
+		BaseCurrency:   "DOGE",
+		QuoteCurrency:  "BTC",
+		BaseIncrement:  *values.NewFloatFromString("0.0001"),
+		PriceIncrement: *values.NewFloatFromString("0.00000001"),
+		MinFunds:       *values.NewFloatFromString("0.00001"),
+	}
+
+	return m, c
+}
+
+func (m *mockServer) Requests() []string {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	return append([]string{}, m.requests...)
+}
+
+func TestSetup(t *testing.T) {
+	m, c := newMockServer(t, map[string]http.HandlerFunc{
+		"/api/v1/symbols": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"code":"200000","data":[{"symbol":"DOGE-BTC","name":"DOGE-BTC","baseCurrency":"DOGE","quoteCurrency":"BTC","baseMinSize":"10","quoteMinSize":"0.00001","baseIncrement":"0.0001","quoteIncrement":"0.00000001","priceIncrement":"0.00000001","minFunds":"0.00001","enableTrading":true}]}`)
+		},
+	})
+	defer m.Close()
+	c.Symbols = make(map[string]*Symbol)
+
+	if err := c.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	s := c.GetSymbol("DOGE-BTC")
+	if s == nil {
+		t.Fatal("symbol missing")
+	}
+	if precision(&s.BaseIncrement) != 4 || precision(&s.PriceIncrement) != 8 || s.MinFunds.ToString() != "0.00001000" {
+		t.Errorf("symbol = %s %s %s", s.BaseIncrement.ToString(), s.PriceIncrement.ToString(), s.MinFunds.ToString())
+	}
+}
+
+func TestPlaceOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		clientOid string
+		response  string
+		id        string
+		err       string
+	}{
+		{"placed", "abc", `{"code":"200000","data":{"orderId":"5bd6e9286d99522a52e458de"}}`, "5bd6e9286d99522a52e458de", ""},
+		{"random client id", "", `{"code":"200000","data":{"orderId":"5bd6e9286d99522a52e458de"}}`, "5bd6e9286d99522a52e458de", ""},
+		{"rejected", "abc", `{"code":"200004","msg":"Balance insufficient!"}`, "", "200004: Balance insufficient!"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]string
+			m, c := newMockServer(t, map[string]http.HandlerFunc{
+				"/api/v1/orders": func(w http.ResponseWriter, r *http.Request) {
+					_ = json.NewDecoder(r.Body).Decode(&body)
+					fmt.Fprint(w, tt.response)
+				},
+			})
+			defer m.Close()
+
+			id, err := c.PlaceOrder("DOGE-BTC", "buy", values.NewFloatFromString("0.00000301"), values.NewFloatFromString("30.12345"), tt.clientOid)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("error = %v, want %s", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != tt.id {
+				t.Errorf("id = %s, want %s", id, tt.id)
+			}
+			want := map[string]string{"symbol": "DOGE-BTC", "side": "buy", "type": "limit", "price": "0.00000301", "size": "30.1234"}
+			if tt.clientOid != "" {
+				want["clientOid"] = tt.clientOid
+			}
+			for k, v := range want {
+				if body[k] != v {
+					t.Errorf("%s = %q, want %q", k, body[k], v)
+				}
+			}
+			if len(body["clientOid"]) == 0 {
+				t.Error("client order id missing")
+			}
+		})
+	}
+}
+
+func TestGetOpenOrdersPages(t *testing.T) {
+	m, c := newMockServer(t, map[string]http.HandlerFunc{
+		"/api/v1/orders": func(w http.ResponseWriter, r *http.Request) {
+			page, _ := strconv.Atoi(r.URL.Query().Get("currentPage"))
+			fmt.Fprintf(w, `{"code":"200000","data":{"currentPage":%d,"pageSize":500,"totalNum":2,"totalPage":2,"items":[{"id":"o%d","clientOid":"c%d","symbol":"DOGE-BTC","side":"buy","type":"limit","price":"0.000003","size":"30","isActive":true,"createdAt":1547026471000}]}}`, page, page, page)
+		},
+	})
+	defer m.Close()
+
+	orders, err := c.GetOpenOrders("DOGE-BTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 2 || orders[1].Id != "o2" || orders[1].ClientOid != "c2" || orders[1].Size.ToString() != "30.00000000" {
+		t.Errorf("orders = %d", len(orders))
+	}
+	if r := m.Requests(); len(r) != 2 || !strings.Contains(r[0], "status=active") || !strings.Contains(r[1], "currentPage=2") {
+		t.Errorf("requests = %v", r)
+	}
+}
+
+func TestInvalidSignature(t *testing.T) {
+	m, c := newMockServer(t, map[string]http.HandlerFunc{
+		"/api/v1/accounts": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"code":"200000","data":[{"id":"5bd6e9286d99522a52e458de","currency":"BTC","type":"trade","balance":"1.5","available":"1.2","holds":"0.3"}]}`)
+		},
+	})
+	defer m.Close()
+
+	balances, err := c.GetBalances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balances["BTC"].ToString() != "1.20000000" {
+		t.Errorf("balance = %s", balances["BTC"].ToString())
+	}
+
+	c.Secret = "wrong"
+	if _, err := c.GetBalances(); err == nil || err.Error() != "400005: Invalid KC-API-SIGN" {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestGetServerTime(t *testing.T) {
+	var ts string
+	m, c := newMockServer(t, map[string]http.HandlerFunc{
+		"/api/v1/timestamp": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"code":"200000","msg":"success","data":1546837113087}`)
+		},
+		"/api/v1/accounts": func(w http.ResponseWriter, r *http.Request) {
+			ts = r.Header.Get("KC-API-TIMESTAMP")
+			fmt.Fprint(w, `{"code":"200000","data":[]}`)
+		},
+	})
+	defer m.Close()
+
+	ms, err := c.GetServerTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ms != 1546837113087 {
+		t.Errorf("server time = %d", ms)
+	}
+
+	// The local clock is 30 seconds ahead
+	c.SetTimeOffset(30000)
+	if _, err := c.GetBalances(); err != nil {
+		t.Fatal(err)
+	}
+	sent, _ := strconv.ParseInt(ts, 10, 64)
+	if d := time.Since(time.Unix(0, sent*int64(time.Millisecond))); d < 29*time.Second || d > 31*time.Second {
+		t.Errorf("timestamp is %s behind, want 30s", d)
+	}
+}
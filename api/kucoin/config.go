@@ -0,0 +1,132 @@
+package kucoin
+
+import (
+	"../../utils/limiter"
+	"../../utils/values"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+type Config struct {
+	Key        string `json:"key"`
+	Secret     string `json:"secret"`
+	Passphrase string `json:"passphrase"`
+
+	RestEndpoint string        `json:"rest-endpoint"`
+	Timeout      time.Duration `json:"timeout"`
+
+	Symbols map[string]*Symbol
+
+	client  *http.Client
+	Limiter *limiter.Limiter
+	// Offset of the local clock in milliseconds, see SetTimeOffset
+	offset int64
+}
+
+type Response struct {
+	Code string          `json:"code"`
+	Msg  string          `json:"msg"`
+	Data json.RawMessage `json:"data"`
+}
+
+type Symbol struct {
+	Symbol         string       `json:"symbol"`
+	Name           string       `json:"name"`
+	BaseCurrency   string       `json:"baseCurrency"`
+	QuoteCurrency  string       `json:"quoteCurrency"`
+	BaseMinSize    values.Float `json:"baseMinSize"`
+	QuoteMinSize   values.Float `json:"quoteMinSize"`
+	BaseIncrement  values.Float `json:"baseIncrement"`
+	QuoteIncrement values.Float `json:"quoteIncrement"`
+	PriceIncrement values.Float `json:"priceIncrement"`
+	MinFunds       values.Float `json:"minFunds"`
+	EnableTrading  bool         `json:"enableTrading"`
+}
+
+type Account struct {
+	Id        string       `json:"id"`
+	Currency  string       `json:"currency"`
+	Type      string       `json:"type"`
+	Balance   values.Float `json:"balance"`
+	Available values.Float `json:"available"`
+	Holds     values.Float `json:"holds"`
+}
+
+type Order struct {
+	Id          string       `json:"id"`
+	ClientOid   string       `json:"clientOid"`
+	Symbol      string       `json:"symbol"`
+	Side        string       `json:"side"`
+	Type        string       `json:"type"`
+	Price       values.Float `json:"price"`
+	Size        values.Float `json:"size"`
+	DealSize    values.Float `json:"dealSize"`
+	DealFunds   values.Float `json:"dealFunds"`
+	Fee         values.Float `json:"fee"`
+	IsActive    bool         `json:"isActive"`
+	CancelExist bool         `json:"cancelExist"`
+	CreatedAt   int64        `json:"createdAt"`
+}
+
+type OrderPage struct {
+	CurrentPage int      `json:"currentPage"`
+	PageSize    int      `json:"pageSize"`
+	TotalNum    int      `json:"totalNum"`
+	TotalPage   int      `json:"totalPage"`
+	Items       []*Order `json:"items"`
+}
+
+type Fill struct {
+	TradeId   string       `json:"tradeId"`
+	OrderId   string       `json:"orderId"`
+	Symbol    string       `json:"symbol"`
+	Side      string       `json:"side"`
+	Price     values.Float `json:"price"`
+	Size      values.Float `json:"size"`
+	CreatedAt int64        `json:"createdAt"`
+}
+
+type FillPage struct {
+	CurrentPage int     `json:"currentPage"`
+	TotalPage   int     `json:"totalPage"`
+	Items       []*Fill `json:"items"`
+}
+
+type PlaceOrderResult struct {
+	OrderId string `json:"orderId"`
+}
+
+type InstanceServer struct {
+	Endpoint     string `json:"endpoint"`
+	Encrypt      bool   `json:"encrypt"`
+	Protocol     string `json:"protocol"`
+	PingInterval int64  `json:"pingInterval"`
+	PingTimeout  int64  `json:"pingTimeout"`
+}
+
+type BulletToken struct {
+	Token           string            `json:"token"`
+	InstanceServers []*InstanceServer `json:"instanceServers"`
+}
+
+// OrderChange is a message of the private "/spotMarket/tradeOrders" topic.
+// Type is one of "open", "match", "update", "filled" or "canceled".
+type OrderChange struct {
+	Symbol     string       `json:"symbol"`
+	OrderType  string       `json:"orderType"`
+	Side       string       `json:"side"`
+	OrderId    string       `json:"orderId"`
+	ClientOid  string       `json:"clientOid"`
+	Type       string       `json:"type"`
+	Status     string       `json:"status"`
+	OrderTime  int64        `json:"orderTime"`
+	Price      values.Float `json:"price"`
+	Size       values.Float `json:"size"`
+	FilledSize values.Float `json:"filledSize"`
+	RemainSize values.Float `json:"remainSize"`
+	MatchPrice values.Float `json:"matchPrice"`
+	MatchSize  values.Float `json:"matchSize"`
+	TradeId    string       `json:"tradeId"`
+	Ts         int64        `json:"ts"`
+}
@@ -0,0 +1,116 @@
+package kucoin
+
+import (
+	"../../utils/log"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"strconv"
+	"time"
+)
+
+const tradeOrdersTopic = "/spotMarket/tradeOrders"
+
+type wsMessage struct {
+	Id      string          `json:"id"`
+	Type    string          `json:"type"`
+	Topic   string          `json:"topic"`
+	Subject string          `json:"subject"`
+	Code    int             `json:"code"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// SubscribeOrders subscribes to the private "/spotMarket/tradeOrders" topic
+// and blocks until the connection fails or stopCh receives a value.
+func (c *Config) SubscribeOrders(updatesCh chan<- OrderChange, stopCh <-chan bool) error {
+	bullet, err := c.GetBulletToken()
+	if err != nil {
+		return err
+	}
+	server := bullet.InstanceServers[0]
+
+	connectId := strconv.FormatInt(time.Now().UnixNano(), 10)
+	endpoint := fmt.Sprintf("%s?token=%s&connectId=%s", server.Endpoint, bullet.Token, connectId)
+	conn, _, err := websocket.DefaultDialer.Dial(endpoint, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	timeout := time.Duration(server.PingInterval+server.PingTimeout) * time.Millisecond
+	interval := time.Duration(server.PingInterval) * time.Millisecond
+	if interval <= 0 {
+		interval = 18 * time.Second
+		timeout = 28 * time.Second
+	}
+
+	err = conn.WriteJSON(map[string]interface{}{
+		"id":             connectId,
+		"type":           "subscribe",
+		"topic":          tradeOrdersTopic,
+		"privateChannel": true,
+		"response":       true,
+	})
+	if err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		for {
+			_ = conn.SetReadDeadline(time.Now().Add(timeout))
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				errCh <- err
+				return
+			}
+			if err := handleMessage(message, updatesCh); err != nil {
+				log.Error(err)
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return nil
+		case err := <-errCh:
+			return err
+		case t := <-ticker.C:
+			err := conn.WriteJSON(map[string]interface{}{
+				"id":   strconv.FormatInt(t.UnixNano(), 10),
+				"type": "ping",
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func handleMessage(message []byte, updatesCh chan<- OrderChange) error {
+	msg := &wsMessage{}
+	if err := json.Unmarshal(message, msg); err != nil {
+		return err
+	}
+
+	switch msg.Type {
+	case "error":
+		return errors.New(fmt.Sprintf("websocket error %d: %s", msg.Code, string(msg.Data)))
+	case "message":
+		if msg.Topic != tradeOrdersTopic {
+			return nil
+		}
+		upd := OrderChange{}
+		if err := json.Unmarshal(msg.Data, &upd); err != nil {
+			return err
+		}
+		updatesCh <- upd
+	}
+	return nil
+}
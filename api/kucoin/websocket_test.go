@@ -0,0 +1,137 @@
+package kucoin
+
+import (
+	"fmt"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Frames as pushed by the KuCoin "/spotMarket/tradeOrders" topic
+const (
+	frameWelcome = `{"id":"hQvf8jkno","type":"welcome"}`
+	frameOpen    = `{"type":"message","topic":"/spotMarket/tradeOrders","subject":"orderChange","channelType":"private","data":{"symbol":"DOGE-BTC","orderType":"limit","side":"buy","orderId":"5efab07953bdea00089965d2","clientOid":"abc","type":"open","status":"open","orderTime":1593487481683297666,"price":"0.00000301","size":"30","filledSize":"0","remainSize":"30","ts":1593487481683297666}}`
+	frameMatch   = `{"type":"message","topic":"/spotMarket/tradeOrders","subject":"orderChange","channelType":"private","data":{"symbol":"DOGE-BTC","orderType":"limit","side":"buy","orderId":"5efab07953bdea00089965d2","clientOid":"abc","type":"match","status":"match","orderTime":1593487481683297666,"price":"0.00000301","size":"30","filledSize":"10","remainSize":"20","matchPrice":"0.00000301","matchSize":"10","tradeId":"5efab07a4ee4c7000a82d6d9","ts":1593487482038606180}}`
+	frameFilled  = `{"type":"message","topic":"/spotMarket/tradeOrders","subject":"orderChange","channelType":"private","data":{"symbol":"DOGE-BTC","orderType":"limit","side":"buy","orderId":"5efab07953bdea00089965d2","clientOid":"abc","type":"filled","status":"done","orderTime":1593487481683297666,"price":"0.00000301","size":"30","filledSize":"30","remainSize":"0","ts":1593487482038606180}}`
+	frameWsError = `{"id":"1","type":"error","code":401,"data":"token is expired"}`
+)
+
+func TestHandleMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		kind    string
+		filled  string
+		err     bool
+	}{
+		{"open", frameOpen, "open", "0.00000000", false},
+		{"match", frameMatch, "match", "10.00000000", false},
+		{"filled", frameFilled, "filled", "30.00000000", false},
+		{"welcome", frameWelcome, "", "", false},
+		{"ack", `{"id":"1","type":"ack"}`, "", "", false},
+		{"pong", `{"id":"1","type":"pong"}`, "", "", false},
+		{"error", frameWsError, "", "", true},
+		{"other topic", `{"type":"message","topic":"/account/balance","data":{"currency":"BTC"}}`, "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updatesCh := make(chan OrderChange, 1)
+			err := handleMessage([]byte(tt.message), updatesCh)
+			if (err != nil) != tt.err {
+				t.Fatalf("error = %v", err)
+			}
+
+			select {
+			case o := <-updatesCh:
+				if o.Type != tt.kind || o.FilledSize.ToString() != tt.filled {
+					t.Errorf("order = %s %s, want %s %s", o.Type, o.FilledSize.ToString(), tt.kind, tt.filled)
+				}
+				if o.OrderId != "5efab07953bdea00089965d2" || o.ClientOid != "abc" || o.Price.ToString() != "0.00000301" {
+					t.Errorf("order = %s %s %s", o.OrderId, o.ClientOid, o.Price.ToString())
+				}
+			default:
+				if tt.kind != "" {
+					t.Errorf("no update received")
+				}
+			}
+		})
+	}
+}
+
+// wsRoutes hand out a bullet token for the websocket, which pushes the given
+// frames after the subscription got acknowledged.
+func wsRoutes(t *testing.T, m **mockServer, frames ...string) map[string]http.HandlerFunc {
+	upgrader := websocket.Upgrader{}
+
+	return map[string]http.HandlerFunc{
+		"/api/v1/bullet-private": func(w http.ResponseWriter, r *http.Request) {
+			endpoint := "ws" + strings.TrimPrefix((*m).URL, "http") + "/ws"
+			fmt.Fprintf(w, `{"code":"200000","data":{"token":"2neAiuYvAU61ZD","instanceServers":[{"endpoint":"%s","encrypt":true,"protocol":"websocket","pingInterval":18000,"pingTimeout":10000}]}}`, endpoint)
+		},
+		"/ws": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("token") != "2neAiuYvAU61ZD" || r.URL.Query().Get("connectId") == "" {
+				t.Errorf("websocket query = %s", r.URL.RawQuery)
+			}
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer conn.Close()
+
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(frameWelcome))
+
+			sub := map[string]interface{}{}
+			if err := conn.ReadJSON(&sub); err != nil || sub["type"] != "subscribe" || sub["topic"] != tradeOrdersTopic || sub["privateChannel"] != true {
+				t.Errorf("subscription = %v, %v", sub, err)
+				return
+			}
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"id":"%s","type":"ack"}`, sub["id"])))
+
+			for _, f := range frames {
+				if err := conn.WriteMessage(websocket.TextMessage, []byte(f)); err != nil {
+					return
+				}
+			}
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		},
+	}
+}
+
+func TestSubscribeOrders(t *testing.T) {
+	var m *mockServer
+	m, c := newMockServer(t, wsRoutes(t, &m, frameOpen, frameMatch, frameFilled))
+	defer m.Close()
+
+	updatesCh := make(chan OrderChange, 8)
+	stopCh := make(chan bool)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.SubscribeOrders(updatesCh, stopCh)
+	}()
+
+	for _, kind := range []string{"open", "match", "filled"} {
+		select {
+		case o := <-updatesCh:
+			if o.Type != kind {
+				t.Errorf("type = %s, want %s", o.Type, kind)
+			}
+		case err := <-errCh:
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s update not received", kind)
+		}
+	}
+
+	stopCh <- true
+	if err := <-errCh; err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,347 @@
+package okx
+
+import (
+	"../../utils/limiter"
+	"../../utils/log"
+	"../../utils/values"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// Placing orders is limited to 60 requests per 2 seconds.
+	reqInterval = 50 * time.Millisecond
+)
+
+// orderNotFound is the error code of an unknown order.
+const orderNotFound = "51603"
+
+func NewOkxApi(key string, secret string, passphrase string) *Config {
+	return &Config{
+		Key:               key,
+		Secret:            secret,
+		Passphrase:        passphrase,
+		RestEndpoint:      "https://www.okx.com",
+		WebsocketEndpoint: "wss://ws.okx.com:8443/ws/v5/private",
+		Timeout:           time.Second * 30,
+		Instruments:       make(map[string]*Instrument),
+		client: &http.Client{
+			Timeout: time.Second * 30,
+		},
+		Limiter: limiter.New(limiter.Config{Name: "okx", Interval: reqInterval}),
+	}
+}
+
+func (c *Config) Setup() error {
+	params := url.Values{}
+	params.Set("instType", "SPOT")
+
+	r := make([]*Instrument, 0)
+	if err := c.do("GET", "/api/v5/public/instruments", params, nil, false, &r); err != nil {
+		return err
+	}
+
+	instruments := make(map[string]*Instrument)
+	for _, i := range r {
+		instruments[i.InstId] = i
+	}
+	c.Instruments = instruments
+	return nil
+}
+
+// GetServerTime returns the server time in milliseconds.
+func (c *Config) GetServerTime() (int64, error) {
+	r := make([]*ServerTime, 0)
+	if err := c.do("GET", "/api/v5/public/time", nil, nil, false, &r); err != nil {
+		return 0, err
+	}
+	if len(r) == 0 {
+		return 0, errors.New("no server time returned")
+	}
+	return strconv.ParseInt(r[0].Ts, 10, 64)
+}
+
+func (c *Config) GetInstrument(instId string) *Instrument {
+	if i, ok := c.Instruments[instId]; ok {
+		return i
+	}
+	return nil
+}
+
+// GetTicker returns the latest price of the given instrument.
+func (c *Config) GetTicker(instId string) (*Ticker, error) {
+	params := url.Values{}
+	params.Set("instId", instId)
+
+	r := make([]*Ticker, 0)
+	if err := c.do("GET", "/api/v5/market/ticker", params, nil, false, &r); err != nil {
+		return nil, err
+	}
+	if len(r) == 0 {
+		return nil, errors.New(fmt.Sprintf("no ticker for instrument: %s", instId))
+	}
+	return r[0], nil
+}
+
+// GetBalances returns the available balance of every currency.
+func (c *Config) GetBalances() (map[string]*values.Float, error) {
+	r := make([]*Balance, 0)
+	if err := c.do("GET", "/api/v5/account/balance", nil, nil, true, &r); err != nil {
+		return nil, err
+	}
+
+	balances := make(map[string]*values.Float)
+	for _, b := range r {
+		for _, d := range b.Details {
+			balances[d.Ccy] = values.NewFloat(&d.AvailBal.Float)
+		}
+	}
+	return balances, nil
+}
+
+func (c *Config) GetOpenOrders(instId string) ([]*Order, error) {
+	orders := make([]*Order, 0)
+	params := url.Values{}
+	params.Set("instType", "SPOT")
+	params.Set("instId", instId)
+
+	for {
+		r := make([]*Order, 0)
+		if err := c.do("GET", "/api/v5/trade/orders-pending", params, nil, true, &r); err != nil {
+			return nil, err
+		}
+		orders = append(orders, r...)
+
+		// Pages contain up to 100 orders, older ones are requested by id
+		if len(r) < 100 {
+			return orders, nil
+		}
+		params.Set("after", r[len(r)-1].OrdId)
+	}
+}
+
+// GetOrder returns the order with the given id, including filled and
+// canceled orders.
+func (c *Config) GetOrder(instId string, ordId string) (*Order, error) {
+	params := url.Values{}
+	params.Set("instId", instId)
+	params.Set("ordId", ordId)
+
+	r := make([]*Order, 0)
+	if err := c.do("GET", "/api/v5/trade/order", params, nil, true, &r); err != nil {
+		return nil, err
+	}
+	if len(r) == 0 {
+		return nil, errors.New(fmt.Sprintf("order not found: %s", ordId))
+	}
+	return r[0], nil
+}
+
+// GetOrderByClientId returns the order with the given client order id,
+// including filled and canceled orders. Nil is returned if there is none.
+func (c *Config) GetOrderByClientId(instId string, clOrdId string) (*Order, error) {
+	params := url.Values{}
+	params.Set("instId", instId)
+	params.Set("clOrdId", clOrdId)
+
+	r := make([]*Order, 0)
+	if err := c.do("GET", "/api/v5/trade/order", params, nil, true, &r); err != nil {
+		if strings.HasPrefix(err.Error(), orderNotFound+":") {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(r) == 0 {
+		return nil, nil
+	}
+	return r[0], nil
+}
+
+// GetFills returns all fills of the given instrument since the given time.
+func (c *Config) GetFills(instId string, since time.Time) ([]*Fill, error) {
+	fills := make([]*Fill, 0)
+	params := url.Values{}
+	params.Set("instType", "SPOT")
+	params.Set("instId", instId)
+	params.Set("begin", strconv.FormatInt(since.UnixNano()/int64(time.Millisecond), 10))
+
+	for {
+		r := make([]*Fill, 0)
+		if err := c.do("GET", "/api/v5/trade/fills-history", params, nil, true, &r); err != nil {
+			return nil, err
+		}
+		fills = append(fills, r...)
+
+		// Pages contain up to 100 fills, older ones are requested by bill id
+		if len(r) < 100 {
+			return fills, nil
+		}
+		params.Set("after", r[len(r)-1].BillId)
+	}
+}
+
+// PlaceOrder places a limit order. The client order id may only contain
+// letters and digits, a random one gets used if clOrdId is empty.
+func (c *Config) PlaceOrder(instId string, side string, price *values.Float, size *values.Float, clOrdId string) (string, error) {
+	i := c.GetInstrument(instId)
+	if i == nil {
+		return "", errors.New(fmt.Sprintf("unknown instrument: %s", instId))
+	}
+
+	if clOrdId == "" {
+		b := make([]byte, 16)
+		_, _ = rand.Read(b)
+		clOrdId = hex.EncodeToString(b)
+	}
+
+	body := map[string]string{
+		"instId":  instId,
+		"tdMode":  "cash",
+		"clOrdId": clOrdId,
+		"side":    side,
+		"ordType": "limit",
+		"px":      price.ToPrecision(precision(&i.TickSz)),
+		"sz":      size.ToPrecision(precision(&i.LotSz)),
+	}
+
+	r := make([]*OrderResult, 0)
+	if err := c.do("POST", "/api/v5/trade/order", nil, body, true, &r); err != nil {
+		return "", err
+	}
+	if len(r) == 0 {
+		return "", errors.New("no order id returned")
+	}
+	if r[0].SCode != "0" {
+		return "", errors.New(fmt.Sprintf("%s: %s", r[0].SCode, r[0].SMsg))
+	}
+	return r[0].OrdId, nil
+}
+
+func (c *Config) CancelOrder(instId string, ordId string) error {
+	body := map[string]string{
+		"instId": instId,
+		"ordId":  ordId,
+	}
+
+	r := make([]*OrderResult, 0)
+	if err := c.do("POST", "/api/v5/trade/cancel-order", nil, body, true, &r); err != nil {
+		return err
+	}
+	if len(r) > 0 && r[0].SCode != "0" {
+		return errors.New(fmt.Sprintf("%s: %s", r[0].SCode, r[0].SMsg))
+	}
+	return nil
+}
+
+// SetTimeOffset sets the offset of the local clock to the server time in
+// milliseconds, which gets subtracted from the timestamp of signed requests.
+func (c *Config) SetTimeOffset(offset int64) {
+	atomic.StoreInt64(&c.offset, offset)
+}
+
+// now returns the local time corrected by the time offset.
+func (c *Config) now() time.Time {
+	return time.Now().Add(-time.Duration(atomic.LoadInt64(&c.offset)) * time.Millisecond)
+}
+
+func (c *Config) do(method string, resource string, params url.Values, payload interface{}, authNeeded bool, result interface{}) error {
+	if err := c.Limiter.Wait(context.Background(), 1, false); err != nil {
+		return err
+	}
+
+	if len(params) > 0 {
+		resource = resource + "?" + params.Encode()
+	}
+
+	body := ""
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = string(b)
+	}
+
+	req, err := http.NewRequest(method, c.RestEndpoint+resource, bytes.NewBufferString(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Content-Type", "application/json")
+	if c.Simulated {
+		req.Header.Add("x-simulated-trading", "1")
+	}
+
+	if authNeeded {
+		if len(c.Key) == 0 || len(c.Secret) == 0 || len(c.Passphrase) == 0 {
+			return errors.New("You need to set API Key, API Secret and API Passphrase to call this method")
+		}
+
+		ts := c.now().UTC().Format("2006-01-02T15:04:05.000Z")
+		req.Header.Add("OK-ACCESS-KEY", c.Key)
+		req.Header.Add("OK-ACCESS-SIGN", c.sign(ts+method+resource+body))
+		req.Header.Add("OK-ACCESS-TIMESTAMP", ts)
+		req.Header.Add("OK-ACCESS-PASSPHRASE", c.Passphrase)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	c.Limiter.Observe(resp)
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	r := &Response{}
+	if err := json.Unmarshal(b, r); err != nil {
+		log.Debug(string(b))
+		return errors.New(fmt.Sprintf("%s: %s", resp.Status, string(b)))
+	}
+	if r.Code != "0" {
+		// Batch style endpoints report the actual reason per entry
+		res := make([]*OrderResult, 0)
+		if err := json.Unmarshal(r.Data, &res); err == nil && len(res) > 0 && res[0].SMsg != "" {
+			return errors.New(fmt.Sprintf("%s: %s", res[0].SCode, res[0].SMsg))
+		}
+		return errors.New(fmt.Sprintf("%s: %s", r.Code, r.Msg))
+	}
+	if result == nil || len(r.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(r.Data, result)
+}
+
+// sign returns the base64 encoded HMAC-SHA256 of the given payload.
+func (c *Config) sign(payload string) string {
+	mac := hmac.New(sha256.New, []byte(c.Secret))
+	mac.Write([]byte(payload))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// precision returns the number of decimals of a size such as "0.0001".
+func precision(inc *values.Float) int {
+	s := inc.Text('f', -1)
+	if i := strings.Index(s, "."); i >= 0 {
+		return len(s) - i - 1
+	}
+	return 0
+}
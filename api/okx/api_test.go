@@ -0,0 +1,294 @@
+package okx
+
+import (
+	"../../utils/values"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockServer is a stand-in of the OKX rest and websocket api. Every private
+// request has to carry a valid signature.
+type mockServer struct {
+	*httptest.Server
+	t *testing.T
+
+	requests []string
+	mx       sync.Mutex
+}
+
+func newMockServer(t *testing.T, routes map[string]http.HandlerFunc) (*mockServer, *Config) {
+	m := &mockServer{t: t}
+
+	c := NewOkxApi("key", "secret", "passphrase")
+	// The server keeps its own copy of the credentials
+	signer := &Config{Secret: c.Secret}
+
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.mx.Lock()
+		m.requests = append(m.requests, r.Method+" "+r.URL.RequestURI())
+		m.mx.Unlock()
+
+		if strings.HasPrefix(r.URL.Path, "/api/v5/trade") || strings.HasPrefix(r.URL.Path, "/api/v5/account") {
+			b, _ := ioutil.ReadAll(r.Body)
+			ts := r.Header.Get("OK-ACCESS-TIMESTAMP")
+			if _, err := time.Parse("2006-01-02T15:04:05.000Z", ts); err != nil {
+				t.Errorf("%s: invalid timestamp %q", r.URL.Path, ts)
+			}
+			want := signer.sign(ts + r.Method + r.URL.RequestURI() + string(b))
+			if r.Header.Get("OK-ACCESS-SIGN") != want || r.Header.Get("OK-ACCESS-PASSPHRASE") != "passphrase" {
+				fmt.Fprint(w, `{"code":"50113","msg":"Invalid Sign","data":[]}`)
+				return
+			}
+			r.Body = ioutil.NopCloser(strings.NewReader(string(b)))
+		}
+
+		h, ok := routes[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"code":"50000","msg":"not found","data":[]}`)
+			return
+		}
+		h(w, r)
+	}))
+
+	c.RestEndpoint = m.URL
+	c.WebsocketEndpoint = "ws" + strings.TrimPrefix(m.URL, "http") + "/ws/v5/private"
+	c.Instruments["DOGE-BTC"] = &Instrument{
+		InstId:   "DOGE-BTC",
+		BaseCcy:  "DOGE",
+		QuoteCcy: "BTC",
+		LotSz:    *values.NewFloatFromString("0.0001"),
+		TickSz:   *values.NewFloatFromString("0.00000001"),
+		MinSz:    *values.NewFloatFromString("1"),
+	}
+
+	return m, c
+}
+
+func (m *mockServer) Requests() []string {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	return append([]string{}, m.requests...)
+}
+
+func TestSetup(t *testing.T) {
+	m, c := newMockServer(t, map[string]http.HandlerFunc{
+		"/api/v5/public/instruments": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"code":"0","msg":"","data":[{"instType":"SPOT","instId":"DOGE-BTC","baseCcy":"DOGE","quoteCcy":"BTC","lotSz":"0.0001","tickSz":"0.00000001","minSz":"1","state":"live"}]}`)
+		},
+	})
+	defer m.Close()
+	c.Instruments = make(map[string]*Instrument)
+
+	if err := c.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	i := c.GetInstrument("DOGE-BTC")
+	if i == nil {
+		t.Fatal("instrument missing")
+	}
+	if precision(&i.LotSz) != 4 || precision(&i.TickSz) != 8 {
+		t.Errorf("precision = %d, %d", precision(&i.LotSz), precision(&i.TickSz))
+	}
+	if r := m.Requests(); len(r) != 1 || r[0] != "GET /api/v5/public/instruments?instType=SPOT" {
+		t.Errorf("requests = %v", r)
+	}
+}
+
+func TestPlaceOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		id       string
+		err      string
+	}{
+		{"placed", `{"code":"0","msg":"","data":[{"ordId":"312269865356374016","clOrdId":"abc","sCode":"0","sMsg":""}]}`, "312269865356374016", ""},
+		{"rejected", `{"code":"1","msg":"Operation failed.","data":[{"ordId":"","clOrdId":"abc","sCode":"51008","sMsg":"Order failed. Insufficient balance."}]}`, "", "51008: Order failed. Insufficient balance."},
+		{"failed", `{"code":"50011","msg":"Rate limit reached.","data":[]}`, "", "50011: Rate limit reached."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]string
+			m, c := newMockServer(t, map[string]http.HandlerFunc{
+				"/api/v5/trade/order": func(w http.ResponseWriter, r *http.Request) {
+					_ = json.NewDecoder(r.Body).Decode(&body)
+					fmt.Fprint(w, tt.response)
+				},
+			})
+			defer m.Close()
+
+			id, err := c.PlaceOrder("DOGE-BTC", "buy", values.NewFloatFromString("0.00000301"), values.NewFloatFromString("30.1234"), "abc")
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("error = %v, want %s", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != tt.id {
+				t.Errorf("id = %s, want %s", id, tt.id)
+			}
+			want := map[string]string{"instId": "DOGE-BTC", "tdMode": "cash", "clOrdId": "abc", "side": "buy", "ordType": "limit", "px": "0.00000301", "sz": "30.1234"}
+			for k, v := range want {
+				if body[k] != v {
+					t.Errorf("%s = %q, want %q", k, body[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestGetOpenOrdersPages(t *testing.T) {
+	m, c := newMockServer(t, map[string]http.HandlerFunc{
+		"/api/v5/trade/orders-pending": func(w http.ResponseWriter, r *http.Request) {
+			n, offset := 100, 0
+			if r.URL.Query().Get("after") == "99" {
+				n, offset = 3, 100
+			}
+			orders := make([]string, 0)
+			for i := 0; i < n; i++ {
+				orders = append(orders, fmt.Sprintf(`{"instId":"DOGE-BTC","ordId":"%d","side":"buy","px":"0.000003","sz":"30","accFillSz":"0","state":"live"}`, offset+i))
+			}
+			fmt.Fprintf(w, `{"code":"0","msg":"","data":[%s]}`, strings.Join(orders, ","))
+		},
+	})
+	defer m.Close()
+
+	orders, err := c.GetOpenOrders("DOGE-BTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 103 {
+		t.Errorf("orders = %d, want 103", len(orders))
+	}
+	if r := m.Requests(); len(r) != 2 || !strings.Contains(r[1], "after=99") {
+		t.Errorf("requests = %v", r)
+	}
+}
+
+func TestInvalidSignature(t *testing.T) {
+	m, c := newMockServer(t, map[string]http.HandlerFunc{
+		"/api/v5/account/balance": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"code":"0","msg":"","data":[{"details":[{"ccy":"BTC","availBal":"1.5","cashBal":"1.5"}]}]}`)
+		},
+	})
+	defer m.Close()
+
+	balances, err := c.GetBalances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balances["BTC"].ToString() != "1.50000000" {
+		t.Errorf("balance = %s", balances["BTC"].ToString())
+	}
+
+	c.Secret = "wrong"
+	if _, err := c.GetBalances(); err == nil || err.Error() != "50113: Invalid Sign" {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestGetTicker(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		last     string
+		err      bool
+	}{
+		{"ticker", `{"code":"0","msg":"","data":[{"instType":"SPOT","instId":"DOGE-BTC","last":"0.00000301","askPx":"0.00000302","bidPx":"0.000003","ts":"1597026383085"}]}`, "0.00000301", false},
+		{"unknown instrument", `{"code":"51001","msg":"Instrument ID does not exist","data":[]}`, "", true},
+		{"empty", `{"code":"0","msg":"","data":[]}`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, c := newMockServer(t, map[string]http.HandlerFunc{
+				"/api/v5/market/ticker": func(w http.ResponseWriter, r *http.Request) {
+					fmt.Fprint(w, tt.response)
+				},
+			})
+			defer m.Close()
+
+			ticker, err := c.GetTicker("DOGE-BTC")
+			if (err != nil) != tt.err {
+				t.Fatalf("error = %v", err)
+			}
+			if err == nil && ticker.Last.ToString() != tt.last {
+				t.Errorf("last = %s, want %s", ticker.Last.ToString(), tt.last)
+			}
+			if r := m.Requests(); len(r) != 1 || r[0] != "GET /api/v5/market/ticker?instId=DOGE-BTC" {
+				t.Errorf("requests = %v", r)
+			}
+		})
+	}
+}
+
+func TestGetOrder(t *testing.T) {
+	m, c := newMockServer(t, map[string]http.HandlerFunc{
+		"/api/v5/trade/order": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("ordId") != "312269865356374016" {
+				fmt.Fprint(w, `{"code":"51603","msg":"Order does not exist","data":[]}`)
+				return
+			}
+			fmt.Fprint(w, `{"code":"0","msg":"","data":[{"instId":"DOGE-BTC","ordId":"312269865356374016","clOrdId":"abc","side":"buy","ordType":"limit","px":"0.00000301","sz":"30","accFillSz":"30","state":"filled"}]}`)
+		},
+	})
+	defer m.Close()
+
+	o, err := c.GetOrder("DOGE-BTC", "312269865356374016")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.State != "filled" || o.AccFillSz.ToString() != "30.00000000" {
+		t.Errorf("order = %s %s", o.State, o.AccFillSz.ToString())
+	}
+	if r := m.Requests(); len(r) != 1 || r[0] != "GET /api/v5/trade/order?instId=DOGE-BTC&ordId=312269865356374016" {
+		t.Errorf("requests = %v", r)
+	}
+
+	if _, err := c.GetOrder("DOGE-BTC", "1"); err == nil || err.Error() != "51603: Order does not exist" {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestGetServerTime(t *testing.T) {
+	var ts string
+	m, c := newMockServer(t, map[string]http.HandlerFunc{
+		"/api/v5/public/time": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"code":"0","msg":"","data":[{"ts":"1597026383085"}]}`)
+		},
+		"/api/v5/account/balance": func(w http.ResponseWriter, r *http.Request) {
+			ts = r.Header.Get("OK-ACCESS-TIMESTAMP")
+			fmt.Fprint(w, `{"code":"0","msg":"","data":[]}`)
+		},
+	})
+	defer m.Close()
+
+	ms, err := c.GetServerTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ms != 1597026383085 {
+		t.Errorf("server time = %d", ms)
+	}
+
+	// The local clock is 30 seconds ahead
+	c.SetTimeOffset(30000)
+	if _, err := c.GetBalances(); err != nil {
+		t.Fatal(err)
+	}
+	sent, _ := time.Parse("2006-01-02T15:04:05.000Z", ts)
+	if d := time.Since(sent); d < 29*time.Second || d > 31*time.Second {
+		t.Errorf("timestamp is %s behind, want 30s", d)
+	}
+}
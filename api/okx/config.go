@@ -0,0 +1,116 @@
+package okx
+
+import (
+	"../../utils/limiter"
+	"../../utils/values"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+type Config struct {
+	Key        string `json:"key"`
+	Secret     string `json:"secret"`
+	Passphrase string `json:"passphrase"`
+
+	RestEndpoint      string        `json:"rest-endpoint"`
+	WebsocketEndpoint string        `json:"wss-endpoint"`
+	Timeout           time.Duration `json:"timeout"`
+
+	// Simulated sends every request to the demo trading environment
+	Simulated bool `json:"simulated"`
+
+	Instruments map[string]*Instrument
+
+	client  *http.Client
+	Limiter *limiter.Limiter
+	// Offset of the local clock in milliseconds, see SetTimeOffset
+	offset int64
+}
+
+type Response struct {
+	Code string          `json:"code"`
+	Msg  string          `json:"msg"`
+	Data json.RawMessage `json:"data"`
+}
+
+type Instrument struct {
+	InstId   string       `json:"instId"`
+	BaseCcy  string       `json:"baseCcy"`
+	QuoteCcy string       `json:"quoteCcy"`
+	LotSz    values.Float `json:"lotSz"`
+	TickSz   values.Float `json:"tickSz"`
+	MinSz    values.Float `json:"minSz"`
+	State    string       `json:"state"`
+}
+
+type Ticker struct {
+	InstId string       `json:"instId"`
+	Last   values.Float `json:"last"`
+}
+
+type BalanceDetail struct {
+	Ccy      string       `json:"ccy"`
+	AvailBal values.Float `json:"availBal"`
+	CashBal  values.Float `json:"cashBal"`
+}
+
+type Balance struct {
+	Details []*BalanceDetail `json:"details"`
+}
+
+// Order is used by the REST api as well as by the "orders" websocket channel.
+// State is one of "live", "partially_filled", "filled" or "canceled".
+type Order struct {
+	InstId    string       `json:"instId"`
+	OrdId     string       `json:"ordId"`
+	ClOrdId   string       `json:"clOrdId"`
+	Side      string       `json:"side"`
+	OrdType   string       `json:"ordType"`
+	Px        values.Float `json:"px"`
+	Sz        values.Float `json:"sz"`
+	AccFillSz values.Float `json:"accFillSz"`
+	FillSz    values.Float `json:"fillSz"`
+	FillPx    values.Float `json:"fillPx"`
+	Fee       values.Float `json:"fee"`
+	FeeCcy    string       `json:"feeCcy"`
+	State     string       `json:"state"`
+	CTime     string       `json:"cTime"`
+	UTime     string       `json:"uTime"`
+}
+
+type Fill struct {
+	InstId  string       `json:"instId"`
+	TradeId string       `json:"tradeId"`
+	OrdId   string       `json:"ordId"`
+	BillId  string       `json:"billId"`
+	Side    string       `json:"side"`
+	FillPx  values.Float `json:"fillPx"`
+	FillSz  values.Float `json:"fillSz"`
+	Ts      string       `json:"ts"`
+}
+
+type OrderResult struct {
+	OrdId   string `json:"ordId"`
+	ClOrdId string `json:"clOrdId"`
+	SCode   string `json:"sCode"`
+	SMsg    string `json:"sMsg"`
+}
+
+type wsMessage struct {
+	Event string          `json:"event"`
+	Code  string          `json:"code"`
+	Msg   string          `json:"msg"`
+	Arg   wsArg           `json:"arg"`
+	Data  json.RawMessage `json:"data"`
+}
+
+type wsArg struct {
+	Channel  string `json:"channel"`
+	InstType string `json:"instType"`
+	InstId   string `json:"instId,omitempty"`
+}
+
+type ServerTime struct {
+	Ts string `json:"ts"`
+}
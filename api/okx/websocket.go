@@ -0,0 +1,132 @@
+package okx
+
+import (
+	"../../utils/log"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"strconv"
+	"time"
+)
+
+// The connection gets closed by OKX if nothing was sent within 30 seconds.
+const (
+	wsTimeout      = 30 * time.Second
+	wsPingInterval = 20 * time.Second
+)
+
+// SubscribeOrders logs in and subscribes to the private "orders" channel of
+// all spot instruments. It blocks until the connection fails or stopCh
+// receives a value.
+func (c *Config) SubscribeOrders(updatesCh chan<- Order, stopCh <-chan bool) error {
+	conn, _, err := websocket.DefaultDialer.Dial(c.WebsocketEndpoint, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := c.login(conn); err != nil {
+		return err
+	}
+
+	err = conn.WriteJSON(map[string]interface{}{
+		"op": "subscribe",
+		"args": []wsArg{
+			{Channel: "orders", InstType: "SPOT"},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		for {
+			_ = conn.SetReadDeadline(time.Now().Add(wsTimeout))
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				errCh <- err
+				return
+			}
+			if err := handleMessage(message, updatesCh); err != nil {
+				log.Error(err)
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return nil
+		case err := <-errCh:
+			return err
+		case <-ticker.C:
+			if err := conn.WriteMessage(websocket.TextMessage, []byte("ping")); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// login authenticates the connection and waits for the confirmation.
+func (c *Config) login(conn *websocket.Conn) error {
+	ts := strconv.FormatInt(c.now().Unix(), 10)
+	err := conn.WriteJSON(map[string]interface{}{
+		"op": "login",
+		"args": []map[string]string{{
+			"apiKey":     c.Key,
+			"passphrase": c.Passphrase,
+			"timestamp":  ts,
+			"sign":       c.sign(ts + "GET" + "/users/self/verify"),
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(wsTimeout))
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		return err
+	}
+
+	msg := &wsMessage{}
+	if err := json.Unmarshal(message, msg); err != nil {
+		return err
+	}
+	if msg.Event != "login" || msg.Code != "0" {
+		return errors.New(fmt.Sprintf("websocket login failed: %s %s", msg.Code, msg.Msg))
+	}
+	return nil
+}
+
+func handleMessage(message []byte, updatesCh chan<- Order) error {
+	if string(message) == "pong" {
+		return nil
+	}
+
+	msg := &wsMessage{}
+	if err := json.Unmarshal(message, msg); err != nil {
+		return err
+	}
+	if msg.Event == "error" {
+		return errors.New(fmt.Sprintf("websocket error %s: %s", msg.Code, msg.Msg))
+	}
+	if msg.Arg.Channel != "orders" || len(msg.Data) == 0 {
+		return nil
+	}
+
+	orders := make([]Order, 0)
+	if err := json.Unmarshal(msg.Data, &orders); err != nil {
+		return err
+	}
+	for _, o := range orders {
+		updatesCh <- o
+	}
+	return nil
+}
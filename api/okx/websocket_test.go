@@ -0,0 +1,157 @@
+package okx
+
+import (
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Frames as pushed by the OKX "orders" channel
+const (
+	frameLive       = `{"arg":{"channel":"orders","instType":"SPOT","uid":"77982378738415879"},"data":[{"instType":"SPOT","instId":"DOGE-BTC","ordId":"312269865356374016","clOrdId":"abc","px":"0.00000301","sz":"30","ordType":"limit","side":"buy","fillPx":"","fillSz":"0","accFillSz":"0","fee":"0","feeCcy":"DOGE","state":"live","cTime":"1597026383085","uTime":"1597026383085"}]}`
+	framePartial    = `{"arg":{"channel":"orders","instType":"SPOT","uid":"77982378738415879"},"data":[{"instType":"SPOT","instId":"DOGE-BTC","ordId":"312269865356374016","clOrdId":"abc","px":"0.00000301","sz":"30","ordType":"limit","side":"buy","fillPx":"0.00000301","fillSz":"10","accFillSz":"10","fee":"-0.01","feeCcy":"DOGE","state":"partially_filled","cTime":"1597026383085","uTime":"1597026383185"}]}`
+	frameFilled     = `{"arg":{"channel":"orders","instType":"SPOT","uid":"77982378738415879"},"data":[{"instType":"SPOT","instId":"DOGE-BTC","ordId":"312269865356374016","clOrdId":"abc","px":"0.00000301","sz":"30","ordType":"limit","side":"buy","fillPx":"0.00000301","fillSz":"20","accFillSz":"30","fee":"-0.03","feeCcy":"DOGE","state":"filled","cTime":"1597026383085","uTime":"1597026383285"}]}`
+	frameSubscribed = `{"event":"subscribe","arg":{"channel":"orders","instType":"SPOT"},"connId":"a4d3ae55"}`
+	frameWsError    = `{"event":"error","code":"60012","msg":"Invalid request","connId":"a4d3ae55"}`
+)
+
+func TestHandleMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		state   string
+		filled  string
+		err     bool
+	}{
+		{"live", frameLive, "live", "0.00000000", false},
+		{"partially filled", framePartial, "partially_filled", "10.00000000", false},
+		{"filled", frameFilled, "filled", "30.00000000", false},
+		{"subscribed", frameSubscribed, "", "", false},
+		{"pong", "pong", "", "", false},
+		{"error", frameWsError, "", "", true},
+		{"other channel", `{"arg":{"channel":"account"},"data":[{"ccy":"BTC"}]}`, "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updatesCh := make(chan Order, 1)
+			err := handleMessage([]byte(tt.message), updatesCh)
+			if (err != nil) != tt.err {
+				t.Fatalf("error = %v", err)
+			}
+
+			select {
+			case o := <-updatesCh:
+				if o.State != tt.state || o.AccFillSz.ToString() != tt.filled {
+					t.Errorf("order = %s %s, want %s %s", o.State, o.AccFillSz.ToString(), tt.state, tt.filled)
+				}
+				if o.OrdId != "312269865356374016" || o.ClOrdId != "abc" {
+					t.Errorf("order = %s %s", o.OrdId, o.ClOrdId)
+				}
+			default:
+				if tt.state != "" {
+					t.Errorf("no update received")
+				}
+			}
+		})
+	}
+}
+
+// wsRoute accepts the login if it's signed with the given secret and pushes
+// the given frames after the subscription.
+func wsRoute(t *testing.T, frames ...string) http.HandlerFunc {
+	signer := &Config{Secret: "secret"}
+	upgrader := websocket.Upgrader{}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		login := struct {
+			Op   string              `json:"op"`
+			Args []map[string]string `json:"args"`
+		}{}
+		if err := conn.ReadJSON(&login); err != nil || login.Op != "login" || len(login.Args) != 1 {
+			t.Errorf("login = %+v, %v", login, err)
+			return
+		}
+		arg := login.Args[0]
+		if arg["apiKey"] != "key" || arg["passphrase"] != "passphrase" || arg["sign"] != signer.sign(arg["timestamp"]+"GET/users/self/verify") {
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"event":"error","code":"60009","msg":"Login failed.","connId":"a4d3ae55"}`))
+			return
+		}
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"event":"login","code":"0","msg":"","connId":"a4d3ae55"}`))
+
+		sub := map[string]interface{}{}
+		if err := conn.ReadJSON(&sub); err != nil || sub["op"] != "subscribe" {
+			t.Errorf("subscription = %v, %v", sub, err)
+			return
+		}
+		b, _ := json.Marshal(sub["args"])
+		if string(b) != `[{"channel":"orders","instType":"SPOT"}]` {
+			t.Errorf("subscription args = %s", b)
+		}
+
+		for _, f := range frames {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(f)); err != nil {
+				return
+			}
+		}
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func TestSubscribeOrders(t *testing.T) {
+	m, c := newMockServer(t, map[string]http.HandlerFunc{
+		"/ws/v5/private": wsRoute(t, frameSubscribed, frameLive, framePartial, frameFilled),
+	})
+	defer m.Close()
+
+	updatesCh := make(chan Order, 8)
+	stopCh := make(chan bool)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.SubscribeOrders(updatesCh, stopCh)
+	}()
+
+	for _, state := range []string{"live", "partially_filled", "filled"} {
+		select {
+		case o := <-updatesCh:
+			if o.State != state {
+				t.Errorf("state = %s, want %s", o.State, state)
+			}
+		case err := <-errCh:
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s update not received", state)
+		}
+	}
+
+	stopCh <- true
+	if err := <-errCh; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSubscribeOrdersLoginFailed(t *testing.T) {
+	m, c := newMockServer(t, map[string]http.HandlerFunc{
+		"/ws/v5/private": wsRoute(t),
+	})
+	defer m.Close()
+	c.Secret = "wrong"
+
+	err := c.SubscribeOrders(make(chan Order, 1), make(chan bool))
+	if err == nil || err.Error() != "websocket login failed: 60009 Login failed." {
+		t.Errorf("error = %v", err)
+	}
+}
@@ -1,7 +1,10 @@
 package poloniex
 
 import (
+	"../../utils/limiter"
 	"../../utils/log"
+	"../../utils/values"
+	"context"
 	"crypto/hmac"
 	"crypto/sha512"
 	"crypto/tls"
@@ -43,7 +46,7 @@ func NewPoloniexApi(key string, secret string) *Config {
 		},
 		Currencies:  make(map[string]*Currency),
 		Pairs:       make(map[string]*Pair),
-		throttle:    time.Tick(reqInterval),
+		Limiter:     limiter.New(limiter.Config{Name: "poloniex", Interval: reqInterval}),
 		httpTimeout: 30 * time.Second,
 		debug:       false,
 	}
@@ -55,6 +58,13 @@ func NewPoloniexApi(key string, secret string) *Config {
 	return c
 }
 
+// SetEndpoints overrides the default REST and websocket endpoints.
+func (c *Config) SetEndpoints(rest string, wss string) {
+	c.RestEndpoint = rest
+	c.WebsocketEndpoint = wss
+	c.Socket.Endpoint = wss
+}
+
 func (c *Config) Setup() error {
 	curr, err := c.GetCurrencies()
 	if err != nil {
@@ -137,8 +147,12 @@ func (c *Config) GetOpenOrders(symbol string) ([]*OpenOrder, error) {
 	return r, nil
 }
 
-func (c *Config) GetTradeHistory(symbol string) ([]*Trade, error) {
-	b, err := c.doCommand("returnTradeHistory", map[string]string{"currencyPair": symbol, "limit": "2500"})
+func (c *Config) GetTradeHistory(symbol string, since time.Time) ([]*Trade, error) {
+	b, err := c.doCommand("returnTradeHistory", map[string]string{
+		"currencyPair": symbol,
+		"start":        strconv.FormatInt(since.Unix(), 10),
+		"limit":        "10000",
+	})
 	if err != nil {
 		log.Error(err)
 		return nil, err
@@ -151,6 +165,35 @@ func (c *Config) GetTradeHistory(symbol string) ([]*Trade, error) {
 	return r, nil
 }
 
+func (c *Config) GetBalances() (map[string]*values.Float, error) {
+	b, err := c.doCommand("returnBalances", nil)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	r := make(map[string]*values.Float)
+	if err := json.Unmarshal(b, &r); err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	return r, nil
+}
+
+func (c *Config) CancelOrder(orderNumber int64) error {
+	b, err := c.doCommand("cancelOrder", map[string]string{"orderNumber": strconv.FormatInt(orderNumber, 10)})
+	if err != nil {
+		return err
+	}
+	var r CancelResponse
+	if err := json.Unmarshal(b, &r); err != nil {
+		return err
+	}
+	if r.ErrorMessage != "" {
+		return errors.New(r.ErrorMessage)
+	}
+	return nil
+}
+
 func (c *Config) GetPair(symbol string) *Pair {
 	if pair, ok := c.Pairs[symbol]; ok {
 		return pair
@@ -169,15 +212,22 @@ func (c *Config) SubscribeAccount(updatesCh chan<- AccountUpd, stopCh <-chan boo
 	return c.Socket.subscribeAccountUpdates(updatesCh, stopCh)
 }
 
-func (c *Config) Buy(symbol string, rate float64, amount float64) (TradeOrder, error) {
-	return c.trade("buy", symbol, rate, amount)
+// Close closes the websocket connection for good.
+func (c *Config) Close() error {
+	return c.Socket.Close()
+}
+
+func (c *Config) Buy(symbol string, rate float64, amount float64, clientOrderId int64) (TradeOrder, error) {
+	return c.trade("buy", symbol, rate, amount, clientOrderId)
 }
 
-func (c *Config) Sell(symbol string, rate float64, amount float64) (TradeOrder, error) {
-	return c.trade("sell", symbol, rate, amount)
+func (c *Config) Sell(symbol string, rate float64, amount float64, clientOrderId int64) (TradeOrder, error) {
+	return c.trade("sell", symbol, rate, amount, clientOrderId)
 }
 
-func (c *Config) trade(direction string, symbol string, rate float64, amount float64) (TradeOrder, error) {
+// trade places a limit order. The client order id is optional and has to be
+// unique across all open orders of the account.
+func (c *Config) trade(direction string, symbol string, rate float64, amount float64, clientOrderId int64) (TradeOrder, error) {
 	if _, ok := c.Pairs[symbol]; !ok {
 		return TradeOrder{}, errors.New("pair not found")
 	}
@@ -187,6 +237,9 @@ func (c *Config) trade(direction string, symbol string, rate float64, amount flo
 		"rate":         strconv.FormatFloat(rate, 'f', -1, 64),
 		"amount":       strconv.FormatFloat(amount, 'f', -1, 64),
 	}
+	if clientOrderId > 0 {
+		params["clientOrderId"] = strconv.FormatInt(clientOrderId, 10)
+	}
 	b, err := c.doCommand(direction, params)
 
 	if err != nil {
@@ -218,7 +271,9 @@ func (c *Config) doCommand(command string, payload map[string]string) (response
 func (c *Config) do(method, resource string, payload map[string]string, authNeeded bool) (response []byte, err error) {
 	respCh := make(chan []byte)
 	errCh := make(chan error)
-	<-c.throttle
+	if err = c.Limiter.Wait(context.Background(), 1, false); err != nil {
+		return
+	}
 	go c.makeReq(method, resource, payload, authNeeded, respCh, errCh)
 	response = <-respCh
 	err = <-errCh
@@ -311,6 +366,9 @@ func (c *Config) doTimeoutRequest(timer *time.Timer, req *http.Request) (*http.R
 			c.dumpRequest(req)
 		}
 		resp, err := c.client.Do(req)
+		if err == nil {
+			c.Limiter.Observe(resp)
+		}
 		if c.debug {
 			c.dumpResponse(resp)
 		}
@@ -1,6 +1,7 @@
 package poloniex
 
 import (
+	"../../utils/limiter"
 	"../../utils/values"
 	"encoding/json"
 	"github.com/gorilla/websocket"
@@ -26,7 +27,7 @@ type Config struct {
 	Pairs      map[string]*Pair
 
 	client      *http.Client
-	throttle    <-chan time.Time
+	Limiter     *limiter.Limiter
 	httpTimeout time.Duration
 	debug       bool
 }
@@ -91,6 +92,8 @@ type AccountUpd struct {
 	Seq int64
 	// Initial indicates, that it's the entire obook snapshot.
 	Initial bool
+	// Subscribed indicates, that the server acknowledged the subscription.
+	Subscribed bool
 	// Obooks - updates of an order book.
 	Orders []OrderBookUpd
 	// Trades - new trades.
@@ -204,6 +207,13 @@ type TradeOrder struct {
 	ErrorMessage    string           `json:"error"`
 }
 
+type CancelResponse struct {
+	Success      int          `json:"success"`
+	Amount       values.Float `json:"amount,string"`
+	Message      string       `json:"message"`
+	ErrorMessage string       `json:"error"`
+}
+
 type ResultingTrade struct {
 	Amount  values.Float `json:"amount,string"`
 	Date    string       `json:"date"`
@@ -221,6 +231,8 @@ type OpenOrder struct {
 	Rate        values.Float  `json:"rate,string"`
 	Amount      values.Float  `json:"amount,string"`
 	Total       values.Float  `json:"total,string"`
+
+	ClientOrderId int64 `json:"clientOrderId,string,omitempty"`
 }
 
 type OrderBook struct {
@@ -78,6 +78,11 @@ func (s *Socket) loop() {
 		}
 		cl, err := s.makeWsClient()
 		s.mx.Lock()
+		if s.ws == nil && cl != nil {
+			// closed while connecting
+			_ = cl.Close()
+			cl, err = nil, errors.New("client closed")
+		}
 		s.conn = cl
 		s.err = err
 		s.mx.Unlock()
@@ -149,6 +154,24 @@ func (s *Socket) timeout(hbChan chan struct{}) {
 	}
 }
 
+// Close closes the connection and stops reconnecting. Pending and later
+// commands fail with "client closed".
+func (s *Socket) Close() error {
+	s.mx.Lock()
+	ws := s.ws
+	s.ws = nil
+	s.mx.Unlock()
+
+	if ws == nil {
+		return nil
+	}
+	close(ws)
+	// wake up everyone waiting for a connect attempt
+	s.cv.Broadcast()
+
+	return s.closeWithErr(errors.New("client closed"))
+}
+
 func (s *Socket) closeWithErr(err error) error {
 	s.mx.Lock()
 	conn := s.conn
@@ -223,7 +246,9 @@ func (s *Socket) parseMessage(msg wsMessage) error {
 	}
 
 	if id == 1000 {
+		// The subscription gets acknowledged by [1000, 1]
 		au.Seq = seq
+		au.Subscribed = msg.data == nil && seq == 1
 		handler(au)
 	} else if len(mu.OrderBooks)+len(mu.Trades) > 0 && seq > 0 {
 		mu.Seq = seq
@@ -270,7 +295,8 @@ func (s *Socket) handleAccountEvents(typ string, i []interface{}, mu *AccountUpd
 func (s *Socket) parseNewOpenOrdersUpdate(arr []interface{}) (OpenOrder, error) {
 	var order OpenOrder
 	var typ string
-	toDecode := []interface{}{&typ, &order.Symbol, &order.OrderNumber, &order.TypeNum, &order.Rate, &order.Amount}
+	var date, original interface{}
+	toDecode := []interface{}{&typ, &order.Symbol, &order.OrderNumber, &order.TypeNum, &order.Rate, &order.Amount, &date, &original, &order.ClientOrderId}
 	obookDec, _ := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
 		Result:     &toDecode,
 		DecodeHook: orderBookDecodeHook,
@@ -0,0 +1,40 @@
+package poloniex
+
+import (
+	"testing"
+)
+
+func TestHandleAccountMessage(t *testing.T) {
+	tests := []struct {
+		name       string
+		message    string
+		subscribed bool
+		trades     int
+		clientId   int64
+	}{
+		{"acknowledged", `[1000,1]`, true, 0, 0},
+		{"trade", `[1000,null,[["o",12345,"0.00000000","f"]]]`, false, 1, 0},
+		{"new order", `[1000,null,[["n",148,6083059,1,"0.00000300","30.00000000","2020-06-19 10:57:30",null]]]`, false, 0, 0},
+		{"new order with client id", `[1000,null,[["n",148,6083059,1,"0.00000300","30.00000000","2020-06-19 10:57:30","30.00000000",12345]]]`, false, 0, 12345},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updatesCh := make(chan AccountUpd, 1)
+			s := &Socket{subs: map[int]wsSub{1000: {handler: (&Socket{}).makeAccountUpdateHandler(updatesCh)}}}
+
+			if err := s.handleMessage([]byte(tt.message)); err != nil {
+				t.Fatal(err)
+			}
+			upd := <-updatesCh
+			if upd.Subscribed != tt.subscribed || len(upd.TradeOrders) != tt.trades {
+				t.Errorf("subscribed = %v, trades = %d, want %v, %d", upd.Subscribed, len(upd.TradeOrders), tt.subscribed, tt.trades)
+			}
+			for _, o := range upd.NewOrders {
+				if o.OrderNumber != 6083059 || o.ClientOrderId != tt.clientId {
+					t.Errorf("new order = %d, client id %d", o.OrderNumber, o.ClientOrderId)
+				}
+			}
+		})
+	}
+}
@@ -4,30 +4,41 @@ import (
 	"../utils/config"
 	"../utils/log"
 	"./notifier"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/kelseyhightower/envconfig"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 )
 
 type App struct {
-	*Config
+	// Replaced on reload, guarded by mx
+	config *Config
 
-	jobs []*Job
+	// Jobs by their file
+	jobs map[string]*Job
+	ctx  context.Context
+
+	mx       sync.Mutex
+	reloadMx sync.Mutex
 }
 
 func DefaultConfig() *Config {
 	dir, _ := os.Getwd()
 
 	c := &Config{
-		Config:   config.DefaultConfig(),
-		Timezone: "UTC",
-		JobDir:   path.Join(dir, "config", "jobs"),
-		Build:    Build{},
-		Provider: make([]*Provider, 0),
+		Config:          config.DefaultConfig(),
+		Timezone:        "UTC",
+		JobDir:          path.Join(dir, "config", "jobs"),
+		ShutdownTimeout: 30,
+		Build:           Build{},
+		Provider:        make([]*Provider, 0),
 	}
 	c.File = path.Join(dir, "config", "app.json")
 	c.Config.SetContext(c)
@@ -50,49 +61,62 @@ func (c *Config) AddFlags(fs *flag.FlagSet) {
 
 	fs.StringVar(&c.Timezone, "timezone", c.Timezone, "Application time zone")
 	fs.StringVar(&c.JobDir, "job-dir", c.JobDir, "Folder containing all job configuration files")
+	fs.IntVar(&c.ShutdownTimeout, "shutdown-timeout", c.ShutdownTimeout, "Seconds to wait for pending orders on shutdown")
 	fs.StringVar(&c.File, "config", c.File, "Application config file")
 }
 
 func NewApp(c *Config) *App {
 	a := &App{
-		Config: c,
-		jobs:   make([]*Job, 0),
+		config: c,
+		jobs:   make(map[string]*Job),
 	}
 
-	for _, n := range a.Notifier {
+	for _, n := range c.Notifier {
 		n.Init()
 	}
 
-	a.loadJobs()
+	a.jobs, _ = c.loadJobs()
+	log.Success(fmt.Sprintf("Loaded %d jobs", len(a.jobs)))
 
 	return a
 }
 
-func (a *App) loadJobs() {
-	a.jobs = make([]*Job, 0)
+// loadJobs loads every job of the job directory, keyed by its file. Files
+// which couldn't be read are returned separately, jobs of an unknown
+// provider are skipped.
+func (c *Config) loadJobs() (map[string]*Job, map[string]error) {
+	jobs := make(map[string]*Job)
+	broken := make(map[string]error)
 
-	_ = filepath.Walk(a.JobDir, func(path string, info os.FileInfo, err error) error {
+	_ = filepath.Walk(c.JobDir, func(path string, info os.FileInfo, err error) error {
 		if filepath.Ext(path) == ".json" {
 
-			j := NewJobFromFile(path)
-			p := a.getProvider(j.ProviderId)
-			j.Notifier = a.getNotifiers(j.NotifierIds)
+			j, err := LoadJobFromFile(path)
+			if err != nil {
+				log.Error(err)
+				broken[path] = err
+				return nil
+			}
+
+			p := c.getProvider(j.ProviderId)
+			j.Notifier = c.getNotifiers(j.NotifierIds)
 
 			if p == nil {
 				log.Error(fmt.Sprintf("Unkown provider: %s", j.ProviderId))
+			} else if err := j.setProvider(p); err != nil {
+				log.Error(err)
 			} else {
-				j.setProvider(p)
-				a.jobs = append(a.jobs, j)
+				jobs[path] = j
 			}
 		}
 		return nil
 	})
 
-	log.Success(fmt.Sprintf("Loaded %d jobs", len(a.jobs)))
+	return jobs, broken
 }
 
-func (a *App) getProvider(key string) *Provider {
-	for _, p := range a.Provider {
+func (c *Config) getProvider(key string) *Provider {
+	for _, p := range c.Provider {
 		if p.Name == key {
 			return p
 		}
@@ -100,10 +124,10 @@ func (a *App) getProvider(key string) *Provider {
 	return nil
 }
 
-func (a *App) getNotifiers(keys []string) []*notifier.Notifier {
+func (c *Config) getNotifiers(keys []string) []*notifier.Notifier {
 	ns := make([]*notifier.Notifier, 0)
 	for _, key := range keys {
-		for _, n := range a.Notifier {
+		for _, n := range c.Notifier {
 			if n.Name == key {
 				ns = append(ns, n)
 			}
@@ -112,14 +136,19 @@ func (a *App) getNotifiers(keys []string) []*notifier.Notifier {
 	return ns
 }
 
-func (a *App) Start() {
+// Start runs all enabled jobs until the given context gets canceled. The
+// returned error reports work which couldn't be finished on shutdown.
+func (a *App) Start(ctx context.Context) error {
+	a.mx.Lock()
+	a.ctx = ctx
 	for _, j := range a.jobs {
 		if j.Enabled == false {
 			continue
 		}
 
-		go j.Start()
+		j.Start(ctx)
 	}
+	a.mx.Unlock()
 
 	ticker := time.NewTicker(time.Minute)
 	defer ticker.Stop()
@@ -128,14 +157,85 @@ func (a *App) Start() {
 		select {
 		case t := <-ticker.C:
 
-			for _, j := range a.jobs {
+			for _, j := range a.getJobs() {
 				if j.Enabled == false {
 					continue
 				}
 
 				go j.Tick(t)
 			}
+		case <-ctx.Done():
+			return a.stop()
+		}
+	}
+
+}
+
+// stop waits until every job finished its pending work and every exchange
+// connection got closed, at most for the configured shutdown timeout.
+func (a *App) stop() error {
+	a.reloadMx.Lock()
+	defer a.reloadMx.Unlock()
+
+	timeout := a.getConfig().ShutdownTimeout
+	log.Info(fmt.Sprintf("Shutting down, waiting up to %ds for pending orders..", timeout))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	failed := 0
+	hubs := make(map[*EventHub]bool)
+	for _, j := range a.getJobs() {
+		if j.Enabled == false {
+			continue
 		}
+		if err := j.Stop(ctx); err != nil {
+			log.Error(fmt.Sprintf("%s %s %s", strings.ToUpper(j.Provider.Name), strings.ToUpper(j.Symbol), err))
+			failed++
+		}
+		hubs[j.hub] = true
+	}
+	for h := range hubs {
+		if err := h.Wait(ctx); err != nil {
+			log.Error(fmt.Sprintf("%s connection not closed: %s", strings.ToUpper(h.provider.Name), err))
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return errors.New(fmt.Sprintf("shutdown incomplete, %d jobs or connections left work undone", failed))
 	}
+	log.Success("Shutdown complete")
+	return nil
+}
 
+func (a *App) getConfig() *Config {
+	a.mx.Lock()
+	defer a.mx.Unlock()
+	return a.config
+}
+
+func (a *App) getJobs() []*Job {
+	a.mx.Lock()
+	jobs := make([]*Job, 0)
+	for _, j := range a.jobs {
+		jobs = append(jobs, j)
+	}
+	a.mx.Unlock()
+
+	return jobs
+}
+
+// sleep pauses for the given duration and reports whether the context is
+// still alive afterwards.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
 }
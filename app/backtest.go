@@ -0,0 +1,285 @@
+package app
+
+import (
+	"../utils/values"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BacktestConfig holds the options of the backtest command.
+type BacktestConfig struct {
+	Job      string
+	Candles  string
+	Format   string
+	Low      string
+	High     string
+	StepSize string
+	TickSize string
+}
+
+// Backtest replays historical prices through the mirror strategy of a job,
+// using an in-memory paper account.
+type Backtest struct {
+	Job      *Job
+	Exchange *PaperExchange
+	Candles  []*Candle
+	Low      *values.Float
+	High     *values.Float
+
+	result  *BacktestResult
+	now     time.Time
+	fills   map[string]*Order
+	origins map[string]*Order
+}
+
+type BacktestResult struct {
+	Start       time.Time
+	End         time.Time
+	Profit      *values.Float
+	RoundTrips  int
+	BuyFills    int
+	SellFills   int
+	PeakCapital *values.Float
+	SoldOut     []*Period
+	// Buy order volume at the end of a compounding job
+	OrderVolume *values.Float
+}
+
+// Period is a time range, End is zero while the period is still open.
+type Period struct {
+	Start time.Time
+	End   time.Time
+}
+
+func DefaultBacktestConfig() *BacktestConfig {
+	return &BacktestConfig{}
+}
+
+// AddFlags adds configuration flags to the given FlagSet.
+func (c *BacktestConfig) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Job, "job", c.Job, "Job configuration file")
+	fs.StringVar(&c.Candles, "candles", c.Candles, "CSV file containing OHLC candles or trade ticks")
+	fs.StringVar(&c.Format, "format", c.Format, "Format of the candle file \"candles\" or \"trades\" (default: picked from the header)")
+	fs.StringVar(&c.Low, "low", c.Low, "Lowest grid price (default: lowest price found)")
+	fs.StringVar(&c.High, "high", c.High, "Highest grid price (default: highest price found)")
+	fs.StringVar(&c.StepSize, "step-size", c.StepSize, "Quantity step size (lot size) of the symbol")
+	fs.StringVar(&c.TickSize, "tick-size", c.TickSize, "Price tick size of the symbol")
+}
+
+func NewBacktestFromConfig(c *BacktestConfig) (*Backtest, error) {
+	if c.Job == "" || c.Candles == "" {
+		return nil, errors.New("a job and a candle file are required")
+	}
+
+	candles, err := LoadCandles(c.Candles, c.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	j, err := LoadJobFromFile(c.Job)
+	if err != nil {
+		return nil, err
+	}
+
+	b := NewBacktest(j, candles)
+	if c.Low != "" {
+		b.Low = values.NewFloatFromString(c.Low)
+	}
+	if c.High != "" {
+		b.High = values.NewFloatFromString(c.High)
+	}
+
+	f := DefaultFilter()
+	if c.StepSize != "" {
+		f.StepSize = values.NewFloatFromString(c.StepSize)
+	}
+	if c.TickSize != "" {
+		f.TickSize = values.NewFloatFromString(c.TickSize)
+	}
+	b.Job.setFilter(f)
+
+	return b, nil
+}
+
+// NewBacktest prepares a backtest of the given job. The grid range defaults
+// to the lowest and highest price found in the candles.
+func NewBacktest(j *Job, candles []*Candle) *Backtest {
+	low, high := candles[0].Low, candles[0].High
+	for _, c := range candles {
+		if c.Low.Lt(low) {
+			low = c.Low
+		}
+		if c.High.Gt(high) {
+			high = c.High
+		}
+	}
+
+	base, quote := j.Secondary, j.Primary
+	p := &Provider{
+		Name:     "backtest",
+		Exchange: "paper",
+		Fee:      j.Fee,
+		Balances: map[string]*values.Float{
+			// The account is large enough to never reject an order, the
+			// capital actually used is reported instead.
+			base:  values.NewFloatFromFloat64(1e15),
+			quote: values.NewFloatFromFloat64(1e15),
+		},
+	}
+
+	ex := NewPaperAccount(p)
+	j.Provider = p
+	j.Exchange = ex
+	j.simulate = true
+
+	return &Backtest{
+		Job:      j,
+		Exchange: ex,
+		Candles:  candles,
+		Low:      low,
+		High:     high,
+		fills:    make(map[string]*Order),
+		origins:  make(map[string]*Order),
+	}
+}
+
+func (b *Backtest) Run() (*BacktestResult, error) {
+	j := b.Job
+	b.result = &BacktestResult{
+		Start:       b.Candles[0].Time,
+		End:         b.Candles[len(b.Candles)-1].Time,
+		Profit:      values.NewEmptyFloat(),
+		PeakCapital: values.NewEmptyFloat(),
+		SoldOut:     make([]*Period, 0),
+	}
+	b.now = b.result.Start
+
+	b.Exchange.Subscribe(j.Symbol, b.handleEvent)
+
+	for _, r := range j.Ladder(b.Candles[0].Open, b.Low, b.High) {
+		if _, err := b.Exchange.PlaceOrder(r); err != nil {
+			return nil, err
+		}
+	}
+	b.update()
+
+	for _, c := range b.Candles {
+		b.now = c.Time
+		for _, price := range c.Prices() {
+			b.Exchange.Tick(j.Symbol, price)
+			b.update()
+		}
+	}
+
+	if j.Compound.Gt(values.ZeroFloat) {
+		b.result.OrderVolume = j.buyVolume()
+	}
+	return b.result, nil
+}
+
+func (b *Backtest) handleEvent(evt *Event) {
+	switch evt.Type {
+	case EventFilled:
+		b.handleFill(evt.Order)
+		b.fills[counterOrderId(evt.Order.Id)] = evt.Order
+		b.Job.handleEvent(evt)
+	case EventNew:
+		// Counter orders carry the client id derived from their origin, a
+		// re-armed order doesn't belong to the fill which triggered it
+		if origin, ok := b.fills[evt.Order.ClientId]; ok {
+			b.origins[evt.Order.Id] = origin
+			delete(b.fills, evt.Order.ClientId)
+		}
+		b.Job.handleEvent(evt)
+	default:
+		b.Job.handleEvent(evt)
+	}
+}
+
+// handleFill books a filled order. A filled counter order completes a round
+// trip, its profit is the quote balance change plus the base balance change
+// valued at the fill price.
+func (b *Backtest) handleFill(o *Order) {
+	if o.Side == SideBuy {
+		b.result.BuyFills++
+	} else {
+		b.result.SellFills++
+	}
+
+	origin, ok := b.origins[o.Id]
+	if !ok {
+		return
+	}
+	delete(b.origins, o.Id)
+
+	buy, sell := origin, o
+	if o.Side == SideBuy {
+		buy, sell = o, origin
+	}
+
+	quote := sell.Total.Sub(sell.Fee).Sub(buy.Total)
+	base := buy.Volume.Sub(buy.Fee).Sub(sell.Volume)
+
+	b.result.Profit = b.result.Profit.Add(quote.Add(base.Mul(o.Price)))
+	b.result.RoundTrips++
+}
+
+// update tracks the capital locked in open orders and whether the grid is
+// sold out.
+func (b *Backtest) update() {
+	orders, _ := b.Exchange.GetOpenOrders(b.Job.Symbol)
+
+	capital := values.NewEmptyFloat()
+	sells := 0
+	for _, o := range orders {
+		capital = capital.Add(o.Volume.Mul(o.Price))
+		if o.Side == SideSell {
+			sells++
+		}
+	}
+	if capital.Gt(b.result.PeakCapital) {
+		b.result.PeakCapital = capital
+	}
+
+	periods := b.result.SoldOut
+	open := len(periods) > 0 && periods[len(periods)-1].End.IsZero()
+	if sells == 0 && !open {
+		b.result.SoldOut = append(periods, &Period{Start: b.now})
+	} else if sells > 0 && open {
+		periods[len(periods)-1].End = b.now
+	}
+}
+
+// ProfitPerCapital returns the profit in percent of the peak capital.
+func (r *BacktestResult) ProfitPerCapital() *values.Float {
+	return r.Profit.Div(r.PeakCapital).Mul(values.HundredFloat)
+}
+
+func (r *BacktestResult) String() string {
+	text := fmt.Sprintf("#### Backtest %s - %s\n", r.Start.Format("2006-01-02 15:04"), r.End.Format("2006-01-02 15:04"))
+	text = text + `
+| Profit | Round Trips | Buy Fills | Sell Fills | Peak Capital | P%   |
+|:-------|:------------|:----------|:-----------|:-------------|:-----|`
+	text = text + fmt.Sprintf("\n| %.8f | %d | %d | %d | %.8f | %.4f%% |\n", r.Profit.ToFloat(), r.RoundTrips, r.BuyFills, r.SellFills, r.PeakCapital.ToFloat(), r.ProfitPerCapital().ToFloat())
+	if r.OrderVolume != nil {
+		text = text + fmt.Sprintf("\nCompounded order volume: %.8f\n", r.OrderVolume.ToFloat())
+	}
+
+	if len(r.SoldOut) > 0 {
+		text = text + "\n#### Sold out\n"
+		lines := make([]string, 0)
+		for _, p := range r.SoldOut {
+			end := p.End
+			if end.IsZero() {
+				end = r.End
+			}
+			lines = append(lines, fmt.Sprintf("- %s - %s (%s)", p.Start.Format("2006-01-02 15:04"), end.Format("2006-01-02 15:04"), end.Sub(p.Start)))
+		}
+		text = text + strings.Join(lines, "\n") + "\n"
+	}
+
+	return text
+}
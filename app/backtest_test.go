@@ -0,0 +1,168 @@
+package app
+
+import (
+	"../utils/values"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// newTestJob returns a job trading DOGEBTC with an absolute step of 1 and a
+// volume of 100 BTC per order.
+func newTestJob(fee string) *Job {
+	j := NewDefaultJob()
+	j.Id = "test"
+	j.Symbol = "DOGEBTC"
+	j.Primary = "BTC"
+	j.Volume = *values.NewFloatFromString("100")
+	j.Step = *values.NewFloatFromString("1")
+	j.Fee = *values.NewFloatFromString(fee)
+	j.Provider = &Provider{Name: "test", Exchange: "paper"}
+	j.Init()
+
+	return j
+}
+
+// newTestTicks returns one trade tick per minute.
+func newTestTicks(prices ...float64) []*Candle {
+	candles := make([]*Candle, 0)
+	for i, p := range prices {
+		price := values.NewFloatFromFloat64(p)
+		candles = append(candles, &Candle{
+			Time:  time.Unix(int64(i)*60, 0),
+			Open:  price,
+			High:  price,
+			Low:   price,
+			Close: price,
+		})
+	}
+	return candles
+}
+
+func TestBacktestProfit(t *testing.T) {
+	tests := []struct {
+		name       string
+		prices     []float64
+		low, high  float64
+		roundTrips int
+		buyFills   int
+		sellFills  int
+		profit     string
+	}{
+		{
+			// The ladder sell at 11 isn't a round trip. The first buy at 10
+			// returns 10 DOGE for the 9.09090909 DOGE sold, both following
+			// round trips earn 10 BTC.
+			name:   "oscillating",
+			prices: []float64{10, 11, 10, 11, 10},
+			low:    8, high: 12,
+			roundTrips: 3, buyFills: 2, sellFills: 2,
+			profit: "29.09090909",
+		},
+		{
+			name:   "no fills",
+			prices: []float64{10, 10.5, 9.5, 10},
+			low:    8, high: 12,
+			roundTrips: 0, buyFills: 0, sellFills: 0,
+			profit: "0.00000000",
+		},
+		{
+			// Ladder orders filled one after another don't complete a
+			// round trip
+			name:   "falling",
+			prices: []float64{10, 9, 8},
+			low:    8, high: 12,
+			roundTrips: 0, buyFills: 2, sellFills: 0,
+			profit: "0.00000000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBacktest(newTestJob("0"), newTestTicks(tt.prices...))
+			b.Low = values.NewFloatFromFloat64(tt.low)
+			b.High = values.NewFloatFromFloat64(tt.high)
+
+			r, err := b.Run()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.RoundTrips != tt.roundTrips {
+				t.Errorf("round trips = %d, want %d", r.RoundTrips, tt.roundTrips)
+			}
+			if r.BuyFills != tt.buyFills || r.SellFills != tt.sellFills {
+				t.Errorf("fills = %d/%d, want %d/%d", r.BuyFills, r.SellFills, tt.buyFills, tt.sellFills)
+			}
+			if got := fmt.Sprintf("%.8f", r.Profit.ToFloat()); got != tt.profit {
+				t.Errorf("profit = %s, want %s", got, tt.profit)
+			}
+		})
+	}
+}
+
+func TestBacktestFeeReducesProfit(t *testing.T) {
+	prices := []float64{10, 11, 10, 11, 10}
+
+	profit := func(fee string) *values.Float {
+		b := NewBacktest(newTestJob(fee), newTestTicks(prices...))
+		b.Low, b.High = values.NewFloatFromFloat64(8), values.NewFloatFromFloat64(12)
+		r, err := b.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r.Profit
+	}
+
+	if free, paid := profit("0"), profit("0.1"); !paid.Lt(free) {
+		t.Errorf("profit with fee %.8f isn't below the profit without %.8f", paid.ToFloat(), free.ToFloat())
+	}
+}
+
+func TestBacktestSoldOut(t *testing.T) {
+	tests := []struct {
+		name    string
+		prices  []float64
+		periods [][2]int64
+	}{
+		{
+			name:    "never sold out",
+			prices:  []float64{10, 11, 10},
+			periods: [][2]int64{},
+		},
+		{
+			// Both sells are gone at 12, the buy at 11 places a sell again
+			name:    "recovered",
+			prices:  []float64{10, 11, 12, 13, 11},
+			periods: [][2]int64{{120, 240}},
+		},
+		{
+			name:    "still open",
+			prices:  []float64{10, 11, 12, 13},
+			periods: [][2]int64{{120, 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBacktest(newTestJob("0"), newTestTicks(tt.prices...))
+			b.Low, b.High = values.NewFloatFromFloat64(8), values.NewFloatFromFloat64(12)
+
+			r, err := b.Run()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(r.SoldOut) != len(tt.periods) {
+				t.Fatalf("sold out periods = %d, want %d", len(r.SoldOut), len(tt.periods))
+			}
+			for i, p := range tt.periods {
+				start, end := r.SoldOut[i].Start.Unix(), int64(0)
+				if !r.SoldOut[i].End.IsZero() {
+					end = r.SoldOut[i].End.Unix()
+				}
+				if start != p[0] || end != p[1] {
+					t.Errorf("period %d = %d - %d, want %d - %d", i, start, end, p[0], p[1])
+				}
+			}
+		})
+	}
+}
@@ -1,205 +1,443 @@
 package app
 
 import (
+	"../utils/limiter"
 	"../utils/log"
 	"../utils/values"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/common"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
-func (j *Job) parseBinOpenOrders(orders []*binance.Order) {
-	for _, o := range orders {
-		j.AttachBinOrder(o)
+func init() {
+	RegisterExchange("binance", NewBinanceExchange)
+}
+
+// binanceLimits are the spot api limits of a Binance account. The used
+// weight and order count are reported with every response.
+var binanceLimits = limiter.Config{
+	Name:         "binance",
+	Weight:       6000,
+	WeightWindow: time.Minute,
+	WeightHeader: "X-MBX-USED-WEIGHT-1M",
+	Orders:       50,
+	OrderWindow:  10 * time.Second,
+	OrderHeader:  "X-MBX-ORDER-COUNT-10S",
+	Backoff:      time.Minute,
+}
+
+// binanceWsTimeout is the time a user data stream may stay silent. Binance
+// pings every 3 minutes, a stream without account activity only receives the
+// pings.
+const binanceWsTimeout = 10 * time.Minute
+
+// binanceWeights contains the weight of every endpoint the bot uses which
+// weights more than a single request.
+var binanceWeights = map[string]int{
+	"GET /api/v3/exchangeInfo": 20,
+	"GET /api/v3/account":      20,
+	"GET /api/v3/openOrders":   3,
+	"GET /api/v3/myTrades":     20,
+	"GET /api/v3/allOrders":    20,
+	"GET /api/v3/order":        2,
+}
+
+// binanceWeight returns the weight of a request and whether it places an
+// order.
+func binanceWeight(r *http.Request) (int, bool) {
+	if r.URL.Path == "/api/v3/openOrders" && r.URL.Query().Get("symbol") == "" {
+		return 40, false
 	}
+	return binanceEndpointWeight(r.Method + " " + r.URL.Path)
 }
 
-func (j *Job) cancelAllBinOrders() {
-	if _, err := j.BinanceClient.NewCancelOpenOrdersService().Symbol(j.Symbol).Do(context.Background()); err != nil {
-		log.Error(err)
-		return
+// binanceEndpointWeight returns the weight of a request to an endpoint such
+// as "GET /api/v3/account" and whether it places an order.
+func binanceEndpointWeight(endpoint string) (int, bool) {
+	if endpoint == "POST /api/v3/order" {
+		return 1, true
+	}
+	if w, ok := binanceWeights[endpoint]; ok {
+		return w, false
 	}
+	return 1, false
 }
 
-func (j *Job) AttachBinOrder(o *binance.Order) {
-	j.mx.Lock()
-	if _, ok := j.orders[o.OrderID]; !ok {
-		j.orders[o.OrderID] = &Order{
-			Id:     o.OrderID,
-			Volume: values.NewFloatFromString(o.OrigQuantity),
-			Price:  values.NewFloatFromString(o.Price),
-			Side:   string(o.Side),
-			Status: string(o.Status),
-			Date:   time.Time{},
-		}
-		j.orders[o.OrderID].Total = j.orders[o.OrderID].Volume.Mul(j.orders[o.OrderID].Price)
-		j.orders[o.OrderID].Fee = j.orders[o.OrderID].Total.Div(values.HundredFloat).Mul(&j.Fee)
-		log.Success(fmt.Sprintf("%s ORDER REGISTERED: %d", strings.ToUpper(j.Provider.Name), o.OrderID))
+type BinanceExchange struct {
+	provider   *Provider
+	client     *binance.Client
+	limiter    *limiter.Limiter
+	clock      *Clock
+	wsEndpoint string
+
+	// Guards the time offset of the client, which signed requests read
+	offsetMx sync.RWMutex
+}
+
+func NewBinanceExchange(p *Provider) (Exchange, error) {
+	client := p.NewBinanceClient()
+	if client == nil {
+		return nil, errors.New(fmt.Sprintf("%s client could not be created", strings.ToUpper(p.Name)))
 	}
-	j.mx.Unlock()
+
+	_, wss, err := p.endpoints(client.BaseURL, binance.BaseWsMainURL)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &BinanceExchange{
+		provider:   p,
+		client:     client,
+		limiter:    limiter.Shared(p.account(client.BaseURL), limiter.New(binanceLimits)),
+		wsEndpoint: wss,
+	}
+
+	// The timestamp of a signed request is the local time minus the offset
+	e.clock = NewClock(p.Name, func() (int64, error) {
+		return client.NewServerTimeService().Do(context.Background())
+	}, func(offset int64) {
+		e.offsetMx.Lock()
+		client.TimeOffset = offset
+		e.offsetMx.Unlock()
+	})
+	if err := e.clock.Sync(); err != nil {
+		return nil, err
+	}
+
+	return e, nil
 }
 
-func (j *Job) validateAmount(amount *values.Float) *values.Float {
-	a := values.NewEmptyFloat()
-	for {
-		if a.Gt(amount) {
-			return a.Sub(j.stepSize)
+// SyncClock measures the offset to the Binance server time.
+func (e *BinanceExchange) SyncClock() error {
+	return e.clock.Sync()
+}
+
+// options returns the options of a signed request.
+func (e *BinanceExchange) options() []binance.RequestOption {
+	if e.provider.RecvWindow > 0 {
+		return []binance.RequestOption{binance.WithRecvWindow(e.provider.RecvWindow)}
+	}
+	return nil
+}
+
+// signed sends a signed request to the given endpoint. The request waits for
+// the limiter before locking the time offset, which keeps clock syncs from
+// waiting for throttled requests. A request rejected due to its timestamp is
+// sent once more after the clock got synced.
+func (e *BinanceExchange) signed(endpoint string, f func(ctx context.Context) error) error {
+	send := func() error {
+		weight, order := binanceEndpointWeight(endpoint)
+		if err := e.limiter.Wait(context.Background(), weight, order); err != nil {
+			return err
 		}
-		a = a.Add(j.stepSize)
+
+		// The offset mustn't change while the request gets signed
+		e.offsetMx.RLock()
+		defer e.offsetMx.RUnlock()
+		return f(limiter.Reserved(context.Background()))
 	}
+
+	err := send()
+
+	var apiErr *common.APIError
+	if errors.As(err, &apiErr) && apiErr.Code == -1021 {
+		log.Warn(fmt.Sprintf("%s TIMESTAMP REJECTED: %s", strings.ToUpper(e.provider.Name), apiErr.Message))
+		if serr := e.clock.Sync(); serr != nil {
+			log.Error(serr)
+			return err
+		}
+		return send()
+	}
+	return err
 }
 
-func (j *Job) wsHandler() func(message []byte) {
+func (e *BinanceExchange) GetFilter(symbol string) (*Filter, error) {
+	ex, err := e.client.NewExchangeInfoService().Do(context.Background())
+	if err != nil {
+		return nil, err
+	}
 
-	return func(message []byte) {
-		evt := &BinanceEvent{}
-		if err := json.Unmarshal(message, evt); err != nil {
-			log.Debug(string(message))
-			log.Error(err)
-			return
+	filter := DefaultFilter()
+	for _, s := range ex.Symbols {
+		if s.Symbol != symbol {
+			continue
+		}
+		for _, f := range s.Filters {
+			switch f["filterType"] {
+			case "LOT_SIZE":
+				filter.StepSize = values.NewFloatFromString(f["stepSize"].(string))
+			case "PRICE_FILTER":
+				filter.TickSize = values.NewFloatFromString(f["tickSize"].(string))
+			case "MIN_NOTIONAL", "NOTIONAL":
+				filter.MinNotional = values.NewFloatFromString(f["minNotional"].(string))
+			}
 		}
+		return filter, nil
+	}
 
-		if evt.OrderType == binance.OrderTypeMarket {
-			log.Debug("SKIPPED: " + string(message))
-			return
+	return nil, errors.New(fmt.Sprintf("unknown symbol: %s", symbol))
+}
+
+func (e *BinanceExchange) GetPrice(symbol string) (*values.Float, error) {
+	prices, err := e.client.NewListPricesService().Symbol(symbol).Do(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range prices {
+		if p.Symbol == symbol {
+			return values.NewFloatFromString(p.Price), nil
 		}
+	}
+	return nil, errors.New(fmt.Sprintf("unknown symbol: %s", symbol))
+}
 
-		if evt.EventType == "executionReport" && evt.Symbol == j.Symbol {
-			to := &binance.Order{
-				Symbol:                   evt.Symbol,
-				OrderID:                  evt.OrderId,
-				ClientOrderID:            evt.ClientOrderId,
-				Price:                    evt.Price.ToString(),
-				OrigQuantity:             evt.Quantity.ToString(),
-				ExecutedQuantity:         evt.LastExecutedQuantity.ToString(),
-				CummulativeQuoteQuantity: evt.CumulativeQuoteQuantity.ToString(),
-				Status:                   evt.Status,
-				TimeInForce:              evt.TimeInForce,
-				Type:                     evt.OrderType,
-				Side:                     evt.Side,
-				StopPrice:                evt.StopPrice.ToString(),
-				IcebergQuantity:          evt.IcebergQuantity.ToString(),
-				Time:                     evt.TimeCreated,
-				UpdateTime:               evt.EventTime,
-				IsWorking:                false,
-				IsIsolated:               false,
-			}
+func (e *BinanceExchange) GetBalances() (map[string]*values.Float, error) {
+	var acc *binance.Account
+	err := e.signed("GET /api/v3/account", func(ctx context.Context) (err error) {
+		acc, err = e.client.NewGetAccountService().Do(ctx, e.options()...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
 
-			if to.Status == binance.OrderStatusTypeNew {
-				j.AttachBinOrder(to)
-			} else if to.Status == binance.OrderStatusTypeCanceled {
-				j.DetachOrder(to.OrderID)
-			} else if to.Status == binance.OrderStatusTypeFilled {
-				if to.Side == binance.SideTypeBuy {
-					// Create a new sell order
-					price := evt.Price.Add(j.getStep("sell"))
+	balances := make(map[string]*values.Float)
+	for _, b := range acc.Balances {
+		balances[b.Asset] = values.NewFloatFromString(b.Free)
+	}
+	return balances, nil
+}
 
-					dif := evt.Quantity.Div(values.HundredFloat)
-					buyFee := dif.Mul(&j.Fee)
+func (e *BinanceExchange) GetOpenOrders(symbol string) ([]*Order, error) {
+	var orders []*binance.Order
+	err := e.signed("GET /api/v3/openOrders", func(ctx context.Context) (err error) {
+		orders, err = e.client.NewListOpenOrdersService().Symbol(symbol).Do(ctx, e.options()...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
 
-					availableAmount := evt.Quantity.Sub(buyFee)
-					sellAmount := j.validateAmount(availableAmount)
+	result := make([]*Order, 0)
+	for _, o := range orders {
+		result = append(result, e.newOrder(o))
+	}
+	return result, nil
+}
 
-					if j.balance[j.Secondary].Gt(buyFee) {
-						sellAmount = &evt.Quantity
+func (e *BinanceExchange) PlaceOrder(r *OrderRequest) (*Order, error) {
+	side := binance.SideTypeBuy
+	if r.Side == SideSell {
+		side = binance.SideTypeSell
+	}
 
-						j.subBalance(j.Secondary, buyFee)
-						log.Info(fmt.Sprintf("%s LEND %.8f %s", strings.ToUpper(j.Provider.Name), buyFee, j.Secondary))
-					} else {
-						dif := availableAmount.Sub(sellAmount)
+	s := e.client.NewCreateOrderService().Symbol(r.Symbol).
+		Side(side).Type(binance.OrderTypeLimit).
+		TimeInForce(binance.TimeInForceTypeGTC).Quantity(r.Amount.ToString()).
+		Price(r.Price.ToString())
+	if r.ClientId != "" {
+		s = s.NewClientOrderID(r.ClientId)
+	}
 
-						j.addBalance(j.Secondary, dif)
-						log.Info(fmt.Sprintf("%s GAVE %.8f %s", strings.ToUpper(j.Provider.Name), dif, j.Secondary))
-					}
+	var order *binance.CreateOrderResponse
+	err := e.signed("POST /api/v3/order", func(ctx context.Context) (err error) {
+		order, err = s.Do(ctx, e.options()...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
 
-					amt, _ := sellAmount.Float64()
+	return &Order{
+		Id:       strconv.FormatInt(order.OrderID, 10),
+		ClientId: order.ClientOrderID,
+		Symbol:   order.Symbol,
+		Volume:   r.Amount,
+		Price:    r.Price,
+		Total:    r.Amount.Mul(r.Price),
+		Fee:      values.NewEmptyFloat(),
+		Side:     r.Side,
+		Status:   StatusNew,
+		Date:     time.Now(),
+	}, nil
+}
 
-					total := price.Mul(sellAmount)
+func (e *BinanceExchange) CancelOrder(symbol string, id string) error {
+	num, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return err
+	}
+	return e.signed("DELETE /api/v3/order", func(ctx context.Context) error {
+		_, err := e.client.NewCancelOrderService().Symbol(symbol).OrderID(num).Do(ctx, e.options()...)
+		return err
+	})
+}
 
-					pf, _ := price.Float64()
-					tot, _ := total.Float64()
+// GetOrder returns the order with the given id, including closed orders.
+func (e *BinanceExchange) GetOrder(symbol string, id string) (*Order, error) {
+	num, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, err
+	}
 
-					ps := price.ToString()
-					as := sellAmount.ToString()
+	var o *binance.Order
+	err = e.signed("GET /api/v3/order", func(ctx context.Context) (err error) {
+		o, err = e.client.NewGetOrderService().Symbol(symbol).OrderID(num).Do(ctx, e.options()...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
 
-					order, err := j.BinanceClient.NewCreateOrderService().Symbol(j.Symbol).
-						Side(binance.SideTypeSell).Type(binance.OrderTypeLimit).
-						TimeInForce(binance.TimeInForceTypeGTC).Quantity(as).
-						Price(ps).Do(context.Background())
-					if err != nil {
-						log.Debug(string(message))
-						log.Debug(j.Provider.Name, evt.OrderId, "sell", pf, amt, ps, as, tot)
-						log.Error(err)
-						return
-					}
+	order := e.newOrder(o)
+	order.Status = binanceStatus(o.Status)
+	return order, nil
+}
 
-					log.Success(fmt.Sprintf("%s ORDER CREATED: %d", strings.ToUpper(j.Provider.Name), order.OrderID))
-
-					diff := total.Sub(evt.Quantity.Mul(&evt.Price))
-					d, _ := diff.Float64()
-
-					go j.SaveOrder(&Order{
-						Id:     evt.OrderId,
-						Volume: &evt.Quantity,
-						Price:  &evt.Price,
-						Total:  total,
-						Fee:    buyFee,
-						Side:   "buy",
-						Status: "filled",
-						Date:   time.Now(),
-					})
-
-					go j.NotifyOrder(pf, amt, tot, d, "sell")
-
-				} else if to.Side == binance.SideTypeSell {
-					// Create a new buy order
-					price := evt.Price.Sub(j.getStep("buy"))
-
-					amount := j.validateAmount(j.Volume.Div(price))
-					total := price.Mul(amount)
-
-					pf, _ := price.Float64()
-					amt, _ := amount.Float64()
-					tot, _ := total.Float64()
-
-					ps := price.ToString()
-					as := amount.ToString()
-
-					order, err := j.BinanceClient.NewCreateOrderService().Symbol(j.Symbol).
-						Side(binance.SideTypeBuy).Type(binance.OrderTypeLimit).
-						TimeInForce(binance.TimeInForceTypeGTC).Quantity(as).
-						Price(ps).Do(context.Background())
-					if err != nil {
-						log.Debug(string(message))
-						log.Debug(j.Provider.Name, evt.OrderId, "buy", pf, amt, ps, as, tot)
-						log.Error(err)
-						return
-					}
+// GetOrderByClientId returns the order with the given client id, including
+// closed orders.
+func (e *BinanceExchange) GetOrderByClientId(symbol string, clientId string) (*Order, error) {
+	var o *binance.Order
+	err := e.signed("GET /api/v3/order", func(ctx context.Context) (err error) {
+		o, err = e.client.NewGetOrderService().Symbol(symbol).OrigClientOrderID(clientId).Do(ctx, e.options()...)
+		return err
+	})
+
+	var apiErr *common.APIError
+	if errors.As(err, &apiErr) && apiErr.Code == -2013 {
+		// Order does not exist
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	order := e.newOrder(o)
+	order.Status = binanceStatus(o.Status)
+	return order, nil
+}
+
+func (e *BinanceExchange) GetTrades(symbol string, since time.Time) ([]*Trade, error) {
+	s := e.client.NewListTradesService().Symbol(symbol).StartTime(since.UnixNano() / int64(time.Millisecond)).Limit(1000)
+
+	result := make([]*Trade, 0)
+	for {
+		var trades []*binance.TradeV3
+		err := e.signed("GET /api/v3/myTrades", func(ctx context.Context) (err error) {
+			trades, err = s.Do(ctx, e.options()...)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range trades {
+			side := SideSell
+			if t.IsBuyer {
+				side = SideBuy
+			}
+			result = append(result, &Trade{
+				Id:      strconv.FormatInt(t.ID, 10),
+				OrderId: strconv.FormatInt(t.OrderID, 10),
+				Symbol:  t.Symbol,
+				Side:    side,
+				Price:   values.NewFloatFromString(t.Price),
+				Volume:  values.NewFloatFromString(t.Quantity),
+				Date:    time.Unix(0, t.Time*int64(time.Millisecond)),
+			})
+		}
+		if len(trades) < 1000 {
+			return result, nil
+		}
 
-					log.Success(fmt.Sprintf("%s ORDER CREATED: %d", strings.ToUpper(j.Provider.Name), order.OrderID))
+		// Binance doesn't allow to combine a start time with a trade id
+		s = e.client.NewListTradesService().Symbol(symbol).FromID(trades[len(trades)-1].ID + 1).Limit(1000)
+	}
+}
 
-					diff := evt.Quantity.Mul(&evt.Price).Sub(total)
-					d, _ := diff.Float64()
+func (e *BinanceExchange) newOrder(o *binance.Order) *Order {
+	volume := values.NewFloatFromString(o.OrigQuantity)
+	price := values.NewFloatFromString(o.Price)
+
+	order := &Order{
+		Id:       strconv.FormatInt(o.OrderID, 10),
+		ClientId: o.ClientOrderID,
+		Symbol:   o.Symbol,
+		Volume:   volume,
+		Price:    price,
+		Total:    volume.Mul(price),
+		Fee:      values.NewEmptyFloat(),
+		Side:     strings.ToLower(string(o.Side)),
+		Status:   strings.ToLower(string(o.Status)),
+		Date:     time.Unix(0, o.Time*int64(time.Millisecond)),
+	}
+	order.setExecuted(values.NewFloatFromString(o.ExecutedQuantity))
 
-					dif := evt.Quantity.Div(values.HundredFloat)
-					sellFee := dif.Mul(&j.Fee)
+	return order
+}
 
-					go j.SaveOrder(&Order{
-						Id:     evt.OrderId,
-						Volume: &evt.Quantity,
-						Price:  &evt.Price,
-						Total:  total,
-						Fee:    sellFee,
-						Side:   "sell",
-						Status: "filled",
-						Date:   time.Now(),
-					})
+// binanceStatus returns the normalized status of an order. Expired and
+// rejected orders count as canceled.
+func binanceStatus(status binance.OrderStatusType) string {
+	switch status {
+	case binance.OrderStatusTypeFilled:
+		return StatusFilled
+	case binance.OrderStatusTypePartiallyFilled:
+		return StatusPartial
+	case binance.OrderStatusTypeCanceled, binance.OrderStatusTypeRejected, binance.OrderStatusTypeExpired, binance.OrderStatusExpiredInMatch:
+		return StatusCanceled
+	}
+	return StatusNew
+}
 
-					go j.NotifyOrder(pf, amt, tot, d, "buy")
-				}
+func (e *BinanceExchange) wsHandler(handler EventHandler) func(message []byte) {
+
+	return func(message []byte) {
+		evt := &BinanceEvent{}
+		if err := json.Unmarshal(message, evt); err != nil {
+			log.Debug(string(message))
+			log.Error(err)
+			return
+		}
+
+		if evt.OrderType == binance.OrderTypeMarket {
+			log.Debug("SKIPPED: " + string(message))
+			return
+		}
+
+		if evt.EventType == "executionReport" {
+			o := &Order{
+				Id:     strconv.FormatInt(evt.OrderId, 10),
+				Symbol: evt.Symbol,
+				Volume: &evt.Quantity,
+				Price:  &evt.Price,
+				Total:  evt.Quantity.Mul(&evt.Price),
+				Fee:    values.NewEmptyFloat(),
+				Side:   strings.ToLower(string(evt.Side)),
+				Status: strings.ToLower(string(evt.Status)),
+				Date:   time.Unix(0, evt.TransactionTime*int64(time.Millisecond)),
+			}
+
+			o.setExecuted(&evt.CumulativeFilledQuantity)
+
+			switch evt.Status {
+			case binance.OrderStatusTypeNew:
+				handler(&Event{Type: EventNew, Symbol: evt.Symbol, Order: o})
+			case binance.OrderStatusTypePartiallyFilled:
+				o.Status = StatusPartial
+				handler(&Event{Type: EventPartial, Symbol: evt.Symbol, Order: o})
+			case binance.OrderStatusTypeCanceled:
+				handler(&Event{Type: EventCanceled, Symbol: evt.Symbol, Order: o})
+			case binance.OrderStatusTypeFilled:
+				handler(&Event{Type: EventFilled, Symbol: evt.Symbol, Order: o})
 			}
 		} else if evt.EventType == "outboundAccountPosition" {
 			/**
@@ -217,47 +455,19 @@ func (j *Job) wsHandler() func(message []byte) {
 			}
 			*/
 			if evt.Balances != nil {
+				balances := make(map[string]*values.Float)
 				for _, b := range evt.Balances {
-					if b.Free.Lt(j.getBalance(b.Asset)) {
-						j.setBalance(b.Asset, &b.Free)
-						log.Info(fmt.Sprintf("%s AVAILABLE BALANCE %.8f %s", strings.ToUpper(j.Provider.Name), b.Free.ToFloat(), b.Asset))
-					}
+					balances[b.Asset] = values.NewFloat(&b.Free.Float)
 				}
+				handler(&Event{Type: EventBalance, Balance: balances})
 			}
 		}
-		log.Debug(j.Provider.Name)
+		log.Debug(e.provider.Name)
 		log.Debug(string(message))
 	}
 }
 
-func (j *Job) setBinanceBalance() {
-	if acc, err := j.BinanceClient.NewGetAccountService().Do(context.Background()); err == nil {
-		for _, b := range acc.Balances {
-			j.balance[b.Asset] = values.NewFloatFromString(b.Free)
-		}
-	}
-}
-
-func (j *Job) setBinanceStepSize() {
-	if ex, err := j.BinanceClient.NewExchangeInfoService().Do(context.Background()); err == nil {
-		for _, s := range ex.Symbols {
-			if s.Symbol == j.Symbol {
-				for _, f := range s.Filters {
-					if ft, ok := f["filterType"]; ok {
-						if ft == "LOT_SIZE" {
-							j.mx.Lock()
-							j.stepSize = values.NewFloatFromString(f["stepSize"].(string))
-							j.mx.Unlock()
-							return
-						}
-					}
-				}
-			}
-		}
-	}
-}
-
-func (j *Job) KeepListenKeyAlive(listenKey string, done chan struct{}, stop chan struct{}) {
+func (e *BinanceExchange) KeepListenKeyAlive(listenKey string, done chan struct{}, stop chan struct{}) {
 	ticker := time.NewTicker(time.Minute * 30)
 	defer ticker.Stop()
 
@@ -268,8 +478,8 @@ func (j *Job) KeepListenKeyAlive(listenKey string, done chan struct{}, stop chan
 		case <-stop:
 			return
 		case <-ticker.C:
-			if err := j.BinanceClient.NewKeepaliveUserStreamService().ListenKey(listenKey).Do(context.Background()); err != nil {
-				log.Access(j.Provider.Name)
+			if err := e.client.NewKeepaliveUserStreamService().ListenKey(listenKey).Do(context.Background()); err != nil {
+				log.Access(e.provider.Name)
 				log.Error(err)
 				stop <- struct{}{}
 				return
@@ -278,25 +488,94 @@ func (j *Job) KeepListenKeyAlive(listenKey string, done chan struct{}, stop chan
 	}
 }
 
-func (j *Job) WatchBinMarket() {
-	for {
-		listenKey, err := j.BinanceClient.NewStartUserStreamService().Do(context.Background())
+// wsUserDataServe serves the user data stream of the given listen key. Unlike
+// binance.WsUserDataServe it connects to the websocket endpoint of the provider.
+func (e *BinanceExchange) wsUserDataServe(listenKey string, handler func(message []byte), errHandler func(err error)) (chan struct{}, chan struct{}, error) {
+	conn, _, err := websocket.DefaultDialer.Dial(fmt.Sprintf("%s/%s", e.wsEndpoint, listenKey), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Every ping proves the connection to be alive and has to be answered,
+	// otherwise the server closes the stream
+	conn.SetPingHandler(func(data string) error {
+		_ = conn.SetReadDeadline(time.Now().Add(binanceWsTimeout))
+		err := conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(10*time.Second))
+		if err == websocket.ErrCloseSent {
+			return nil
+		}
+		return err
+	})
+
+	doneC := make(chan struct{})
+	stopC := make(chan struct{})
+	go func() {
+		defer close(doneC)
+		for {
+			_ = conn.SetReadDeadline(time.Now().Add(binanceWsTimeout))
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				select {
+				case <-stopC:
+				default:
+					errHandler(err)
+				}
+				return
+			}
+			handler(message)
+		}
+	}()
+	go func() {
+		select {
+		case <-stopC:
+		case <-doneC:
+		}
+		_ = conn.Close()
+	}()
+
+	return doneC, stopC, nil
+}
+
+func (e *BinanceExchange) Watch(ctx context.Context, handler EventHandler) {
+	go e.clock.Run(ctx)
+
+	for ctx.Err() == nil {
+		listenKey, err := e.client.NewStartUserStreamService().Do(context.Background())
 		if err == nil {
-			log.Success(fmt.Sprintf("Subscribing to %s account update events..", strings.ToUpper(j.Provider.Name)))
-			doneC, stopC, err := binance.WsUserDataServe(listenKey, j.wsHandler(), func(err error) {
-				log.Error(err)
+			log.Success(fmt.Sprintf("Subscribing to %s account update events..", strings.ToUpper(e.provider.Name)))
+			doneC, stopC, err := e.wsUserDataServe(listenKey, e.wsHandler(handler), func(err error) {
+				if ctx.Err() == nil {
+					log.Error(err)
+				}
 			})
 			if err != nil {
 				log.Error(err)
-				stopC <- struct{}{}
+				sleep(ctx, time.Second)
 			} else {
-				go j.KeepListenKeyAlive(listenKey, doneC, stopC)
-				<-doneC
+				handler(&Event{Type: EventConnected})
+				go e.KeepListenKeyAlive(listenKey, doneC, stopC)
+				select {
+				case <-doneC:
+				case <-ctx.Done():
+					select {
+					case stopC <- struct{}{}:
+					case <-doneC:
+					}
+					<-doneC
+					e.closeListenKey(listenKey)
+				}
 			}
 		} else {
-			log.Error(fmt.Sprintf("Subscribing to %s account update events failed", strings.ToUpper(j.Provider.Name)))
+			log.Error(fmt.Sprintf("Subscribing to %s account update events failed", strings.ToUpper(e.provider.Name)))
 			log.Error(err)
-			time.Sleep(time.Second)
+			sleep(ctx, time.Second)
 		}
 	}
 }
+
+// closeListenKey invalidates the listen key of a closed user data stream.
+func (e *BinanceExchange) closeListenKey(listenKey string) {
+	if err := e.client.NewCloseUserStreamService().ListenKey(listenKey).Do(context.Background()); err != nil {
+		log.Error(err)
+	}
+}
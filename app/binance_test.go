@@ -0,0 +1,138 @@
+package app
+
+import (
+	"../utils/values"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBinanceSignedTimestamp(t *testing.T) {
+	// The server clock is a minute behind
+	const behind = int64(60000)
+	now := func() int64 { return time.Now().UnixNano() / int64(time.Millisecond) }
+
+	var timestamps []int64
+	mx := sync.Mutex{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/time", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"serverTime":%d}`, now()-behind)
+	})
+	mux.HandleFunc("/api/v3/account", func(w http.ResponseWriter, r *http.Request) {
+		ts, _ := strconv.ParseInt(r.URL.Query().Get("timestamp"), 10, 64)
+		mx.Lock()
+		timestamps = append(timestamps, now()-behind-ts)
+		mx.Unlock()
+		fmt.Fprint(w, `{"balances":[{"asset":"BTC","free":"1.5","locked":"0"}]}`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	ex, err := NewBinanceExchange(&Provider{Name: "binance-test", Exchange: "binance", Key: "key", Secret: "secret", RestEndpoint: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := ex.(*BinanceExchange)
+
+	// Signed requests and clock syncs run concurrently
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := e.GetBalances(); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			e.clock.apply(behind)
+		}()
+	}
+	wg.Wait()
+
+	if len(timestamps) != 4 {
+		t.Fatalf("requests = %d, want 4", len(timestamps))
+	}
+	for i, d := range timestamps {
+		// Every timestamp is close to the server time
+		if d < -1000 || d > 1000 {
+			t.Errorf("request %d: timestamp is %dms off the server time", i, d)
+		}
+	}
+}
+
+func TestBinanceGetOrder(t *testing.T) {
+	tests := []struct {
+		status   string
+		executed string
+		want     string
+	}{
+		{"NEW", "0", StatusNew},
+		{"PARTIALLY_FILLED", "10", StatusPartial},
+		{"FILLED", "30", StatusFilled},
+		{"CANCELED", "10", StatusCanceled},
+		{"EXPIRED", "0", StatusCanceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/v3/time", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `{"serverTime":%d}`, time.Now().UnixNano()/int64(time.Millisecond))
+			})
+			mux.HandleFunc("/api/v3/order", func(w http.ResponseWriter, r *http.Request) {
+				if q := r.URL.Query(); q.Get("symbol") != "DOGEBTC" || q.Get("orderId") != "28" {
+					t.Errorf("query = %s", r.URL.RawQuery)
+				}
+				fmt.Fprintf(w, `{"symbol":"DOGEBTC","orderId":28,"clientOrderId":"abc","price":"0.00000301","origQty":"30","executedQty":"%s","status":"%s","type":"LIMIT","side":"BUY","time":1499827319559}`, tt.executed, tt.status)
+			})
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+
+			ex, err := NewBinanceExchange(&Provider{Name: "binance-test", Exchange: "binance", Key: "key", Secret: "secret", RestEndpoint: srv.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			o, err := ex.(OrderGetter).GetOrder("DOGEBTC", "28")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if o.Id != "28" || o.ClientId != "abc" || o.Status != tt.want || o.Executed.ToString() != values.NewFloatFromString(tt.executed).ToString() {
+				t.Errorf("order = %s %s %s %s", o.Id, o.ClientId, o.Status, o.Executed.ToString())
+			}
+		})
+	}
+}
+
+func TestBinanceWeight(t *testing.T) {
+	tests := []struct {
+		method string
+		url    string
+		weight int
+		order  bool
+	}{
+		{"POST", "/api/v3/order", 1, true},
+		{"GET", "/api/v3/order?symbol=DOGEBTC", 2, false},
+		{"DELETE", "/api/v3/order", 1, false},
+		{"GET", "/api/v3/account", 20, false},
+		{"GET", "/api/v3/exchangeInfo", 20, false},
+		{"GET", "/api/v3/myTrades?symbol=DOGEBTC", 20, false},
+		{"GET", "/api/v3/openOrders?symbol=DOGEBTC", 3, false},
+		{"GET", "/api/v3/openOrders", 40, false},
+		{"GET", "/api/v3/time", 1, false},
+	}
+
+	for _, tt := range tests {
+		weight, order := binanceWeight(httptest.NewRequest(tt.method, tt.url, nil))
+		if weight != tt.weight || order != tt.order {
+			t.Errorf("%s %s = %d, %v, want %d, %v", tt.method, tt.url, weight, order, tt.weight, tt.order)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package app
+
+import (
+	"../utils/log"
+	"../utils/values"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// checkBounds returns an error if the given price lies outside of the price
+// band of the job. A band without min-price or max-price is open on that side.
+func (j *Job) checkBounds(price *values.Float) error {
+	if j.MinPrice.Gt(values.ZeroFloat) && price.Lt(&j.MinPrice) {
+		return errors.New(fmt.Sprintf("price %s is below the min-price %s", price.ToString(), j.MinPrice.ToString()))
+	}
+	if j.MaxPrice.Gt(values.ZeroFloat) && price.Gt(&j.MaxPrice) {
+		return errors.New(fmt.Sprintf("price %s is above the max-price %s", price.ToString(), j.MaxPrice.ToString()))
+	}
+	return nil
+}
+
+// rearm places the counter orders which got parked outside of the price band
+// as soon as a fill inside of the band shows that the market came back. The
+// bounds get checked once more, only orders which lie inside of the band by
+// now get placed, e.g. after the band got widened.
+func (j *Job) rearm(price *values.Float) {
+	if !j.Rearm || j.checkBounds(price) != nil {
+		return
+	}
+
+	for _, in := range j.parkedOrders() {
+		r := in.Request
+		if in.Reason != string(errorBounds) || j.checkBounds(r.Price) != nil {
+			continue
+		}
+
+		log.Info(fmt.Sprintf("%s REARMING PARKED ORDER: %s %s @ %.8f", strings.ToUpper(j.Provider.Name), in.Fill.Id, strings.ToUpper(r.Side), r.Price.ToFloat()))
+		j.execute(in)
+	}
+}
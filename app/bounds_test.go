@@ -0,0 +1,153 @@
+package app
+
+import (
+	"../utils/values"
+	"./notifier"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCheckBounds(t *testing.T) {
+	tests := []struct {
+		min, max string
+		price    string
+		err      bool
+	}{
+		{"0", "0", "1000", false},
+		{"9", "0", "8.99", true},
+		{"9", "0", "9", false},
+		{"0", "11", "11", false},
+		{"0", "11", "11.01", true},
+		{"9", "11", "10", false},
+		{"9", "11", "8", true},
+		{"9", "11", "12", true},
+	}
+
+	for _, tt := range tests {
+		j := NewDefaultJob()
+		j.MinPrice = *values.NewFloatFromString(tt.min)
+		j.MaxPrice = *values.NewFloatFromString(tt.max)
+		if err := j.checkBounds(values.NewFloatFromString(tt.price)); (err != nil) != tt.err {
+			t.Errorf("%s within %s - %s: error = %v", tt.price, tt.min, tt.max, err)
+		}
+	}
+}
+
+// newBoundsJob returns a job which places its counter orders right away on a
+// paper account holding the given amount of DOGE.
+func newBoundsJob(t *testing.T, doge string) (*Job, *PaperExchange) {
+	dir, err := ioutil.TempDir("", "bounds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	j := newTestJob("0")
+	j.OrderDir = dir
+	ex := NewPaperAccount(&Provider{
+		Name:     "test",
+		Exchange: "paper",
+		Balances: map[string]*values.Float{"DOGE": values.NewFloatFromString(doge)},
+	})
+	j.Exchange = ex
+	return j, ex
+}
+
+func TestRearm(t *testing.T) {
+	// The sell order at 11 for the buy filled at 10 got parked above the
+	// max-price of 10.5
+	tests := []struct {
+		name     string
+		rearm    bool
+		maxPrice string
+		price    string
+		rearmed  bool
+	}{
+		{"band widened", true, "12", "9.8", true},
+		{"still outside of the band", true, "10.5", "9.8", false},
+		{"fill outside of the band", true, "12", "8", false},
+		{"disabled", false, "12", "9.8", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j, ex := newBoundsJob(t, "100")
+			j.Rearm = tt.rearm
+			j.MinPrice = *values.NewFloatFromString("9")
+			j.MaxPrice = *values.NewFloatFromString("10.5")
+
+			j.execute(newTestIntent("1", time.Now()))
+			if parked := j.parkedOrders(); len(parked) != 1 || parked[0].Reason != string(errorBounds) {
+				t.Fatalf("parked = %d", len(parked))
+			}
+
+			j.MaxPrice = *values.NewFloatFromString(tt.maxPrice)
+			j.rearm(values.NewFloatFromString(tt.price))
+
+			orders, _ := ex.GetOpenOrders(j.Symbol)
+			if rearmed := len(orders) == 1; rearmed != tt.rearmed {
+				t.Errorf("rearmed = %v, want %v", rearmed, tt.rearmed)
+			}
+			if parked := len(j.parkedOrders()) == 1; parked == tt.rearmed {
+				t.Errorf("parked = %v, want %v", parked, !tt.rearmed)
+			}
+			for _, o := range orders {
+				if err := j.checkBounds(o.Price); err != nil {
+					t.Errorf("placed outside of the band: %s", err)
+				}
+			}
+		})
+	}
+}
+
+func TestParkNotifications(t *testing.T) {
+	messages := 0
+	mx := sync.Mutex{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mx.Lock()
+		messages++
+		mx.Unlock()
+		fmt.Fprint(w, "ok")
+	}))
+	defer srv.Close()
+
+	// The paper account can't afford the sell order
+	j, _ := newBoundsJob(t, "0")
+	j.Notifier = []*notifier.Notifier{{Driver: "slack", Endpoint: srv.URL}}
+
+	steps := []struct {
+		name     string
+		run      func()
+		reason   errorClass
+		messages int
+	}{
+		{"parked", func() { j.execute(newTestIntent("1", time.Now())) }, errorBalance, 1},
+		{"retried", j.retryParked, errorBalance, 1},
+		{"retried again", j.retryParked, errorBalance, 1},
+		{"reason changed", func() {
+			j.MaxPrice = *values.NewFloatFromString("10.5")
+			j.execute(j.parkedOrders()[0])
+		}, errorBounds, 2},
+	}
+
+	for _, s := range steps {
+		s.run()
+		j.work.Wait()
+
+		parked := j.parkedOrders()
+		if len(parked) != 1 || parked[0].Reason != string(s.reason) {
+			t.Fatalf("%s: parked = %d", s.name, len(parked))
+		}
+		mx.Lock()
+		if messages != s.messages {
+			t.Errorf("%s: notifications = %d, want %d", s.name, messages, s.messages)
+		}
+		mx.Unlock()
+	}
+}
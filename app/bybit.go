@@ -0,0 +1,241 @@
+package app
+
+import (
+	"../api/bybit"
+	"../utils/log"
+	"../utils/values"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterExchange("bybit", NewBybitExchange)
+}
+
+type BybitExchange struct {
+	provider *Provider
+	client   *bybit.Config
+	clock    *Clock
+}
+
+func NewBybitExchange(p *Provider) (Exchange, error) {
+	client := p.NewBybitClient()
+	if client == nil {
+		return nil, errors.New(fmt.Sprintf("%s client could not be created", strings.ToUpper(p.Name)))
+	}
+
+	e := &BybitExchange{
+		provider: p,
+		client:   client,
+	}
+
+	// The timestamp of a signed request is the local time minus the offset
+	e.clock = NewClock(p.Name, client.GetServerTime, client.SetTimeOffset)
+	if err := e.clock.Sync(); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// SyncClock measures the offset to the Bybit server time.
+func (e *BybitExchange) SyncClock() error {
+	return e.clock.Sync()
+}
+
+func (e *BybitExchange) GetFilter(symbol string) (*Filter, error) {
+	i := e.client.GetInstrument(symbol)
+	if i == nil {
+		return nil, errors.New(fmt.Sprintf("unknown symbol: %s", symbol))
+	}
+
+	filter := DefaultFilter()
+	filter.StepSize = values.NewFloat(&i.LotSizeFilter.BasePrecision.Float)
+	filter.TickSize = values.NewFloat(&i.PriceFilter.TickSize.Float)
+	filter.MinNotional = values.NewFloat(&i.LotSizeFilter.MinOrderAmt.Float)
+
+	return filter, nil
+}
+
+func (e *BybitExchange) GetBalances() (map[string]*values.Float, error) {
+	return e.client.GetBalances()
+}
+
+func (e *BybitExchange) GetOpenOrders(symbol string) ([]*Order, error) {
+	orders, err := e.client.GetOpenOrders(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Order, 0)
+	for _, o := range orders {
+		if o.OrderType != "Limit" {
+			continue
+		}
+		result = append(result, e.newOrder(o))
+	}
+	return result, nil
+}
+
+func (e *BybitExchange) PlaceOrder(r *OrderRequest) (*Order, error) {
+	id, err := e.client.PlaceOrder(r.Symbol, r.Side, r.Price, r.Amount, r.ClientId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Order{
+		Id:       id,
+		ClientId: r.ClientId,
+		Symbol:   r.Symbol,
+		Volume:   r.Amount,
+		Price:    r.Price,
+		Total:    r.Amount.Mul(r.Price),
+		Fee:      values.NewEmptyFloat(),
+		Side:     r.Side,
+		Status:   StatusNew,
+		Date:     time.Now(),
+	}, nil
+}
+
+func (e *BybitExchange) CancelOrder(symbol string, id string) error {
+	return e.client.CancelOrder(symbol, id)
+}
+
+// GetOrder returns the order with the given id, including closed orders.
+func (e *BybitExchange) GetOrder(symbol string, id string) (*Order, error) {
+	o, err := e.client.GetOrder(symbol, id)
+	if err != nil {
+		return nil, err
+	}
+	return e.closedOrder(o), nil
+}
+
+// GetOrderByClientId returns the order with the given order link id,
+// including closed orders.
+func (e *BybitExchange) GetOrderByClientId(symbol string, clientId string) (*Order, error) {
+	o, err := e.client.GetOrderByLinkId(symbol, clientId)
+	if err != nil || o == nil {
+		return nil, err
+	}
+	return e.closedOrder(o), nil
+}
+
+func (e *BybitExchange) GetTrades(symbol string, since time.Time) ([]*Trade, error) {
+	executions, err := e.client.GetExecutions(symbol, since)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Trade, 0)
+	for _, x := range executions {
+		ts, _ := strconv.ParseInt(x.ExecTime, 10, 64)
+		result = append(result, &Trade{
+			Id:      x.ExecId,
+			OrderId: x.OrderId,
+			Symbol:  x.Symbol,
+			Side:    strings.ToLower(x.Side),
+			Price:   values.NewFloat(&x.ExecPrice.Float),
+			Volume:  values.NewFloat(&x.ExecQty.Float),
+			Date:    time.Unix(0, ts*int64(time.Millisecond)),
+		})
+	}
+	return result, nil
+}
+
+func (e *BybitExchange) newOrder(o *bybit.Order) *Order {
+	volume := values.NewFloat(&o.Qty.Float)
+	price := values.NewFloat(&o.Price.Float)
+	ts, _ := strconv.ParseInt(o.CreatedTime, 10, 64)
+
+	order := &Order{
+		Id:       o.OrderId,
+		ClientId: o.OrderLinkId,
+		Symbol:   o.Symbol,
+		Volume:   volume,
+		Price:    price,
+		Total:    volume.Mul(price),
+		Fee:      values.NewEmptyFloat(),
+		Side:     strings.ToLower(o.Side),
+		Status:   StatusNew,
+		Date:     time.Unix(0, ts*int64(time.Millisecond)),
+	}
+	order.setExecuted(values.NewFloat(&o.CumExecQty.Float))
+
+	return order
+}
+
+// closedOrder returns an order including its final status.
+func (e *BybitExchange) closedOrder(o *bybit.Order) *Order {
+	order := e.newOrder(o)
+	switch o.OrderStatus {
+	case "PartiallyFilled":
+		order.Status = StatusPartial
+	case "Filled":
+		order.Status = StatusFilled
+	case "Cancelled", "PartiallyFilledCanceled", "Rejected", "Deactivated":
+		order.Status = StatusCanceled
+	}
+	return order
+}
+
+func (e *BybitExchange) handleAccountUpdates(upd bybit.AccountUpd, handler EventHandler) {
+	for _, o := range upd.Orders {
+		if o.Category != "spot" || o.OrderType != "Limit" {
+			continue
+		}
+		symbol := o.Symbol
+
+		order := e.newOrder(o)
+		switch o.OrderStatus {
+		case "New":
+			handler(&Event{Type: EventNew, Symbol: symbol, Order: order})
+		case "Filled":
+			order.Status = StatusFilled
+			order.Date = time.Now()
+			handler(&Event{Type: EventFilled, Symbol: symbol, Order: order})
+		case "PartiallyFilled":
+			order.Status = StatusPartial
+			handler(&Event{Type: EventPartial, Symbol: symbol, Order: order})
+		case "Cancelled", "PartiallyFilledCanceled", "Rejected", "Deactivated":
+			order.Status = StatusCanceled
+			handler(&Event{Type: EventCanceled, Symbol: symbol, Order: order})
+		}
+	}
+
+	for _, x := range upd.Executions {
+		if x.Category != "spot" {
+			continue
+		}
+		log.Info(fmt.Sprintf("%s ORDER UPDATE: %s %s @ %s - %.8f at %.8f", strings.ToUpper(e.provider.Name), x.Symbol, x.OrderId, strings.ToLower(x.Side), x.ExecQty.ToFloat(), x.ExecPrice.ToFloat()))
+	}
+}
+
+func (e *BybitExchange) Watch(ctx context.Context, handler EventHandler) {
+	go e.clock.Run(ctx)
+
+	AcUpdChan := make(chan bybit.AccountUpd, 128)
+	stopChan := make(chan bool)
+
+	go func() {
+		for upd := range AcUpdChan {
+			e.handleAccountUpdates(upd, handler)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		close(stopChan)
+	}()
+
+	for ctx.Err() == nil {
+		log.Success(fmt.Sprintf("Subscribing to %s account update events..", strings.ToUpper(e.provider.Name)))
+		handler(&Event{Type: EventConnected})
+		if err := e.client.SubscribeAccount(AcUpdChan, stopChan); err != nil {
+			log.Error(err)
+			sleep(ctx, time.Second)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"../api/bybit"
+	"../utils/values"
+	"testing"
+)
+
+func TestBybitAccountUpdates(t *testing.T) {
+	tests := []struct {
+		name      string
+		category  string
+		orderType string
+		status    string
+		executed  string
+		event     EventType
+		want      string
+	}{
+		{"new", "spot", "Limit", "New", "0", EventNew, StatusNew},
+		{"partially filled", "spot", "Limit", "PartiallyFilled", "10", EventPartial, StatusPartial},
+		{"filled", "spot", "Limit", "Filled", "30", EventFilled, StatusFilled},
+		{"canceled", "spot", "Limit", "Cancelled", "0", EventCanceled, StatusCanceled},
+		{"partially filled canceled", "spot", "Limit", "PartiallyFilledCanceled", "10", EventCanceled, StatusCanceled},
+		{"rejected", "spot", "Limit", "Rejected", "0", EventCanceled, StatusCanceled},
+		{"untriggered", "spot", "Limit", "Untriggered", "0", "", ""},
+		{"market order", "spot", "Market", "Filled", "30", "", ""},
+		{"linear", "linear", "Limit", "Filled", "30", "", ""},
+	}
+
+	e := &BybitExchange{provider: &Provider{Name: "bybit-test"}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := make([]*Event, 0)
+			e.handleAccountUpdates(bybit.AccountUpd{Orders: []*bybit.Order{{
+				Category:    tt.category,
+				Symbol:      "DOGEBTC",
+				OrderId:     "1321003749386327552",
+				OrderLinkId: "abc",
+				Side:        "Buy",
+				OrderType:   tt.orderType,
+				Price:       *values.NewFloatFromString("0.00000301"),
+				Qty:         *values.NewFloatFromString("30"),
+				CumExecQty:  *values.NewFloatFromString(tt.executed),
+				OrderStatus: tt.status,
+				CreatedTime: "1672364262444",
+			}}}, func(evt *Event) { events = append(events, evt) })
+
+			if tt.event == "" {
+				if len(events) != 0 {
+					t.Errorf("events = %d, want 0", len(events))
+				}
+				return
+			}
+			if len(events) != 1 {
+				t.Fatalf("events = %d, want 1", len(events))
+			}
+			evt, o := events[0], events[0].Order
+			if evt.Type != tt.event || evt.Symbol != "DOGEBTC" || o.Status != tt.want {
+				t.Errorf("event = %s %s %s", evt.Type, evt.Symbol, o.Status)
+			}
+			if o.Id != "1321003749386327552" || o.ClientId != "abc" || o.Side != SideBuy {
+				t.Errorf("order = %s %s %s", o.Id, o.ClientId, o.Side)
+			}
+			if o.Volume.ToString() != "30.00000000" || o.Price.ToString() != "0.00000301" || !o.Executed.Eq(values.NewFloatFromString(tt.executed)) {
+				t.Errorf("order = %s @ %s, executed %s", o.Volume.ToString(), o.Price.ToString(), o.Executed.ToString())
+			}
+		})
+	}
+}
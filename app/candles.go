@@ -0,0 +1,213 @@
+package app
+
+import (
+	"../utils/values"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Candle is a single OHLC entry. Trade ticks are stored as candles with
+// identical open, high, low and close prices.
+type Candle struct {
+	Time  time.Time
+	Open  *values.Float
+	High  *values.Float
+	Low   *values.Float
+	Close *values.Float
+}
+
+// Prices returns the price path of the candle in the order the market most
+// likely travelled it.
+func (c *Candle) Prices() []*values.Float {
+	if c.Open.Eq(c.High) && c.Open.Eq(c.Low) && c.Open.Eq(c.Close) {
+		return []*values.Float{c.Open}
+	}
+	if c.Close.Lt(c.Open) {
+		return []*values.Float{c.Open, c.High, c.Low, c.Close}
+	}
+	return []*values.Float{c.Open, c.Low, c.High, c.Close}
+}
+
+const (
+	// FormatAuto picks the format of a candle file from its header
+	FormatAuto    = ""
+	FormatCandles = "candles"
+	FormatTrades  = "trades"
+)
+
+var (
+	candleTimeColumns = []string{"time", "timestamp", "date", "datetime", "open_time", "open time"}
+	candleColumns     = []string{"open", "high", "low", "close"}
+)
+
+// LoadCandles reads a csv file containing either OHLC candles or trade
+// ticks. The columns get picked by the names of the header line, files
+// without a header have to start with time,open,high,low,close or with
+// time,price. Headerless files with more than two columns are only loaded
+// with an explicit format, trade exports carry additional columns as well.
+func LoadCandles(filename string, format string) ([]*Candle, error) {
+	if format != FormatAuto && format != FormatCandles && format != FormatTrades {
+		return nil, errors.New(fmt.Sprintf("unknown candle format: %s", format))
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	candles := make([]*Candle, 0)
+	var columns []int
+	line := 0
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		line++
+
+		if len(record) < 2 {
+			continue
+		}
+
+		if columns == nil {
+			if _, err := parseCandleTime(record[0]); err != nil && line == 1 {
+				if columns, err = headerColumns(record, format); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if columns, err = positionalColumns(len(record), format); err != nil {
+				return nil, err
+			}
+		}
+
+		c, err := parseCandle(record, columns)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("line %d: %s", line, err.Error()))
+		}
+		candles = append(candles, c)
+	}
+
+	if len(candles) == 0 {
+		return nil, errors.New(fmt.Sprintf("no candles found in %s", filename))
+	}
+
+	return candles, nil
+}
+
+// headerColumns returns the indexes of the time and price columns named by
+// the header. Candles take the time, open, high, low and close column,
+// trades the time and price column.
+func headerColumns(header []string, format string) ([]int, error) {
+	index := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := index[name]; !ok {
+			index[name] = i
+		}
+	}
+
+	t := -1
+	for _, name := range candleTimeColumns {
+		if i, ok := index[name]; ok {
+			t = i
+			break
+		}
+	}
+	if t < 0 {
+		return nil, errors.New(fmt.Sprintf("no time column found in header: %s", strings.Join(header, ",")))
+	}
+
+	if format != FormatTrades {
+		columns := []int{t}
+		for _, name := range candleColumns {
+			if i, ok := index[name]; ok {
+				columns = append(columns, i)
+			}
+		}
+		if len(columns) == 5 {
+			return columns, nil
+		}
+		if format == FormatCandles {
+			return nil, errors.New(fmt.Sprintf("no open, high, low and close columns found in header: %s", strings.Join(header, ",")))
+		}
+	}
+
+	if i, ok := index["price"]; ok {
+		return []int{t, i}, nil
+	}
+	return nil, errors.New(fmt.Sprintf("no price column found in header: %s", strings.Join(header, ",")))
+}
+
+// positionalColumns returns the columns of a file without a header.
+func positionalColumns(n int, format string) ([]int, error) {
+	switch {
+	case format == FormatCandles && n >= 5:
+		return []int{0, 1, 2, 3, 4}, nil
+	case format == FormatCandles:
+		return nil, errors.New(fmt.Sprintf("%d columns are too few for candles", n))
+	case format == FormatTrades || n == 2:
+		return []int{0, 1}, nil
+	}
+	return nil, errors.New(fmt.Sprintf("%d columns without a header, set the format to %s or %s", n, FormatCandles, FormatTrades))
+}
+
+// parseCandle reads a candle from the given columns, two columns hold the
+// time and price of a trade.
+func parseCandle(record []string, columns []int) (*Candle, error) {
+	for _, i := range columns {
+		if i >= len(record) {
+			return nil, errors.New(fmt.Sprintf("%d columns expected", i+1))
+		}
+	}
+
+	t, err := parseCandleTime(record[columns[0]])
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Candle{Time: t}
+	if len(columns) == 5 {
+		c.Open = values.NewFloatFromString(record[columns[1]])
+		c.High = values.NewFloatFromString(record[columns[2]])
+		c.Low = values.NewFloatFromString(record[columns[3]])
+		c.Close = values.NewFloatFromString(record[columns[4]])
+	} else {
+		price := values.NewFloatFromString(record[columns[1]])
+		c.Open, c.High, c.Low, c.Close = price, price, price, price
+	}
+
+	if !c.Low.Gt(values.ZeroFloat) {
+		return nil, errors.New("invalid price")
+	}
+	return c, nil
+}
+
+func parseCandleTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if n > 1e12 {
+			return time.Unix(0, n*int64(time.Millisecond)), nil
+		}
+		return time.Unix(n, 0), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New(fmt.Sprintf("invalid time: %s", s))
+}
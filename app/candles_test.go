@@ -0,0 +1,68 @@
+package app
+
+import (
+	"../utils/values"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestLoadCandles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "candles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name   string
+		csv    string
+		format string
+		prices string
+		err    bool
+	}{
+		{"candles", "time,open,high,low,close\n1600000000,5,6,4,5.5\n", FormatAuto, "5 6 4 5.5", false},
+		{"candles reordered", "open_time,close,open,low,high,volume\n1600000000,5.5,5,4,6,100\n", FormatAuto, "5 6 4 5.5", false},
+		{"trades", "time,price\n1600000000,5\n", FormatAuto, "5 5 5 5", false},
+		// A trade export isn't mistaken for candles because of its columns
+		{"trade export", "id,price,qty,quote_qty,time,is_buyer_maker\n1,5,10,50,1600000000,true\n", FormatAuto, "5 5 5 5", false},
+		{"trades of candles", "time,open,high,low,close,price\n1600000000,5,6,4,5.5,7\n", FormatTrades, "7 7 7 7", false},
+		{"candles without columns", "time,price\n1600000000,5\n", FormatCandles, "", true},
+		{"no time column", "id,price\n1,5\n", FormatAuto, "", true},
+		{"no price column", "time,qty\n1600000000,5\n", FormatAuto, "", true},
+		{"headerless trades", "1600000000,5\n", FormatAuto, "5 5 5 5", false},
+		{"headerless columns", "1600000000,5,6,4,5.5\n", FormatAuto, "", true},
+		{"headerless candles", "1600000000,5,6,4,5.5\n", FormatCandles, "5 6 4 5.5", false},
+		{"headerless trade export", "1600000000,5,6,4,5.5\n", FormatTrades, "5 5 5 5", false},
+		{"unknown format", "time,price\n1600000000,5\n", "ticks", "", true},
+		{"invalid price", "time,price\n1600000000,0\n", FormatAuto, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := path.Join(dir, "candles.csv")
+			if err := ioutil.WriteFile(file, []byte(tt.csv), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			candles, err := LoadCandles(file, tt.format)
+			if (err != nil) != tt.err {
+				t.Fatalf("error = %v", err)
+			}
+			if err != nil {
+				return
+			}
+			c := candles[0]
+			if c.Time.Unix() != 1600000000 {
+				t.Errorf("time = %d", c.Time.Unix())
+			}
+			for i, p := range strings.Fields(tt.prices) {
+				if got, want := []*values.Float{c.Open, c.High, c.Low, c.Close}[i], values.NewFloatFromString(p); !got.Eq(want) {
+					t.Errorf("price %d = %s, want %s", i, got.ToString(), want.ToString())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"../utils/log"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	clockSyncInterval = 10 * time.Minute
+	// Timestamp errors of concurrent requests cause a single resync
+	clockResyncInterval = 5 * time.Second
+)
+
+// Clock keeps track of the offset between the local clock and the server time
+// of an exchange. Signed requests have to carry a timestamp within the receive
+// window of the server, which a drifting local clock leaves sooner or later.
+type Clock struct {
+	name   string
+	fetch  func() (int64, error)
+	apply  func(offset int64)
+	offset int64
+	synced time.Time
+	mx     sync.Mutex
+}
+
+// NewClock creates a clock of a provider. fetch returns the server time in
+// milliseconds, apply receives the offset of the local clock in milliseconds.
+func NewClock(name string, fetch func() (int64, error), apply func(offset int64)) *Clock {
+	return &Clock{
+		name:  name,
+		fetch: fetch,
+		apply: apply,
+		mx:    sync.Mutex{},
+	}
+}
+
+// Sync measures the offset of the local clock. The server time is compared to
+// the middle of the round trip. A clock which got synced just now is kept.
+func (c *Clock) Sync() error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if !c.synced.IsZero() && time.Since(c.synced) < clockResyncInterval {
+		return nil
+	}
+
+	start := time.Now()
+	server, err := c.fetch()
+	if err != nil {
+		return err
+	}
+	end := time.Now()
+
+	local := start.Add(end.Sub(start)/2).UnixNano() / int64(time.Millisecond)
+	offset := local - server
+	if drift := offset - c.offset; !c.synced.IsZero() && (drift > 1000 || drift < -1000) {
+		log.Warn(fmt.Sprintf("%s CLOCK DRIFT: %dms", strings.ToUpper(c.name), drift))
+	}
+	log.Debug(fmt.Sprintf("%s clock offset: %dms", strings.ToUpper(c.name), offset))
+
+	c.offset = offset
+	c.synced = time.Now()
+	c.apply(offset)
+	return nil
+}
+
+// Run syncs the clock periodically until the context expires.
+func (c *Clock) Run(ctx context.Context) {
+	ticker := time.NewTicker(clockSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Sync(); err != nil {
+				log.Error(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package app
+
+import (
+	"../api/coinbase"
+	"../utils/log"
+	"../utils/values"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	RegisterExchange("coinbase", NewCoinbaseExchange)
+}
+
+// CoinbaseExchange doesn't implement ClientOrderGetter. Coinbase doesn't
+// create a second order with a known client order id, it returns the
+// existing order instead.
+type CoinbaseExchange struct {
+	provider *Provider
+	client   *coinbase.Config
+	clock    *Clock
+
+	// The user channel repeats the current order status on every update.
+	// The last known status is kept in order to emit each event only once.
+	status map[string]string
+	mx     sync.Mutex
+}
+
+func NewCoinbaseExchange(p *Provider) (Exchange, error) {
+	client := p.NewCoinbaseClient()
+	if client == nil {
+		return nil, errors.New(fmt.Sprintf("%s client could not be created", strings.ToUpper(p.Name)))
+	}
+
+	e := &CoinbaseExchange{
+		provider: p,
+		client:   client,
+		status:   make(map[string]string),
+		mx:       sync.Mutex{},
+	}
+
+	// The tokens of signed requests expire 2 minutes after the local time
+	e.clock = NewClock(p.Name, client.GetServerTime, client.SetTimeOffset)
+	if err := e.clock.Sync(); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// SyncClock measures the offset to the Coinbase server time.
+func (e *CoinbaseExchange) SyncClock() error {
+	return e.clock.Sync()
+}
+
+func (e *CoinbaseExchange) GetFilter(symbol string) (*Filter, error) {
+	p := e.client.GetProduct(symbol)
+	if p == nil {
+		return nil, errors.New(fmt.Sprintf("unknown symbol: %s", symbol))
+	}
+
+	filter := DefaultFilter()
+	filter.StepSize = values.NewFloat(&p.BaseIncrement.Float)
+	filter.TickSize = values.NewFloat(&p.QuoteIncrement.Float)
+	if p.PriceIncrement.Sign() > 0 {
+		filter.TickSize = values.NewFloat(&p.PriceIncrement.Float)
+	}
+	filter.MinNotional = values.NewFloat(&p.QuoteMinSize.Float)
+
+	return filter, nil
+}
+
+func (e *CoinbaseExchange) GetBalances() (map[string]*values.Float, error) {
+	return e.client.GetBalances()
+}
+
+func (e *CoinbaseExchange) GetOpenOrders(symbol string) ([]*Order, error) {
+	orders, err := e.client.GetOpenOrders(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Order, 0)
+	for _, o := range orders {
+		if order := e.newOrder(o); order != nil {
+			e.setStatus(o.OrderId, o.Status)
+			result = append(result, order)
+		}
+	}
+	return result, nil
+}
+
+func (e *CoinbaseExchange) PlaceOrder(r *OrderRequest) (*Order, error) {
+	id, err := e.client.PlaceOrder(r.Symbol, r.Side, r.Price, r.Amount, r.ClientId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Order{
+		Id:       id,
+		ClientId: r.ClientId,
+		Symbol:   r.Symbol,
+		Volume:   r.Amount,
+		Price:    r.Price,
+		Total:    r.Amount.Mul(r.Price),
+		Fee:      values.NewEmptyFloat(),
+		Side:     r.Side,
+		Status:   StatusNew,
+		Date:     time.Now(),
+	}, nil
+}
+
+func (e *CoinbaseExchange) CancelOrder(symbol string, id string) error {
+	return e.client.CancelOrder(id)
+}
+
+// GetOrder returns the order with the given id, including closed orders.
+func (e *CoinbaseExchange) GetOrder(symbol string, id string) (*Order, error) {
+	o, err := e.client.GetOrder(id)
+	if err != nil {
+		return nil, err
+	}
+
+	order := e.newOrder(o)
+	if order == nil {
+		return nil, errors.New(fmt.Sprintf("not a limit order: %s", id))
+	}
+	switch o.Status {
+	case "OPEN":
+		if o.FilledSize.Sign() > 0 {
+			order.Status = StatusPartial
+		}
+	case "FILLED":
+		order.Status = StatusFilled
+	case "CANCELLED", "EXPIRED", "FAILED":
+		order.Status = StatusCanceled
+	}
+	return order, nil
+}
+
+func (e *CoinbaseExchange) GetTrades(symbol string, since time.Time) ([]*Trade, error) {
+	fills, err := e.client.GetFills(symbol, since)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Trade, 0)
+	for _, f := range fills {
+		result = append(result, &Trade{
+			Id:      f.TradeId,
+			OrderId: f.OrderId,
+			Symbol:  f.ProductId,
+			Side:    strings.ToLower(f.Side),
+			Price:   values.NewFloat(&f.Price.Float),
+			Volume:  values.NewFloat(&f.Size.Float),
+			Date:    f.TradeTime,
+		})
+	}
+	return result, nil
+}
+
+// newOrder returns the order of a good till canceled limit order and nil for
+// every other order type.
+func (e *CoinbaseExchange) newOrder(o *coinbase.Order) *Order {
+	limit := o.OrderConfiguration.LimitGtc
+	if limit == nil {
+		return nil
+	}
+	volume := values.NewFloatFromString(limit.BaseSize)
+	price := values.NewFloatFromString(limit.LimitPrice)
+
+	order := &Order{
+		Id:       o.OrderId,
+		ClientId: o.ClientOrderId,
+		Symbol:   o.ProductId,
+		Volume:   volume,
+		Price:    price,
+		Total:    volume.Mul(price),
+		Fee:      values.NewEmptyFloat(),
+		Side:     strings.ToLower(o.Side),
+		Status:   StatusNew,
+		Date:     o.CreatedTime,
+	}
+	order.setExecuted(values.NewFloat(&o.FilledSize.Float))
+
+	return order
+}
+
+// setStatus stores the status of an order and reports whether it changed.
+// Orders which reached a final status are forgotten.
+func (e *CoinbaseExchange) setStatus(id string, status string) bool {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
+	if e.status[id] == status {
+		return false
+	}
+	switch status {
+	case "FILLED", "CANCELLED", "EXPIRED", "FAILED":
+		delete(e.status, id)
+	default:
+		e.status[id] = status
+	}
+	return true
+}
+
+func (e *CoinbaseExchange) handleUserEvent(evt coinbase.UserEvent, handler EventHandler) {
+	for _, o := range evt.Orders {
+		if !strings.EqualFold(o.OrderType, "limit") {
+			continue
+		}
+		symbol := o.ProductId
+		status := o.Status
+		if status == "OPEN" && o.CumulativeQuantity.Sign() > 0 {
+			// Every partial fill of an open order is an update of its own
+			status = fmt.Sprintf("%s:%s", status, o.CumulativeQuantity.ToString())
+		}
+		if !e.setStatus(o.OrderId, status) {
+			continue
+		}
+
+		price := values.NewFloat(&o.LimitPrice.Float)
+		if price.Sign() == 0 {
+			price = values.NewFloat(&o.AvgPrice.Float)
+		}
+		volume := o.CumulativeQuantity.Add(&o.LeavesQuantity)
+		order := &Order{
+			Id:       o.OrderId,
+			ClientId: o.ClientOrderId,
+			Symbol:   o.ProductId,
+			Volume:   volume,
+			Price:    price,
+			Total:    volume.Mul(price),
+			Fee:      values.NewEmptyFloat(),
+			Side:     strings.ToLower(o.OrderSide),
+			Date:     o.CreationTime,
+		}
+		order.setExecuted(values.NewFloat(&o.CumulativeQuantity.Float))
+
+		switch o.Status {
+		case "OPEN":
+			if o.CumulativeQuantity.Sign() > 0 {
+				order.Status = StatusPartial
+				handler(&Event{Type: EventPartial, Symbol: symbol, Order: order})
+				continue
+			}
+			order.Status = StatusNew
+			handler(&Event{Type: EventNew, Symbol: symbol, Order: order})
+		case "FILLED":
+			order.Status = StatusFilled
+			order.Date = time.Now()
+			handler(&Event{Type: EventFilled, Symbol: symbol, Order: order})
+		case "CANCELLED", "EXPIRED", "FAILED":
+			order.Status = StatusCanceled
+			handler(&Event{Type: EventCanceled, Symbol: symbol, Order: order})
+		default:
+			log.Info(fmt.Sprintf("%s ORDER UPDATE: %s %s @ %s - %s", strings.ToUpper(e.provider.Name), symbol, o.OrderId, order.Side, o.Status))
+		}
+	}
+}
+
+func (e *CoinbaseExchange) Watch(ctx context.Context, handler EventHandler) {
+	go e.clock.Run(ctx)
+
+	UserChan := make(chan coinbase.UserEvent, 128)
+	stopChan := make(chan bool)
+
+	go func() {
+		for evt := range UserChan {
+			e.handleUserEvent(evt, handler)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		close(stopChan)
+	}()
+
+	for ctx.Err() == nil {
+		log.Success(fmt.Sprintf("Subscribing to %s account update events..", strings.ToUpper(e.provider.Name)))
+		handler(&Event{Type: EventConnected})
+		if err := e.client.SubscribeUser(nil, UserChan, stopChan); err != nil {
+			log.Error(err)
+			sleep(ctx, time.Second)
+		}
+	}
+}
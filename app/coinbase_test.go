@@ -0,0 +1,68 @@
+package app
+
+import (
+	"../api/coinbase"
+	"../utils/values"
+	"testing"
+)
+
+func TestCoinbaseUserEvents(t *testing.T) {
+	// Updates of the same order, the user channel repeats unchanged states
+	steps := []struct {
+		name      string
+		status    string
+		orderType string
+		filled    string
+		event     EventType
+		executed  string
+	}{
+		{"open", "OPEN", "Limit", "0", EventNew, "0.00000000"},
+		{"repeated", "OPEN", "Limit", "0", "", ""},
+		{"partially filled", "OPEN", "Limit", "10", EventPartial, "10.00000000"},
+		{"repeated partial", "OPEN", "Limit", "10", "", ""},
+		{"filled again", "OPEN", "Limit", "20", EventPartial, "20.00000000"},
+		{"filled", "FILLED", "Limit", "30", EventFilled, "30.00000000"},
+		{"market order", "FILLED", "Market", "30", "", ""},
+	}
+
+	e := &CoinbaseExchange{provider: &Provider{Name: "coinbase-test"}, status: make(map[string]string)}
+	for _, s := range steps {
+		events := make([]*Event, 0)
+		e.handleUserEvent(coinbase.UserEvent{Type: "update", Orders: []*coinbase.UserOrder{{
+			OrderId:            "11111-00000-000000",
+			ClientOrderId:      "abc",
+			ProductId:          "DOGE-BTC",
+			OrderSide:          "BUY",
+			OrderType:          s.orderType,
+			Status:             s.status,
+			LimitPrice:         *values.NewFloatFromString("0.00000301"),
+			CumulativeQuantity: *values.NewFloatFromString(s.filled),
+			LeavesQuantity:     *values.NewFloatFromString("30").Sub(values.NewFloatFromString(s.filled)),
+		}}}, func(evt *Event) { events = append(events, evt) })
+
+		if s.event == "" {
+			if len(events) != 0 {
+				t.Errorf("%s: events = %d, want 0", s.name, len(events))
+			}
+			continue
+		}
+		if len(events) != 1 {
+			t.Fatalf("%s: events = %d, want 1", s.name, len(events))
+		}
+		evt, o := events[0], events[0].Order
+		if evt.Type != s.event || evt.Symbol != "DOGE-BTC" {
+			t.Errorf("%s: event = %s %s", s.name, evt.Type, evt.Symbol)
+		}
+		if o.Id != "11111-00000-000000" || o.ClientId != "abc" || o.Side != SideBuy || o.Volume.ToString() != "30.00000000" || o.Price.ToString() != "0.00000301" {
+			t.Errorf("%s: order = %s %s %s %s @ %s", s.name, o.Id, o.ClientId, o.Side, o.Volume.ToString(), o.Price.ToString())
+		}
+		if o.Executed.ToString() != s.executed {
+			t.Errorf("%s: executed = %s, want %s", s.name, o.Executed.ToString(), s.executed)
+		}
+	}
+
+	// Orders are forgotten once they reached a final status
+	if len(e.status) != 0 {
+		t.Errorf("status = %v", e.status)
+	}
+}
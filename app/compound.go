@@ -0,0 +1,146 @@
+package app
+
+import (
+	"../utils/config"
+	"../utils/filesystem"
+	"../utils/log"
+	"../utils/values"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Compounding is the profit which got added to the volume of a job. The
+// volume is the one used for the last buy counter order.
+type Compounding struct {
+	Profit *values.Float `json:"profit"`
+	Added  *values.Float `json:"added"`
+	Volume *values.Float `json:"volume"`
+}
+
+func NewCompounding() *Compounding {
+	return &Compounding{
+		Profit: values.NewEmptyFloat(),
+		Added:  values.NewEmptyFloat(),
+		Volume: values.NewEmptyFloat(),
+	}
+}
+
+// buyVolume returns the volume of a new buy counter order. A share of the
+// realized profit gets added to the job volume, limited by max-volume.
+func (j *Job) buyVolume() *values.Float {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	return j.compoundedVolume()
+}
+
+// compoundedVolume has to be called while holding the job mutex.
+func (j *Job) compoundedVolume() *values.Float {
+	if !j.Compound.Gt(values.ZeroFloat) {
+		return values.NewFloat(&j.Volume.Float)
+	}
+
+	volume := j.Volume.Add(j.compounding.Added)
+	if j.MaxVolume.Gt(values.ZeroFloat) && volume.Gt(&j.MaxVolume) {
+		volume = values.NewFloat(&j.MaxVolume.Float)
+	}
+	if volume.Lt(&j.Volume) {
+		volume = values.NewFloat(&j.Volume.Float)
+	}
+	return volume
+}
+
+// compound adds the share of the realized profit of a filled sell order to
+// the volume of the job.
+func (j *Job) compound(o *Order) {
+	if o.Side != SideSell || !j.Compound.Gt(values.ZeroFloat) || j.ProfitAsset == ProfitBase {
+		// Profit which is kept in the base asset doesn't add to the quote balance
+		return
+	}
+
+	profit := j.sellProfit(o)
+	if !profit.Gt(values.ZeroFloat) {
+		return
+	}
+
+	j.mx.Lock()
+	j.compounding.Profit = j.compounding.Profit.Add(profit)
+	j.compounding.Added = j.compounding.Added.Add(profit.Mul(&j.Compound).Div(values.HundredFloat))
+	volume := j.compoundedVolume()
+	j.compounding.Volume = volume
+	j.mx.Unlock()
+
+	log.Info(fmt.Sprintf("%s VOLUME COMPOUNDED: %.8f %s", strings.ToUpper(j.Provider.Name), volume.ToFloat(), j.Primary))
+	j.saveCompounding()
+}
+
+func (j *Job) compoundingFile() string {
+	return path.Join(j.OrderDir, "volume", j.Id+".json")
+}
+
+// saveCompounding writes the compounded volume of the job.
+func (j *Job) saveCompounding() {
+	if j.simulate {
+		return
+	}
+
+	j.saveMx.Lock()
+	defer j.saveMx.Unlock()
+
+	j.mx.Lock()
+	c := *j.compounding
+	j.mx.Unlock()
+
+	d := path.Dir(j.compoundingFile())
+	filesystem.CreateDirectory(d)
+
+	cfg := config.NewConfig()
+	cfg.RootDir = d
+	cfg.File = j.compoundingFile()
+	cfg.Silent = true
+	cfg.SetContext(&c)
+
+	_, _ = cfg.Save()
+}
+
+// loadCompounding reads the compounded volume of a previous run. A job which
+// starts compounding adds the profit of all saved sell orders first.
+func (j *Job) loadCompounding() {
+	if j.simulate {
+		return
+	}
+
+	c := NewCompounding()
+	if _, err := os.Stat(j.compoundingFile()); err == nil {
+		cfg := config.NewConfig()
+		cfg.File = j.compoundingFile()
+		cfg.Silent = true
+		cfg.SetContext(c)
+		cfg.Load(j.compoundingFile())
+	} else if j.Compound.Gt(values.ZeroFloat) {
+		files, _ := filepath.Glob(path.Join(j.OrderDir, "*", j.Id, "*.json"))
+		for _, file := range files {
+			o := j.LoadOrder(file)
+			if o.Side != SideSell || (o.Status != StatusFilled && o.Status != StatusPartial) {
+				continue
+			}
+			if profit := j.sellProfit(o); profit.Gt(values.ZeroFloat) {
+				c.Profit = c.Profit.Add(profit)
+			}
+		}
+		c.Added = c.Profit.Mul(&j.Compound).Div(values.HundredFloat)
+	}
+
+	j.mx.Lock()
+	j.compounding = c
+	c.Volume = j.compoundedVolume()
+	j.mx.Unlock()
+
+	if j.Compound.Gt(values.ZeroFloat) {
+		log.Info(fmt.Sprintf("%s %s ORDER VOLUME: %.8f %s", strings.ToUpper(j.Provider.Name), strings.ToUpper(j.Symbol), c.Volume.ToFloat(), j.Primary))
+		j.saveCompounding()
+	}
+}
@@ -0,0 +1,119 @@
+package app
+
+import (
+	"../utils/values"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestBuyVolume(t *testing.T) {
+	tests := []struct {
+		name      string
+		compound  string
+		added     string
+		maxVolume string
+		volume    string
+	}{
+		{"disabled", "0", "10", "0", "100.00000000"},
+		{"compounded", "50", "10", "0", "110.00000000"},
+		{"limited", "50", "30", "120", "120.00000000"},
+		{"below the limit", "50", "10", "120", "110.00000000"},
+		// The job volume is the minimum
+		{"limit below the volume", "50", "10", "90", "100.00000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := newTestJob("0")
+			j.Compound = *values.NewFloatFromString(tt.compound)
+			j.MaxVolume = *values.NewFloatFromString(tt.maxVolume)
+			j.compounding.Added = values.NewFloatFromString(tt.added)
+
+			if v := j.buyVolume().ToString(); v != tt.volume {
+				t.Errorf("volume = %s, want %s", v, tt.volume)
+			}
+		})
+	}
+}
+
+func TestCompound(t *testing.T) {
+	// A sell of 10 at 11 was bought at 10 and earns 10
+	sell := &Order{Id: "s1", Side: SideSell, Volume: values.NewFloatFromString("10"), Price: values.NewFloatFromString("11"), Status: StatusFilled}
+	buy := &Order{Id: "b1", Side: SideBuy, Volume: values.NewFloatFromString("10"), Price: values.NewFloatFromString("10"), Status: StatusFilled}
+
+	tests := []struct {
+		name     string
+		compound string
+		fee      string
+		profit   string
+		orders   []*Order
+		profits  string
+		volume   string
+	}{
+		{"sell", "50", "0", ProfitQuote, []*Order{sell}, "10.00000000", "105.00000000"},
+		{"sells", "50", "0", ProfitQuote, []*Order{sell, sell}, "20.00000000", "110.00000000"},
+		{"all of the profit", "100", "0", ProfitQuote, []*Order{sell}, "10.00000000", "110.00000000"},
+		{"buys aren't compounded", "50", "0", ProfitQuote, []*Order{buy}, "0.00000000", "100.00000000"},
+		// The fees of 21 exceed the gain of 10
+		{"losses aren't compounded", "50", "10", ProfitQuote, []*Order{sell}, "0.00000000", "100.00000000"},
+		{"base profit isn't compounded", "50", "0", ProfitBase, []*Order{sell}, "0.00000000", "100.00000000"},
+		{"disabled", "0", "0", ProfitQuote, []*Order{sell}, "0.00000000", "100.00000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := newTestJob(tt.fee)
+			j.simulate = true
+			j.Compound = *values.NewFloatFromString(tt.compound)
+			j.ProfitAsset = tt.profit
+
+			for _, o := range tt.orders {
+				j.compound(o)
+			}
+			if p := j.compounding.Profit.ToString(); p != tt.profits {
+				t.Errorf("profit = %s, want %s", p, tt.profits)
+			}
+			if v := j.buyVolume().ToString(); v != tt.volume {
+				t.Errorf("volume = %s, want %s", v, tt.volume)
+			}
+		})
+	}
+}
+
+func TestLoadCompounding(t *testing.T) {
+	dir, err := ioutil.TempDir("", "compound")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	j := newTestJob("0")
+	j.OrderDir = dir
+	j.Compound = *values.NewFloatFromString("50")
+	for _, o := range []*Order{
+		{Id: "s1", Side: SideSell, Volume: values.NewFloatFromString("10"), Price: values.NewFloatFromString("11"), Status: StatusFilled},
+		{Id: "s2", Side: SideSell, Volume: values.NewFloatFromString("20"), Price: values.NewFloatFromString("11"), Status: StatusPartial},
+		{Id: "s3", Side: SideSell, Volume: values.NewFloatFromString("10"), Price: values.NewFloatFromString("11"), Status: StatusCanceled},
+		{Id: "b1", Side: SideBuy, Volume: values.NewFloatFromString("10"), Price: values.NewFloatFromString("10"), Status: StatusFilled},
+	} {
+		j.SaveOrder(o)
+	}
+
+	// A job which starts compounding adds the profit of the saved sells
+	j.loadCompounding()
+	if p, v := j.compounding.Profit.ToString(), j.buyVolume().ToString(); p != "30.00000000" || v != "115.00000000" {
+		t.Fatalf("profit = %s, volume = %s, want 30, 115", p, v)
+	}
+
+	// Afterwards the saved compounding is used
+	j.compound(&Order{Id: "s4", Side: SideSell, Volume: values.NewFloatFromString("10"), Price: values.NewFloatFromString("11"), Status: StatusFilled})
+
+	restarted := newTestJob("0")
+	restarted.OrderDir = dir
+	restarted.Compound = *values.NewFloatFromString("50")
+	restarted.loadCompounding()
+	if p, v := restarted.compounding.Profit.ToString(), restarted.buyVolume().ToString(); p != "40.00000000" || v != "120.00000000" {
+		t.Errorf("profit = %s, volume = %s, want 40, 120", p, v)
+	}
+}
@@ -1,10 +1,10 @@
 package app
 
 import (
-	"../api/poloniex"
 	"../utils/config"
 	"../utils/values"
 	"./notifier"
+	"context"
 	"github.com/adshao/go-binance/v2"
 	"os"
 	"sync"
@@ -19,8 +19,9 @@ type Build struct {
 type Config struct {
 	*config.Config
 
-	Timezone string `json:"timezone"`
-	JobDir   string `json:"job-dir"`
+	Timezone        string `json:"timezone"`
+	JobDir          string `json:"job-dir"`
+	ShutdownTimeout int    `json:"shutdown-timeout"`
 
 	Provider []*Provider          `json:"provider"`
 	Notifier []*notifier.Notifier `json:"notifier"`
@@ -39,28 +40,66 @@ type Job struct {
 	Secondary string `json:"-"`
 	OrderDir  string `json:"order-dir"`
 
-	Volume      values.Float `json:"volume,string"`
-	Step        values.Float `json:"step,string"`
-	BuyStep     values.Float `json:"buy-step,string"`
-	SellStep    values.Float `json:"sell-step,string"`
+	Volume   values.Float `json:"volume,string"`
+	Step     values.Float `json:"step,string"`
+	BuyStep  values.Float `json:"buy-step,string"`
+	SellStep values.Float `json:"sell-step,string"`
+
+	StepPercent     values.Float `json:"step-percent,string"`
+	BuyStepPercent  values.Float `json:"buy-step-percent,string"`
+	SellStepPercent values.Float `json:"sell-step-percent,string"`
+
+	MinPrice values.Float `json:"min-price,string"`
+	MaxPrice values.Float `json:"max-price,string"`
+	Rearm    bool         `json:"rearm"`
+
+	// Share of the realized profit in percent which gets added to the volume
+	Compound  values.Float `json:"compound,string"`
+	MaxVolume values.Float `json:"max-volume,string"`
+
+	ProfitAsset string `json:"profit-asset"`
+
 	Fee         values.Float `json:"fee,string"`
 	Enabled     bool         `json:"enabled"`
+	Integrity   string       `json:"integrity"`
+	Partial     string       `json:"partial-fills"`
 	Alert       *Alert       `json:"alerts"`
 	ProviderId  string       `json:"provider"`
 	NotifierIds []string     `json:"notifier"`
 	Provider    *Provider    `json:"-"`
 
-	stepSize *values.Float `json:"-"`
+	filter   *Filter `json:"-"`
+	simulate bool    `json:"-"`
 
-	orders  map[int64]*Order         `json:"-"`
+	orders  map[string]*Order        `json:"-"`
 	balance map[string]*values.Float `json:"-"`
 
+	// Filled orders by id, in order to counter every fill once
+	filled      map[string]time.Time `json:"-"`
+	recovering  bool                 `json:"-"`
+	journal     *Journal             `json:"-"`
+	compounding *Compounding         `json:"-"`
+	queue       chan *Intent         `json:"-"`
+	// Set once the queue got drained on shutdown, guarded by queueMx
+	queueClosed bool       `json:"-"`
+	queueMx     sync.Mutex `json:"-"`
+
+	// Running placements, saves and notifications, awaited on shutdown
+	ctx          context.Context    `json:"-"`
+	cancel       context.CancelFunc `json:"-"`
+	subscription int                `json:"-"`
+	work         sync.WaitGroup     `json:"-"`
+
+	// Content of the job file, in order to detect changes on reload
+	source string `json:"-"`
+
 	lastOperation time.Time  `json:"-"`
 	mx            sync.Mutex `json:"-"`
+	saveMx        sync.Mutex `json:"-"`
 
-	PoloniexClient *poloniex.Config     `json:"-"`
-	BinanceClient  *binance.Client      `json:"-"`
-	Notifier       []*notifier.Notifier `json:"-"`
+	Exchange Exchange             `json:"-"`
+	hub      *EventHub            `json:"-"`
+	Notifier []*notifier.Notifier `json:"-"`
 }
 
 type Alert struct {
@@ -0,0 +1,133 @@
+package app
+
+import (
+	"../utils/values"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventNew      = EventType("new")
+	EventFilled   = EventType("filled")
+	EventCanceled = EventType("canceled")
+	EventBalance  = EventType("balance")
+	// EventPartial is emitted every time a part of an order got executed. The
+	// executed volume of the order is cumulative.
+	EventPartial = EventType("partial")
+
+	// EventConnected is emitted every time the account subscription gets
+	// (re)established. Events of the time in between might have been missed.
+	EventConnected = EventType("connected")
+)
+
+// Exchange is implemented by every exchange driver. A driver translates the
+// exchange specific api into normalized orders and events.
+type Exchange interface {
+	// GetFilter returns the trading rules of the given symbol.
+	GetFilter(symbol string) (*Filter, error)
+	// GetBalances returns the free balance of every asset.
+	GetBalances() (map[string]*values.Float, error)
+	// GetOpenOrders returns all open orders of the given symbol.
+	GetOpenOrders(symbol string) ([]*Order, error)
+	// PlaceOrder places a new limit order.
+	PlaceOrder(r *OrderRequest) (*Order, error)
+	// CancelOrder cancels an open order.
+	CancelOrder(symbol string, id string) error
+	// GetTrades returns the own trades of the given symbol executed since the
+	// given time.
+	GetTrades(symbol string, since time.Time) ([]*Trade, error)
+	// Watch subscribes to the order and balance updates of every symbol of
+	// the account and blocks until the context gets canceled and the
+	// connection got closed. Each provider is watched once by its EventHub.
+	Watch(ctx context.Context, handler EventHandler)
+}
+
+// SymbolWatcher is implemented by exchanges which have to know every watched
+// symbol, such as the paper exchange which starts a price feed per symbol.
+type SymbolWatcher interface {
+	WatchSymbol(ctx context.Context, symbol string)
+}
+
+// OrderGetter is implemented by exchanges which return the current status of
+// a single order, including orders which got filled or canceled already.
+// The status of the returned order is one of StatusNew, StatusPartial,
+// StatusFilled or StatusCanceled.
+type OrderGetter interface {
+	GetOrder(symbol string, id string) (*Order, error)
+}
+
+// ClientOrderGetter is implemented by exchanges which look up an order by its
+// client id, including orders which got filled or canceled already. A nil
+// order is returned if the exchange doesn't know the client id.
+type ClientOrderGetter interface {
+	GetOrderByClientId(symbol string, clientId string) (*Order, error)
+}
+
+// Ticker is implemented by exchanges which provide the last traded price of
+// a symbol.
+type Ticker interface {
+	GetPrice(symbol string) (*values.Float, error)
+}
+
+// ClockSyncer is implemented by exchanges which sign requests with a
+// timestamp. SyncClock measures the offset to the server time once more.
+type ClockSyncer interface {
+	SyncClock() error
+}
+
+type EventHandler func(evt *Event)
+
+// Event is a normalized account update, emitted by Exchange.Watch.
+type Event struct {
+	Type    EventType
+	Symbol  string
+	Order   *Order
+	Balance map[string]*values.Float
+}
+
+// Filter holds the trading rules of a symbol.
+type Filter struct {
+	StepSize    *values.Float
+	TickSize    *values.Float
+	MinNotional *values.Float
+}
+
+type ExchangeFactory func(p *Provider) (Exchange, error)
+
+var (
+	exchanges   = make(map[string]ExchangeFactory)
+	exchangesMx = sync.Mutex{}
+)
+
+func DefaultFilter() *Filter {
+	return &Filter{
+		StepSize:    values.NewFloatFromFloat64(0.00000001),
+		TickSize:    values.NewFloatFromFloat64(0.00000001),
+		MinNotional: values.NewEmptyFloat(),
+	}
+}
+
+// RegisterExchange makes an exchange driver available under the given name.
+// The name is matched against Provider.Exchange.
+func RegisterExchange(name string, factory ExchangeFactory) {
+	exchangesMx.Lock()
+	exchanges[strings.ToLower(name)] = factory
+	exchangesMx.Unlock()
+}
+
+func NewExchange(p *Provider) (Exchange, error) {
+	exchangesMx.Lock()
+	factory, ok := exchanges[strings.ToLower(p.Exchange)]
+	exchangesMx.Unlock()
+
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("unknown exchange: %s", p.Exchange))
+	}
+	return factory(p)
+}
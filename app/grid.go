@@ -0,0 +1,434 @@
+package app
+
+import (
+	"../utils/log"
+	"../utils/values"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GridConfig holds the options of the grid command.
+type GridConfig struct {
+	App   string
+	Job   string
+	Apply bool
+	Low   string
+	High  string
+	Price string
+	Yes   bool
+}
+
+func DefaultGridConfig() *GridConfig {
+	dir, _ := os.Getwd()
+
+	return &GridConfig{
+		App: path.Join(dir, "config", "app.json"),
+	}
+}
+
+// AddFlags adds configuration flags to the given FlagSet.
+func (c *GridConfig) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.App, "config", c.App, "Application config file")
+	fs.StringVar(&c.Job, "job", c.Job, "Job configuration file")
+	fs.BoolVar(&c.Apply, "apply", c.Apply, "Apply the changes instead of a dry-run")
+	fs.StringVar(&c.Low, "low", c.Low, "Lowest price of the grid")
+	fs.StringVar(&c.High, "high", c.High, "Highest price of the grid")
+	fs.StringVar(&c.Price, "price", c.Price, "Current price, which stays empty (default: last exchange price)")
+	fs.BoolVar(&c.Yes, "yes", c.Yes, "Don't ask for a confirmation")
+}
+
+// Range returns the parsed price options. Empty options are nil.
+func (c *GridConfig) Range() (low *values.Float, high *values.Float, price *values.Float, err error) {
+	parse := func(name string, s string) (*values.Float, error) {
+		if s == "" {
+			return nil, nil
+		}
+		f := values.NewFloatFromString(s)
+		if !f.Gt(values.ZeroFloat) {
+			return nil, errors.New(fmt.Sprintf("invalid %s price: %s", name, s))
+		}
+		return f, nil
+	}
+
+	if low, err = parse("low", c.Low); err != nil {
+		return
+	}
+	if high, err = parse("high", c.High); err != nil {
+		return
+	}
+	if price, err = parse("current", c.Price); err != nil {
+		return
+	}
+	if low != nil && high != nil && !low.Lt(high) {
+		err = errors.New("the low price has to be below the high price")
+	}
+	return
+}
+
+// NewGridJob loads a job and connects it to its provider without watching
+// the account.
+func NewGridJob(c *GridConfig) (*Job, error) {
+	if c.Job == "" {
+		return nil, errors.New("a job file is required")
+	}
+
+	j, err := LoadJobFromFile(c.Job)
+	if err != nil {
+		return nil, err
+	}
+	ac := NewConfigFromFile(c.App)
+
+	var p *Provider
+	for _, provider := range ac.Provider {
+		if provider.Name == j.ProviderId {
+			p = provider
+		}
+	}
+	if p == nil {
+		return nil, errors.New(fmt.Sprintf("unknown provider: %s", j.ProviderId))
+	}
+	if err := j.setProvider(p); err != nil {
+		return nil, err
+	}
+
+	f, err := j.Exchange.GetFilter(j.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	j.setFilter(f)
+	j.journal = LoadJournal(j.journalFile())
+	j.loadCompounding()
+
+	return j, nil
+}
+
+// Ladder returns the orders required to cover the range between low and
+// high. Sell orders are placed above and buy orders below the given price.
+// Buy orders get the compounded volume.
+func (j *Job) Ladder(price *values.Float, low *values.Float, high *values.Float) []*OrderRequest {
+	orders := make([]*OrderRequest, 0)
+	price = price.Truncate(j.getFilter().TickSize)
+
+	// A sum of steps isn't exact, prices within half a tick are equal
+	margin := j.getFilter().TickSize.Div(values.NewFloatFromFloat64(2))
+	low, high = low.Sub(margin), high.Add(margin)
+
+	if j.getStepAt(SideSell, price).Gt(values.ZeroFloat) {
+		for p := j.sellPrice(price); !p.Gt(high); p = j.sellPrice(p) {
+			orders = append(orders, &OrderRequest{
+				Symbol: j.Symbol,
+				Side:   SideSell,
+				Price:  p,
+				Amount: j.validateAmount(j.Volume.Div(p)),
+			})
+		}
+	}
+
+	if j.getStepAt(SideBuy, price).Gt(values.ZeroFloat) {
+		volume := j.buyVolume()
+		for p := j.buyPrice(price); !p.Lt(low) && p.Gt(values.ZeroFloat); p = j.buyPrice(p) {
+			orders = append(orders, &OrderRequest{
+				Symbol: j.Symbol,
+				Side:   SideBuy,
+				Price:  p,
+				Amount: j.validateAmount(volume.Div(p)),
+			})
+		}
+	}
+
+	return orders
+}
+
+// PlanGrid computes the ladder between low and high around the current price.
+// Rungs covered by an open order, outside of the price band of the job and
+// below the minimums of the symbol are left out.
+func (j *Job) PlanGrid(price *values.Float, low *values.Float, high *values.Float) (*GridPlacement, error) {
+	if price == nil {
+		t, ok := j.Exchange.(Ticker)
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("%s doesn't provide the current price, please set it by -price", j.Provider.Exchange))
+		}
+		p, err := t.GetPrice(j.Symbol)
+		if err != nil {
+			return nil, err
+		}
+		price = p
+	}
+
+	orders, err := j.Exchange.GetOpenOrders(j.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	balances, err := j.Exchange.GetBalances()
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]bool)
+	for _, o := range orders {
+		existing[o.Side+j.priceKey(o.Price)] = true
+	}
+
+	p := &GridPlacement{
+		Provider:  j.Provider.Name,
+		Symbol:    j.Symbol,
+		Price:     price,
+		Orders:    make([]*OrderRequest, 0),
+		TooSmall:  make([]*OrderRequest, 0),
+		Required:  map[string]*values.Float{j.Primary: values.NewEmptyFloat(), j.Secondary: values.NewEmptyFloat()},
+		Available: map[string]*values.Float{j.Primary: values.NewEmptyFloat(), j.Secondary: values.NewEmptyFloat()},
+	}
+	for asset := range p.Available {
+		if b, ok := balances[asset]; ok {
+			p.Available[asset] = b
+		}
+	}
+
+	for _, r := range j.Ladder(price, low, high) {
+		if existing[r.Side+j.priceKey(r.Price)] {
+			p.Existing++
+			continue
+		}
+		if j.checkBounds(r.Price) != nil {
+			p.OutOfBounds++
+			continue
+		}
+		if !j.counterable(r.Amount, r.Price) {
+			p.TooSmall = append(p.TooSmall, r)
+			continue
+		}
+
+		// Running the command twice doesn't place a rung twice
+		r.ClientId = counterOrderId(fmt.Sprintf("grid:%s:%s:%s", j.Symbol, r.Side, r.Price.ToString()))
+		p.Orders = append(p.Orders, r)
+
+		if r.Side == SideBuy {
+			p.Required[j.Primary] = p.Required[j.Primary].Add(r.Amount.Mul(r.Price))
+		} else {
+			p.Required[j.Secondary] = p.Required[j.Secondary].Add(r.Amount)
+		}
+	}
+
+	sort.SliceStable(p.Orders, func(a, b int) bool { return p.Orders[a].Price.Gt(p.Orders[b].Price) })
+	return p, nil
+}
+
+// PlaceGrid places the orders of a plan one after another. Rate limits and
+// other temporary errors are retried.
+func (j *Job) PlaceGrid(p *GridPlacement) {
+	for _, r := range p.Orders {
+		if j.placeRung(r) {
+			p.Placed++
+		} else {
+			p.Failed++
+		}
+	}
+}
+
+func (j *Job) placeRung(r *OrderRequest) bool {
+	for attempts := 1; ; attempts++ {
+		o, err := j.Exchange.PlaceOrder(r)
+		if err == nil {
+			log.Success(fmt.Sprintf("%s ORDER PLACED: %s %s %.8f @ %.8f", strings.ToUpper(j.Provider.Name), o.Id, strings.ToUpper(r.Side), r.Amount.ToFloat(), r.Price.ToFloat()))
+			return true
+		}
+
+		class := classifyError(err)
+		if class == errorDuplicate {
+			log.Warn(fmt.Sprintf("%s ORDER ALREADY PLACED: %s", strings.ToUpper(j.Provider.Name), r.ClientId))
+			return true
+		}
+		log.Error(err)
+		if !class.retryable(attempts) || attempts >= placementAttempts {
+			return false
+		}
+
+		d := backoff(attempts)
+		log.Warn(fmt.Sprintf("%s %s, retrying in %s..", strings.ToUpper(j.Provider.Name), class, d))
+		time.Sleep(d)
+	}
+}
+
+// PlanCancel returns the open orders which sit on the ladder of the job,
+// optionally limited to the range between low and high. Foreign orders, such
+// as manually placed ones, are kept.
+func (j *Job) PlanCancel(low *values.Float, high *values.Float) (*GridCancellation, error) {
+	orders, err := j.Exchange.GetOpenOrders(j.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &GridCancellation{
+		Provider: j.Provider.Name,
+		Symbol:   j.Symbol,
+		Orders:   make([]*Order, 0),
+	}
+	if len(orders) == 0 {
+		return c, nil
+	}
+
+	r, err := j.inspect(orders)
+	if err != nil {
+		return nil, err
+	}
+	foreign := make(map[string]bool)
+	for _, o := range r.Foreign {
+		foreign[o.Id] = true
+	}
+
+	for _, o := range orders {
+		if foreign[o.Id] {
+			c.Kept++
+			continue
+		}
+		if (low != nil && o.Price.Lt(low)) || (high != nil && o.Price.Gt(high)) {
+			continue
+		}
+		c.Orders = append(c.Orders, o)
+	}
+
+	sort.SliceStable(c.Orders, func(a, b int) bool { return c.Orders[a].Price.Gt(c.Orders[b].Price) })
+	return c, nil
+}
+
+// CancelGrid cancels the orders of a plan.
+func (j *Job) CancelGrid(c *GridCancellation) {
+	for _, o := range c.Orders {
+		if err := j.Exchange.CancelOrder(j.Symbol, o.Id); err != nil {
+			log.Error(err)
+			c.Failed++
+			continue
+		}
+		log.Warn(fmt.Sprintf("%s ORDER CANCELED: %s %s %.8f @ %.8f", strings.ToUpper(j.Provider.Name), o.Id, strings.ToUpper(o.Side), o.Volume.ToFloat(), o.Price.ToFloat()))
+		c.Canceled++
+	}
+}
+
+// priceKey identifies a price by its number of ticks, independent of its
+// decimal representation.
+func (j *Job) priceKey(p *values.Float) string {
+	tick := j.getFilter().TickSize
+	if !tick.Gt(values.ZeroFloat) {
+		return p.ToString()
+	}
+	return fmt.Sprintf("%d", int64(math.Round(p.ToFloat()/tick.ToFloat())))
+}
+
+// GridPlacement lists the orders of a new grid and the balances it requires.
+type GridPlacement struct {
+	Provider string
+	Symbol   string
+	Price    *values.Float
+
+	Orders []*OrderRequest
+	// Rungs below the lot size or the minimum notional of the symbol
+	TooSmall []*OrderRequest
+	// Rungs covered by an open order
+	Existing int
+	// Rungs outside of the price band of the job
+	OutOfBounds int
+
+	Required  map[string]*values.Float
+	Available map[string]*values.Float
+
+	Placed int
+	Failed int
+}
+
+// Sufficient reports whether the available balances cover all orders.
+func (p *GridPlacement) Sufficient() bool {
+	for asset, required := range p.Required {
+		if required.Gt(p.Available[asset]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *GridPlacement) String() string {
+	text := fmt.Sprintf("#### %s %s grid placement - current price %.8f\n", strings.ToUpper(p.Provider), strings.ToUpper(p.Symbol), p.Price.ToFloat())
+	if len(p.Orders) > 0 {
+		text = text + `
+| Side | Price | Volume | Total |
+|:-----|:------|:-------|:------|`
+		empty := false
+		for _, r := range p.Orders {
+			if r.Side == SideBuy && !empty {
+				text = text + "\n| EMPTY | EMPTY | EMPTY | EMPTY |"
+				empty = true
+			}
+			text = text + fmt.Sprintf("\n| %s | %.8f | %.8f | %.8f |", strings.ToUpper(r.Side), r.Price.ToFloat(), r.Amount.ToFloat(), r.Amount.Mul(r.Price).ToFloat())
+		}
+		text = text + "\n"
+	}
+
+	assets := make([]string, 0)
+	for asset := range p.Required {
+		assets = append(assets, asset)
+	}
+	sort.Strings(assets)
+
+	text = text + `
+| Asset | Required | Available |
+|:------|:---------|:----------|`
+	for _, asset := range assets {
+		text = text + fmt.Sprintf("\n| %s | %.8f | %.8f |", asset, p.Required[asset].ToFloat(), p.Available[asset].ToFloat())
+	}
+	text = text + "\n\n"
+
+	text = text + fmt.Sprintf("%d orders to place", len(p.Orders))
+	if p.Existing > 0 {
+		text = text + fmt.Sprintf(", %d rungs covered by an open order", p.Existing)
+	}
+	if p.OutOfBounds > 0 {
+		text = text + fmt.Sprintf(", %d rungs outside of the price band skipped", p.OutOfBounds)
+	}
+	if len(p.TooSmall) > 0 {
+		text = text + fmt.Sprintf(", %d rungs below the minimum order size skipped", len(p.TooSmall))
+	}
+	text = text + ".\n"
+	if !p.Sufficient() {
+		text = text + "The available balance doesn't cover all orders.\n"
+	}
+	return text
+}
+
+// GridCancellation lists the ladder orders of a job to cancel.
+type GridCancellation struct {
+	Provider string
+	Symbol   string
+
+	Orders []*Order
+	// Foreign orders which are kept
+	Kept int
+
+	Canceled int
+	Failed   int
+}
+
+func (c *GridCancellation) String() string {
+	text := fmt.Sprintf("#### %s %s grid cancellation\n", strings.ToUpper(c.Provider), strings.ToUpper(c.Symbol))
+	if len(c.Orders) > 0 {
+		text = text + `
+| Side | Price | Volume | Order |
+|:-----|:------|:-------|:------|`
+		for _, o := range c.Orders {
+			text = text + fmt.Sprintf("\n| %s | %.8f | %.8f | %s |", strings.ToUpper(o.Side), o.Price.ToFloat(), o.Volume.ToFloat(), o.Id)
+		}
+		text = text + "\n"
+	}
+
+	text = text + fmt.Sprintf("\n%d orders to cancel", len(c.Orders))
+	if c.Kept > 0 {
+		text = text + fmt.Sprintf(", %d foreign orders kept", c.Kept)
+	}
+	text = text + ".\n"
+	return text
+}
@@ -0,0 +1,43 @@
+package app
+
+import (
+	"../utils/values"
+	"testing"
+)
+
+func TestLadder(t *testing.T) {
+	tests := []struct {
+		name     string
+		compound string
+		added    string
+		want     []string
+	}{
+		{"fixed volume", "0", "0", []string{
+			"sell 9.09090909 @ 11.00000000", "sell 8.33333333 @ 12.00000000",
+			"buy 11.11111111 @ 9.00000000", "buy 12.50000000 @ 8.00000000",
+		}},
+		// Only buy orders use the compounded volume
+		{"compounded", "50", "10", []string{
+			"sell 9.09090909 @ 11.00000000", "sell 8.33333333 @ 12.00000000",
+			"buy 12.22222222 @ 9.00000000", "buy 13.75000000 @ 8.00000000",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := newTestJob("0")
+			j.Compound = *values.NewFloatFromString(tt.compound)
+			j.compounding.Added = values.NewFloatFromString(tt.added)
+
+			orders := j.Ladder(values.NewFloatFromString("10"), values.NewFloatFromString("8"), values.NewFloatFromString("12"))
+			if len(orders) != len(tt.want) {
+				t.Fatalf("orders = %d, want %d", len(orders), len(tt.want))
+			}
+			for i, o := range orders {
+				if got := o.Side + " " + o.Amount.ToString() + " @ " + o.Price.ToString(); got != tt.want[i] {
+					t.Errorf("order %d = %s, want %s", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+var (
+	hubs   = make(map[string]*EventHub)
+	hubsMx = sync.Mutex{}
+)
+
+// EventHub shares a single exchange connection between all jobs of a
+// provider and routes every event to the jobs trading its symbol.
+type EventHub struct {
+	Exchange Exchange
+
+	provider *Provider
+	handlers map[string][]*subscription
+	lastId   int
+	started  bool
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+	mx       sync.Mutex
+}
+
+type subscription struct {
+	id      int
+	handler EventHandler
+}
+
+// NewEventHub returns the hub of the given provider. The exchange driver gets
+// created once per provider, a changed provider configuration replaces the
+// hub. Jobs of the previous hub keep using it until they get stopped.
+func NewEventHub(p *Provider) (*EventHub, error) {
+	hubsMx.Lock()
+	defer hubsMx.Unlock()
+
+	if h, ok := hubs[p.Name]; ok && reflect.DeepEqual(h.provider, p) {
+		return h, nil
+	}
+
+	ex, err := NewExchange(p)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	close(done)
+
+	h := &EventHub{
+		Exchange: ex,
+		provider: p,
+		handlers: make(map[string][]*subscription),
+		started:  false,
+		done:     done,
+		mx:       sync.Mutex{},
+	}
+	hubs[p.Name] = h
+
+	return h, nil
+}
+
+// Subscribe registers an event handler for the given symbol and returns its
+// subscription id. The exchange gets watched as soon as the first handler is
+// registered.
+func (h *EventHub) Subscribe(symbol string, handler EventHandler) int {
+	h.mx.Lock()
+	h.lastId++
+	id := h.lastId
+	h.handlers[symbol] = append(h.handlers[symbol], &subscription{id: id, handler: handler})
+	start := !h.started
+	if start {
+		h.started = true
+		h.ctx, h.cancel = context.WithCancel(context.Background())
+		h.done = make(chan struct{})
+	}
+	ctx, done := h.ctx, h.done
+	h.mx.Unlock()
+
+	if w, ok := h.Exchange.(SymbolWatcher); ok {
+		w.WatchSymbol(ctx, symbol)
+	}
+	if start {
+		go func() {
+			h.Exchange.Watch(ctx, h.dispatch)
+			close(done)
+		}()
+	}
+	return id
+}
+
+// Unsubscribe removes an event handler. The exchange connection gets closed
+// as soon as the last handler is gone.
+func (h *EventHub) Unsubscribe(symbol string, id int) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	subs := make([]*subscription, 0)
+	for _, s := range h.handlers[symbol] {
+		if s.id != id {
+			subs = append(subs, s)
+		}
+	}
+	if len(subs) > 0 {
+		h.handlers[symbol] = subs
+	} else {
+		delete(h.handlers, symbol)
+	}
+
+	if len(h.handlers) == 0 && h.started {
+		h.started = false
+		h.cancel()
+	}
+}
+
+// Wait blocks until the exchange connection got closed or the given context
+// expires. A hub which isn't watching returns right away.
+func (h *EventHub) Wait(ctx context.Context) error {
+	h.mx.Lock()
+	done := h.done
+	h.mx.Unlock()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// dispatch routes an event to the handlers of its symbol. Balance updates and
+// reconnects concern every job of the account.
+func (h *EventHub) dispatch(evt *Event) {
+	h.mx.Lock()
+	handlers := make([]EventHandler, 0)
+	if evt.Type == EventBalance || evt.Type == EventConnected {
+		for _, subs := range h.handlers {
+			for _, s := range subs {
+				handlers = append(handlers, s.handler)
+			}
+		}
+	} else {
+		for _, s := range h.handlers[evt.Symbol] {
+			handlers = append(handlers, s.handler)
+		}
+	}
+	h.mx.Unlock()
+
+	for _, handler := range handlers {
+		handler(evt)
+	}
+}
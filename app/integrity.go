@@ -0,0 +1,391 @@
+package app
+
+import (
+	"../utils/log"
+	"../utils/values"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	IntegrityOff    = "off"
+	IntegrityReport = "report"
+	IntegrityApply  = "apply"
+)
+
+// GridReport lists the differences between the open orders of a job and the
+// ladder implied by its steps. Like Ladder, sell orders are expected every
+// sell step and buy orders every buy step.
+type GridReport struct {
+	Provider string
+	Symbol   string
+
+	// Rungs missing between the lowest and the highest order of a side
+	Missing []*OrderRequest
+	// Orders sharing their rung with an older order
+	Duplicates []*Order
+	// Orders which don't sit on the ladder, such as manually placed ones
+	Foreign []*Order
+
+	// Empty rungs between the highest buy and the lowest sell order which
+	// exceed the expected spread. Their side depends on the market price,
+	// which is why they won't get filled automatically.
+	GapLow   *values.Float
+	GapHigh  *values.Float
+	GapRungs int
+
+	Applied bool
+}
+
+// ladder holds the orders of one side by their rung, counted in ticks.
+type ladder struct {
+	step    int64
+	residue int64
+	// Rungs of a percentage step in ascending order, keyed by their index
+	levels []int64
+	rungs  map[int64][]*Order
+}
+
+// Check compares the open orders with the expected ladder. In apply mode
+// missing rungs get placed and duplicates get canceled.
+func (j *Job) Check(apply bool) (*GridReport, error) {
+	orders, err := j.Exchange.GetOpenOrders(j.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := j.inspect(orders)
+	if err != nil {
+		return nil, err
+	}
+	if apply {
+		j.repair(r)
+	}
+	return r, nil
+}
+
+// checkIntegrity runs the startup check according to the job integrity mode.
+func (j *Job) checkIntegrity(orders []*Order) {
+	if j.Integrity == IntegrityOff {
+		return
+	}
+
+	r, err := j.inspect(orders)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	if r.Ok() {
+		log.Success(fmt.Sprintf("%s %s GRID OK", strings.ToUpper(j.Provider.Name), strings.ToUpper(j.Symbol)))
+		return
+	}
+
+	if j.Integrity == IntegrityApply {
+		j.repair(r)
+	}
+
+	log.Warn(r.String())
+	j.Notify(r.String())
+}
+
+func (j *Job) inspect(orders []*Order) (*GridReport, error) {
+	r := &GridReport{
+		Provider:   j.Provider.Name,
+		Symbol:     j.Symbol,
+		Missing:    make([]*OrderRequest, 0),
+		Duplicates: make([]*Order, 0),
+		Foreign:    make([]*Order, 0),
+	}
+
+	tick := j.getFilter().TickSize
+	if !tick.Gt(values.ZeroFloat) {
+		return nil, errors.New("the tick size of the symbol is unknown")
+	}
+	ticks := func(p *values.Float) int64 {
+		return int64(math.Round(p.ToFloat() / tick.ToFloat()))
+	}
+
+	buys := make([]*Order, 0)
+	sells := make([]*Order, 0)
+	for _, o := range orders {
+		if o.Side == SideBuy {
+			buys = append(buys, o)
+		} else if o.Side == SideSell {
+			sells = append(sells, o)
+		}
+	}
+
+	// Rungs which are about to be placed by the placement queue
+	pending := make(map[string]bool)
+	for _, in := range j.journal.Pending() {
+		pending[in.Request.Side+in.Request.Price.ToString()] = true
+	}
+
+	sides := []struct {
+		side   string
+		orders []*Order
+		ladder *ladder
+	}{
+		{SideBuy, buys, nil},
+		{SideSell, sells, nil},
+	}
+	for i, s := range sides {
+		if len(s.orders) == 0 {
+			continue
+		}
+
+		var l *ladder
+		if pct := j.getStepPercent(s.side); pct.Gt(values.ZeroFloat) {
+			// Buy orders get placed downwards and sell orders upwards
+			next := func(t int64) int64 {
+				p := values.NewFloatFromFloat64(float64(t)).Mul(tick)
+				if s.side == SideBuy {
+					return ticks(j.stepDown(p, pct))
+				}
+				return ticks(j.stepUp(p, pct))
+			}
+			l = newPercentLadder(next, s.side == SideBuy, s.orders, ticks)
+		} else {
+			l = newLadder(ticks(j.getStep(s.side)), s.orders, ticks)
+		}
+		sides[i].ladder = l
+
+		for _, o := range s.orders {
+			if _, ok := l.key(ticks(o.Price)); !ok {
+				r.Foreign = append(r.Foreign, o)
+			}
+		}
+
+		keys := l.keys()
+		for _, k := range keys {
+			rung := l.rungs[k]
+			sort.Slice(rung, func(a, b int) bool { return rung[a].Date.Before(rung[b].Date) })
+			r.Duplicates = append(r.Duplicates, rung[1:]...)
+		}
+
+		// Buy rungs get the compounded volume of new buy orders
+		volume := &j.Volume
+		if s.side == SideBuy {
+			volume = j.buyVolume()
+		}
+		for k := keys[0]; k <= keys[len(keys)-1]; k++ {
+			if _, ok := l.rungs[k]; ok {
+				continue
+			}
+			price := l.price(k, tick)
+			if pending[s.side+price.ToString()] {
+				continue
+			}
+			r.Missing = append(r.Missing, &OrderRequest{
+				Symbol: j.Symbol,
+				Side:   s.side,
+				Price:  price,
+				Amount: j.validateAmount(volume.Div(price)),
+			})
+		}
+	}
+
+	if buy, sell := sides[0].ladder, sides[1].ladder; buy != nil && sell != nil {
+		bk, sk := buy.keys(), sell.keys()
+		highest := buy.price(bk[len(bk)-1], tick)
+		lowest := sell.price(sk[0], tick)
+
+		buyStep, sellStep := ticks(j.getStepAt(SideBuy, highest)), ticks(j.getStepAt(SideSell, highest))
+		spread := buyStep + sellStep
+		if gap := ticks(lowest) - ticks(highest); gap > spread {
+			step := buyStep
+			if sellStep > step {
+				step = sellStep
+			}
+			if step < 1 {
+				step = 1
+			}
+			r.GapLow = highest
+			r.GapHigh = lowest
+			r.GapRungs = int((gap - spread) / step)
+		}
+	}
+
+	return r, nil
+}
+
+// repair places the missing rungs and cancels the duplicates of a report.
+// Foreign orders are left alone.
+func (j *Job) repair(r *GridReport) {
+	for _, o := range r.Duplicates {
+		if err := j.Exchange.CancelOrder(j.Symbol, o.Id); err != nil {
+			log.Error(err)
+			continue
+		}
+		log.Warn(fmt.Sprintf("%s DUPLICATE ORDER CANCELED: %s", strings.ToUpper(j.Provider.Name), o.Id))
+	}
+
+	for _, req := range r.Missing {
+		o, err := j.Exchange.PlaceOrder(req)
+		if err != nil {
+			log.Debug(j.Provider.Name, req.Side, req.Price.ToString(), req.Amount.ToString())
+			log.Error(err)
+			continue
+		}
+		log.Success(fmt.Sprintf("%s ORDER CREATED: %s", strings.ToUpper(j.Provider.Name), o.Id))
+		j.AttachOrder(o)
+	}
+
+	r.Applied = true
+}
+
+// newLadder groups orders by rung. The ladder is aligned to the price offset
+// shared by most orders, all other orders are considered foreign.
+func newLadder(step int64, orders []*Order, ticks func(p *values.Float) int64) *ladder {
+	if step < 1 {
+		step = 1
+	}
+	l := &ladder{
+		step:  step,
+		rungs: make(map[int64][]*Order),
+	}
+
+	count := make(map[int64]int)
+	for _, o := range orders {
+		count[(ticks(o.Price)%step+step)%step]++
+	}
+	best := -1
+	for residue, c := range count {
+		if c > best || (c == best && residue < l.residue) {
+			l.residue, best = residue, c
+		}
+	}
+
+	for _, o := range orders {
+		if k, ok := l.key(ticks(o.Price)); ok {
+			l.rungs[k] = append(l.rungs[k], o)
+		}
+	}
+	return l
+}
+
+// newPercentLadder creates the ladder of a percentage step. Its rungs start
+// at the order which puts most of the orders on the ladder and follow next,
+// downwards from the highest or upwards from the lowest order.
+func newPercentLadder(next func(t int64) int64, down bool, orders []*Order, ticks func(p *values.Float) int64) *ladder {
+	prices := make(map[int64]int)
+	low, high := int64(math.MaxInt64), int64(math.MinInt64)
+	for _, o := range orders {
+		t := ticks(o.Price)
+		prices[t]++
+		if t < low {
+			low = t
+		}
+		if t > high {
+			high = t
+		}
+	}
+
+	walk := func(t int64) []int64 {
+		levels := []int64{t}
+		for {
+			n := next(t)
+			if (down && (n >= t || n < low)) || (!down && (n <= t || n > high)) {
+				break
+			}
+			levels = append(levels, n)
+			t = n
+		}
+		if down {
+			for a, b := 0, len(levels)-1; a < b; a, b = a+1, b-1 {
+				levels[a], levels[b] = levels[b], levels[a]
+			}
+		}
+		return levels
+	}
+
+	l := &ladder{
+		step:  1,
+		rungs: make(map[int64][]*Order),
+	}
+	best := -1
+	for t := range prices {
+		levels := walk(t)
+		matched := 0
+		for _, level := range levels {
+			matched += prices[level]
+		}
+		if matched > best || (matched == best && levels[0] < l.levels[0]) {
+			l.levels, best = levels, matched
+		}
+	}
+
+	for _, o := range orders {
+		if k, ok := l.key(ticks(o.Price)); ok {
+			l.rungs[k] = append(l.rungs[k], o)
+		}
+	}
+	return l
+}
+
+// key returns the rung of a price in ticks and whether it sits on the ladder.
+func (l *ladder) key(t int64) (int64, bool) {
+	if l.levels != nil {
+		i := sort.Search(len(l.levels), func(i int) bool { return l.levels[i] >= t })
+		return int64(i), i < len(l.levels) && l.levels[i] == t
+	}
+	if (t%l.step+l.step)%l.step != l.residue {
+		return 0, false
+	}
+	return (t - l.residue) / l.step, true
+}
+
+func (l *ladder) keys() []int64 {
+	keys := make([]int64, 0)
+	for k := range l.rungs {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(a, b int) bool { return keys[a] < keys[b] })
+	return keys
+}
+
+func (l *ladder) price(k int64, tick *values.Float) *values.Float {
+	if l.levels != nil {
+		return values.NewFloatFromFloat64(float64(l.levels[k])).Mul(tick)
+	}
+	return values.NewFloatFromFloat64(float64(k*l.step + l.residue)).Mul(tick)
+}
+
+// Ok reports whether no issue was found.
+func (r *GridReport) Ok() bool {
+	return len(r.Missing) == 0 && len(r.Duplicates) == 0 && len(r.Foreign) == 0 && r.GapRungs == 0
+}
+
+func (r *GridReport) String() string {
+	mode := "dry-run"
+	if r.Applied {
+		mode = "applied"
+	}
+
+	text := fmt.Sprintf("#### %s %s grid check (%s)\n", strings.ToUpper(r.Provider), strings.ToUpper(r.Symbol), mode)
+	if r.Ok() {
+		return text + "\nNo issues found.\n"
+	}
+
+	text = text + `
+| Issue     | Side | Price | Amount | Order |
+|:----------|:-----|:------|:-------|:------|`
+	for _, o := range r.Missing {
+		text = text + fmt.Sprintf("\n| missing | %s | %.8f | %.8f | |", o.Side, o.Price.ToFloat(), o.Amount.ToFloat())
+	}
+	for _, o := range r.Duplicates {
+		text = text + fmt.Sprintf("\n| duplicate | %s | %.8f | %.8f | %s |", o.Side, o.Price.ToFloat(), o.Volume.ToFloat(), o.Id)
+	}
+	for _, o := range r.Foreign {
+		text = text + fmt.Sprintf("\n| foreign | %s | %.8f | %.8f | %s |", o.Side, o.Price.ToFloat(), o.Volume.ToFloat(), o.Id)
+	}
+	text = text + "\n"
+
+	if r.GapRungs > 0 {
+		text = text + fmt.Sprintf("\n%d rungs are missing between %.8f and %.8f, please place them manually.\n", r.GapRungs, r.GapLow.ToFloat(), r.GapHigh.ToFloat())
+	}
+	return text
+}
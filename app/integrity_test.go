@@ -0,0 +1,53 @@
+package app
+
+import (
+	"../utils/values"
+	"testing"
+)
+
+func TestInspectMissingRungs(t *testing.T) {
+	tests := []struct {
+		name     string
+		compound string
+		added    string
+		buy      string
+		sell     string
+	}{
+		{"fixed volume", "0", "0", "12.50000000", "8.33333333"},
+		// Only buy orders use the compounded volume
+		{"compounded", "50", "10", "13.75000000", "8.33333333"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := newTestJob("0")
+			j.Compound = *values.NewFloatFromString(tt.compound)
+			j.compounding.Added = values.NewFloatFromString(tt.added)
+
+			orders := make([]*Order, 0)
+			for _, o := range []struct {
+				side  string
+				price string
+			}{{SideBuy, "7"}, {SideBuy, "9"}, {SideSell, "11"}, {SideSell, "13"}} {
+				orders = append(orders, &Order{Id: o.side + o.price, Side: o.side, Price: values.NewFloatFromString(o.price), Volume: values.NewFloatFromString("10")})
+			}
+
+			r, err := j.inspect(orders)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(r.Missing) != 2 {
+				t.Fatalf("missing = %d, want 2", len(r.Missing))
+			}
+			for _, m := range r.Missing {
+				price, amount := "8.00000000", tt.buy
+				if m.Side == SideSell {
+					price, amount = "12.00000000", tt.sell
+				}
+				if m.Price.ToString() != price || m.Amount.ToString() != amount {
+					t.Errorf("missing %s = %s @ %s, want %s @ %s", m.Side, m.Amount.ToString(), m.Price.ToString(), amount, price)
+				}
+			}
+		})
+	}
+}
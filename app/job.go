@@ -9,33 +9,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"github.com/mitchellh/mapstructure"
-	"math/big"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
-	"reflect"
 	"strings"
 	"sync"
 	"time"
 )
 
-var (
-	decType             = reflect.TypeOf(values.Float{})
-	orderBookDecodeHook = mapstructure.ComposeDecodeHookFunc(decimalDecodeHook)
-)
-
-func decimalDecodeHook(from reflect.Type, to reflect.Type, v interface{}) (interface{}, error) {
-	if to == decType {
-		if str, ok := v.(string); ok {
-			val, _ := new(big.Float).SetString(str)
-			return values.Float{Float: *val}, nil
-		}
-		return nil, errors.New(fmt.Sprintf("cannot decode %s to decimal", from.String()))
-	}
-	return v, nil
-}
-
 func NewDefaultJob() *Job {
 	dir, _ := os.Getwd()
 
@@ -43,13 +25,15 @@ func NewDefaultJob() *Job {
 		Config:     config.DefaultConfig(),
 		OrderDir:   path.Join(dir, "data", "orders"),
 		Symbol:     "",
-		Id:     	"",
+		Id:         "",
 		Primary:    "",
 		ProviderId: "",
 		Volume:     *values.NewEmptyFloat(),
 		Step:       *values.NewEmptyFloat(),
 		Fee:        *values.NewEmptyFloat(),
-		stepSize:   values.NewFloatFromFloat64(0.00000001),
+		Integrity:  IntegrityReport,
+		Partial:    PartialAggregate,
+		filter:     DefaultFilter(),
 		Alert: &Alert{
 			Buy:     true,
 			Sell:    true,
@@ -58,7 +42,11 @@ func NewDefaultJob() *Job {
 		},
 		lastOperation: time.Now(),
 		mx:            sync.Mutex{},
-		orders:        make(map[int64]*Order),
+		orders:        make(map[string]*Order),
+		filled:        make(map[string]time.Time),
+		journal:       NewJournal(""),
+		compounding:   NewCompounding(),
+		ctx:           context.Background(),
 		balance:       make(map[string]*values.Float),
 		NotifierIds:   make([]string, 0),
 		Notifier:      make([]*notifier.Notifier, 0),
@@ -69,34 +57,181 @@ func NewDefaultJob() *Job {
 }
 
 func NewJobFromFile(filepath string) *Job {
+	j, _ := LoadJobFromFile(filepath)
+
+	return j
+}
+
+// LoadJobFromFile loads a job and reports a job file which couldn't be read.
+// A missing file is an error as well, instead of a default job being written.
+func LoadJobFromFile(filepath string) (*Job, error) {
+	if _, err := os.Stat(filepath); err != nil {
+		return nil, err
+	}
+
 	j := NewDefaultJob()
 
-	j.Load(filepath)
+	ok := j.Load(filepath)
 	j.File = filepath
 	j.Id = filesystem.FileNameWithoutExtension(filepath)
 	j.Init()
 
-	return j
+	if b, err := ioutil.ReadFile(filepath); err == nil {
+		j.source = string(b)
+	}
+	if !ok {
+		return j, errors.New(fmt.Sprintf("job file %s couldn't be loaded", filepath))
+	}
+	return j, nil
 }
 
 func (j *Job) Init() {
-	sec := strings.Replace(j.Symbol, j.Primary, "", 1)
-	sec = strings.Replace(sec, "_", "", 1)
-	sec = strings.Replace(sec, "-", "", 1)
-	sec = strings.Replace(sec, "/", "", 1)
-
-	j.Secondary = sec
+	j.Secondary = secondaryAsset(j.Symbol, j.Primary)
 
 	j.balance[j.Primary] = values.NewEmptyFloat()
 	j.balance[j.Secondary] = values.NewEmptyFloat()
 }
 
-func (j *Job) Start() {
-	if j.Provider.Exchange == "poloniex" {
-		j.StartPoloniex()
+// secondaryAsset returns the asset of a symbol which isn't the primary one.
+// Symbols like "DOGE_BTC", "DOGE-EUR" or "DOGE/BTC" get split at their
+// separator, otherwise the primary asset gets trimmed from either end.
+func secondaryAsset(symbol string, primary string) string {
+	for _, sep := range []string{"_", "-", "/"} {
+		if parts := strings.SplitN(symbol, sep, 2); len(parts) == 2 {
+			if parts[0] == primary {
+				return parts[1]
+			}
+			return parts[0]
+		}
+	}
+
+	if strings.HasSuffix(symbol, primary) {
+		return strings.TrimSuffix(symbol, primary)
+	}
+	return strings.TrimPrefix(symbol, primary)
+}
+
+// Start runs the job in the background until it gets stopped or the given
+// context gets canceled.
+func (j *Job) Start(ctx context.Context) {
+	j.mx.Lock()
+	j.ctx, j.cancel = context.WithCancel(ctx)
+	ctx = j.ctx
+	j.mx.Unlock()
+
+	go j.run(ctx)
+}
+
+func (j *Job) run(ctx context.Context) {
+	if f, err := j.Exchange.GetFilter(j.Symbol); err == nil {
+		j.setFilter(f)
+	} else {
+		log.Error(err)
+	}
+
+	if balances, err := j.Exchange.GetBalances(); err == nil {
+		for asset, b := range balances {
+			j.setBalance(asset, b)
+		}
 	} else {
-		j.StartBinance()
+		log.Error(err)
 	}
+
+	j.loadOpenOrders()
+	j.loadCompounding()
+
+	orders, err := j.Exchange.GetOpenOrders(j.Symbol)
+	if err == nil {
+		for _, o := range orders {
+			j.AttachOrder(o)
+		}
+	} else {
+		log.Error(err)
+	}
+
+	if !j.simulate {
+		j.journal = LoadJournal(j.journalFile())
+		j.queueMx.Lock()
+		j.queue = make(chan *Intent, 256)
+		j.queueClosed = false
+		j.queueMx.Unlock()
+		go j.runQueue()
+	}
+	j.replayJournal(orders)
+
+	if err == nil {
+		j.checkIntegrity(orders)
+	}
+
+	j.mx.Lock()
+	if ctx.Err() == nil {
+		j.subscription = j.hub.Subscribe(j.Symbol, j.handleEvent)
+	}
+	j.mx.Unlock()
+
+	// Orders of the previous run might have been filled in the meantime
+	go j.Recover()
+}
+
+// Stop ignores all further events and waits until all queued counter orders
+// got placed and all pending files got written, or the given context expires.
+// Counter orders which are left inside the journal get replayed on the next
+// start.
+func (j *Job) Stop(ctx context.Context) error {
+	j.mx.Lock()
+	if j.cancel != nil {
+		j.cancel()
+	}
+	if j.subscription != 0 {
+		j.hub.Unsubscribe(j.Symbol, j.subscription)
+		j.subscription = 0
+	}
+	j.mx.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		j.work.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = errors.New("pending work timed out")
+	}
+
+	left := 0
+	for _, in := range j.journal.Pending() {
+		if !in.Parked {
+			left++
+		}
+	}
+	if left > 0 {
+		return errors.New(fmt.Sprintf("%d counter orders left in the journal", left))
+	}
+	return err
+}
+
+// begin registers work Stop has to wait for, unless the job got stopped
+// already. Stop cancels under the same lock, so it never misses any work.
+func (j *Job) begin() bool {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+	if j.ctx.Err() != nil {
+		return false
+	}
+	j.work.Add(1)
+	return true
+}
+
+// background runs f in its own goroutine and lets Stop wait for it.
+func (j *Job) background(f func()) {
+	j.work.Add(1)
+	go func() {
+		defer j.work.Done()
+		f()
+	}()
 }
 
 func (j *Job) Tick(t time.Time) {
@@ -108,6 +243,8 @@ func (j *Job) Tick(t time.Time) {
 	}
 
 	if t.Minute() == 0 {
+		j.retryParked()
+
 		hour := t.Hour()
 		for _, i := range j.Alert.Summary {
 			if hour == i {
@@ -117,84 +254,185 @@ func (j *Job) Tick(t time.Time) {
 	}
 }
 
-func (j *Job) Validate() {
-	if j.Provider.Exchange == "poloniex" {
+// getStep returns the absolute step size of a side. It's zero if the side
+// uses a percentage step.
+func (j *Job) getStep(d string) *values.Float {
+	if d == SideSell {
+		if j.SellStep.Gt(values.ZeroFloat) {
+			return &j.SellStep
+		}
+		if j.SellStepPercent.Gt(values.ZeroFloat) {
+			return values.NewEmptyFloat()
+		}
+	} else {
+		if j.BuyStep.Gt(values.ZeroFloat) {
+			return &j.BuyStep
+		}
+		if j.BuyStepPercent.Gt(values.ZeroFloat) {
+			return values.NewEmptyFloat()
+		}
+	}
+	return &j.Step
+}
 
+// getStepPercent returns the percentage step of a side. The step of a side
+// takes precedence over the default step, an absolute step over a percentage.
+func (j *Job) getStepPercent(d string) *values.Float {
+	if d == SideSell {
+		if j.SellStep.Gt(values.ZeroFloat) {
+			return values.NewEmptyFloat()
+		}
+		if j.SellStepPercent.Gt(values.ZeroFloat) {
+			return &j.SellStepPercent
+		}
 	} else {
-		for _, o := range j.orders {
-			if o.Side == "sell" {
-				return
-			}
+		if j.BuyStep.Gt(values.ZeroFloat) {
+			return values.NewEmptyFloat()
+		}
+		if j.BuyStepPercent.Gt(values.ZeroFloat) {
+			return &j.BuyStepPercent
 		}
-		go j.cancelAllBinOrders()
 	}
+	if j.Step.Gt(values.ZeroFloat) {
+		return values.NewEmptyFloat()
+	}
+	return &j.StepPercent
 }
 
-func (j *Job) StartPoloniex() {
-	if orders, err := j.PoloniexClient.GetOpenOrders(j.Symbol); err == nil {
-		j.parsePolOpenOrders(orders)
+// getStepAt returns the step size of a side at the given price, without
+// rounding it to the tick size.
+func (j *Job) getStepAt(d string, price *values.Float) *values.Float {
+	if pct := j.getStepPercent(d); pct.Gt(values.ZeroFloat) {
+		return price.Div(values.HundredFloat).Mul(pct)
 	}
+	return j.getStep(d)
+}
 
-	j.WatchPolMarket()
+// sellPrice returns the price one sell step above the given price. A
+// percentage step gets rounded up to the tick size, which keeps the profit
+// of a rung at least at the configured percentage.
+func (j *Job) sellPrice(price *values.Float) *values.Float {
+	pct := j.getStepPercent(SideSell)
+	if !pct.Gt(values.ZeroFloat) {
+		return price.Add(j.getStep(SideSell))
+	}
+
+	return j.stepUp(price, pct)
 }
 
-func (j *Job) StartBinance() {
-	j.setBinanceStepSize()
-	j.setBinanceBalance()
-	if orders, err := j.BinanceClient.NewListOpenOrdersService().Symbol(j.Symbol).Do(context.Background()); err == nil {
-		j.parseBinOpenOrders(orders)
+// buyPrice returns the price one buy step below the given price. A
+// percentage step divides the price, a sell step followed by a buy step of
+// the same percentage ends on the initial price. It's rounded down to the
+// tick size.
+func (j *Job) buyPrice(price *values.Float) *values.Float {
+	pct := j.getStepPercent(SideBuy)
+	if !pct.Gt(values.ZeroFloat) {
+		return price.Sub(j.getStep(SideBuy))
 	}
 
-	j.WatchBinMarket()
+	return j.stepDown(price, pct)
 }
 
-func (j *Job) getStep(d string) *values.Float {
-	if d == "sell" {
-		if j.SellStep.Gt(values.ZeroFloat) {
-			return &j.SellStep
-		}
-	} else {
-		if j.BuyStep.Gt(values.ZeroFloat) {
-			return &j.BuyStep
-		}
+// stepUp returns the price the given percentage above a price, rounded up to
+// the next tick.
+func (j *Job) stepUp(price *values.Float, pct *values.Float) *values.Float {
+	tick := j.getFilter().TickSize
+	p := price.Mul(values.HundredFloat.Add(pct)).Div(values.HundredFloat)
+	if !tick.Gt(values.ZeroFloat) {
+		return p
 	}
-	return &j.Step
+	rounded := p.Truncate(tick)
+	if rounded.Lt(p) {
+		rounded = rounded.Add(tick)
+	}
+	if !rounded.Gt(price) {
+		rounded = price.Add(tick)
+	}
+	return rounded
 }
 
-func (j *Job) setProvider(p *Provider) {
+// stepDown returns the price the given percentage step below a price, rounded
+// down to the next tick.
+func (j *Job) stepDown(price *values.Float, pct *values.Float) *values.Float {
+	tick := j.getFilter().TickSize
+	p := price.Mul(values.HundredFloat).Div(values.HundredFloat.Add(pct))
+	if !tick.Gt(values.ZeroFloat) {
+		return p
+	}
+	rounded := p.Truncate(tick)
+	if !rounded.Lt(price) {
+		rounded = price.Sub(tick)
+	}
+	return rounded
+}
+
+func (j *Job) setProvider(p *Provider) error {
+	hub, err := NewEventHub(p)
+	if err != nil {
+		return err
+	}
+
 	j.mx.Lock()
 	j.Provider = p
+	j.Exchange = hub.Exchange
+	j.hub = hub
+	j.mx.Unlock()
 
-	if j.Provider.Exchange == "poloniex" {
-		j.PoloniexClient = j.Provider.NewPoloniexClient()
-	} else {
-		j.BinanceClient = j.Provider.NewBinanceClient()
-	}
+	return nil
+}
 
+func (j *Job) getFilter() *Filter {
+	j.mx.Lock()
+	f := j.filter
+	j.mx.Unlock()
+
+	return f
+}
+
+func (j *Job) setFilter(f *Filter) {
+	j.mx.Lock()
+	j.filter = f
 	j.mx.Unlock()
 }
 
 func (j *Job) AttachOrder(o *Order) {
+	if o.Total == nil || o.Total.Eq(values.ZeroFloat) {
+		o.Total = o.Volume.Mul(o.Price)
+	}
+	if o.Fee == nil || o.Fee.Eq(values.ZeroFloat) {
+		o.Fee = o.Total.Div(values.HundredFloat).Mul(&j.Fee)
+	}
+
 	j.mx.Lock()
-	if _, ok := j.orders[o.Id]; !ok {
+	_, ok := j.orders[o.Id]
+	if !ok {
 		j.orders[o.Id] = o
-		log.Success(fmt.Sprintf("%s ORDER REGISTERED: %d", strings.ToUpper(j.Provider.Name), o.Id))
+		log.Success(fmt.Sprintf("%s ORDER REGISTERED: %s", strings.ToUpper(j.Provider.Name), o.Id))
 	}
 	j.mx.Unlock()
+
+	if !ok {
+		j.saveOpenOrders()
+	}
 }
 
-func (j *Job) DetachOrder(num int64) {
+func (j *Job) DetachOrder(id string) {
 	j.mx.Lock()
-	if _, ok := j.orders[num]; ok {
-		delete(j.orders, num)
+	_, ok := j.orders[id]
+	if ok {
+		delete(j.orders, id)
 	}
 	j.mx.Unlock()
-	log.Warn(fmt.Sprintf("%s ORDER REMOVED: %d", strings.ToUpper(j.Provider.Name), num))
+	log.Warn(fmt.Sprintf("%s ORDER REMOVED: %s", strings.ToUpper(j.Provider.Name), id))
+
+	if ok {
+		j.saveOpenOrders()
+	}
 }
 
-func (j *Job) GetOrder(on int64) (*Order, error) {
+func (j *Job) GetOrder(id string) (*Order, error) {
 	j.mx.Lock()
-	o, ok := j.orders[on]
+	o, ok := j.orders[id]
 	j.mx.Unlock()
 	if !ok {
 		return nil, errors.New("order not found")
@@ -269,46 +507,62 @@ func (j *Job) SendSummary() {
 
 	vol := values.NewEmptyFloat()
 	prof := values.NewEmptyFloat()
+	// Profit valued in the quote asset
+	value := values.NewEmptyFloat()
 
 	numBuyOrders := 0
 	numSellOrders := 0
 
 	for _, o := range orders {
 
-		if o.Side == "sell" && o.Status == "filled" {
-
-			if now.Sub(o.Date).Hours() <= 24 {
-
-				sellAmount := o.Volume
-				sellRate := o.Price
-				sellTotal := sellAmount.Mul(sellRate)
+		filled := o.Status == StatusFilled || o.Status == StatusPartial
 
-				buyAmount := sellAmount
-				buyRate := sellRate.Sub(j.getStep("sell"))
-				buyTotal := buyAmount.Mul(buyRate)
+		if o.Side == SideSell && filled {
 
-				sellFee := sellTotal.Div(values.HundredFloat).Mul(&j.Fee)
-				buyFee := buyTotal.Div(values.HundredFloat).Mul(&j.Fee)
+			if now.Sub(o.Date).Hours() <= 24 {
 
-				vol = vol.Add(buyAmount)
-				prof = prof.Add(sellTotal.Sub(buyTotal).Sub(sellFee).Sub(buyFee))
+				profit := j.sellProfit(o)
+				vol = vol.Add(o.Volume)
+				prof = prof.Add(profit)
+				if j.ProfitAsset == ProfitBase {
+					profit = profit.Mul(o.Price)
+				}
+				value = value.Add(profit)
 
 				numSellOrders++
 			}
-		} else if o.Side == "buy" && o.Status == "filled" {
+		} else if o.Side == SideBuy && filled {
 			if now.Sub(o.Date).Hours() <= 24 {
 				numBuyOrders++
 			}
 		}
 	}
 
-	totalProfit := prof.Div(&j.Volume).Mul(values.HundredFloat)
+	totalProfit := value.Div(&j.Volume).Mul(values.HundredFloat)
+
+	asset := j.Primary
+	if j.ProfitAsset == ProfitBase {
+		asset = j.Secondary
+	}
 
 	text := fmt.Sprintf("#### %s %s Summary\n", strings.ToUpper(j.Provider.Name), strings.ToUpper(j.Symbol))
 	text = text + `
 | Volume | Profit | Sell Orders | Buy Orders | P%   |
 |:-------|:-------|:------------|:-----------|:-----|`
-	text = text + fmt.Sprintf("\n| %.8f | %.8f | %d | %d | %.4f%% |", vol, prof, numSellOrders, numBuyOrders, totalProfit)
+	text = text + fmt.Sprintf("\n| %.8f | %.8f %s | %d | %d | %.4f%% |", vol, prof, asset, numSellOrders, numBuyOrders, totalProfit)
+
+	if j.Compound.Gt(values.ZeroFloat) {
+		volume := j.buyVolume()
+		text = text + fmt.Sprintf("\n\nOrder volume: %.8f %s (%.8f %s compounded)", volume.ToFloat(), j.Primary, volume.Sub(&j.Volume).ToFloat(), j.Primary)
+	}
+
+	if parked := j.parkedOrders(); len(parked) > 0 {
+		text = text + fmt.Sprintf("\n\n%d parked orders:\n", len(parked))
+		for _, in := range parked {
+			r := in.Request
+			text = text + fmt.Sprintf("\n- %s %.8f @ %.8f: %s", strings.ToUpper(r.Side), r.Amount.ToFloat(), r.Price.ToFloat(), in.Error)
+		}
+	}
 
 	j.Notify(text)
 
@@ -328,8 +582,12 @@ func (j *Job) loadOrders(dir string) []*Order {
 }
 
 func (j *Job) SaveOrder(o *Order) {
+	if j.simulate {
+		return
+	}
+
 	d := j.CurrentOrderDir()
-	filename := fmt.Sprintf("%s/%d.json", d, o.Id)
+	filename := path.Join(d, o.fileName())
 
 	c := config.NewConfig()
 	c.RootDir = d
@@ -362,9 +620,22 @@ func (j *Job) touch() {
 	j.mx.Unlock()
 }
 
+func (j *Job) setNotifiers(ns []*notifier.Notifier) {
+	j.mx.Lock()
+	j.Notifier = ns
+	j.mx.Unlock()
+}
+
 func (j *Job) Notify(msg string) {
-	for _, n := range j.Notifier {
-		go n.Send(msg)
+	j.mx.Lock()
+	ns := j.Notifier
+	j.mx.Unlock()
+
+	for _, n := range ns {
+		n := n
+		j.background(func() {
+			n.Send(msg)
+		})
 	}
 }
 
@@ -375,7 +646,7 @@ func (j *Job) NotifyOrder(pf float64, amt float64, total float64, dif float64, d
 |:------|:------|:-------|:-------|`
 	text = text + fmt.Sprintf("\n| %.8f | %.8f | %.8f | %.8f |", dif, pf, amt, total)
 
-	if (j.Alert.Sell && direction == "sell") || (j.Alert.Buy && direction == "buy") {
+	if (j.Alert.Sell && direction == SideSell) || (j.Alert.Buy && direction == SideBuy) {
 		j.Notify(text)
 	}
 
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"../utils/values"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStepPrices(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps map[string]string
+		tick  string
+		price string
+		sell  string
+		buy   string
+	}{
+		{"absolute", map[string]string{"step": "1"}, "0.00000001", "10", "11.00000000", "9.00000000"},
+		{"percent", map[string]string{"step-percent": "1"}, "0.00000001", "10", "10.10000000", "9.90099009"},
+		// Sell prices get rounded up and buy prices down to the tick size
+		{"percent rounded", map[string]string{"step-percent": "1"}, "0.01", "1.23", "1.25000000", "1.21000000"},
+		// A step below the tick size still moves the price by a tick
+		{"percent below tick", map[string]string{"step-percent": "1"}, "0.01", "0.05", "0.06000000", "0.04000000"},
+		{"absolute over percent", map[string]string{"step": "1", "step-percent": "5"}, "0.00000001", "10", "11.00000000", "9.00000000"},
+		{"sides", map[string]string{"buy-step": "2", "sell-step-percent": "10"}, "0.00000001", "10", "11.00000000", "8.00000000"},
+		{"side over default", map[string]string{"sell-step": "0.5", "step-percent": "1"}, "0.00000001", "10", "10.50000000", "9.90099009"},
+		{"side absolute over side percent", map[string]string{"buy-step": "2", "buy-step-percent": "1", "step-percent": "5"}, "0.00000001", "10", "10.50000000", "8.00000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := NewDefaultJob()
+			fields := map[string]*values.Float{
+				"step":              &j.Step,
+				"buy-step":          &j.BuyStep,
+				"sell-step":         &j.SellStep,
+				"step-percent":      &j.StepPercent,
+				"buy-step-percent":  &j.BuyStepPercent,
+				"sell-step-percent": &j.SellStepPercent,
+			}
+			for k, v := range tt.steps {
+				*fields[k] = *values.NewFloatFromString(v)
+			}
+			f := DefaultFilter()
+			f.TickSize = values.NewFloatFromString(tt.tick)
+			j.setFilter(f)
+
+			price := values.NewFloatFromString(tt.price)
+			if sell := j.sellPrice(price).ToString(); sell != tt.sell {
+				t.Errorf("sell price = %s, want %s", sell, tt.sell)
+			}
+			if buy := j.buyPrice(price).ToString(); buy != tt.buy {
+				t.Errorf("buy price = %s, want %s", buy, tt.buy)
+			}
+		})
+	}
+}
+
+func TestPercentRoundTrip(t *testing.T) {
+	// A sell step followed by a buy step of the same percentage never ends
+	// above the initial price
+	for _, pct := range []string{"0.5", "1", "2.5", "10"} {
+		j := NewDefaultJob()
+		j.StepPercent = *values.NewFloatFromString(pct)
+		f := DefaultFilter()
+		f.TickSize = values.NewFloatFromString("0.01")
+		j.setFilter(f)
+
+		for _, p := range []string{"0.05", "1.23", "99.99", "12345.67"} {
+			price := values.NewFloatFromString(p)
+			if back := j.buyPrice(j.sellPrice(price)); back.Gt(price) {
+				t.Errorf("%s%% from %s: back at %s", pct, p, back.ToString())
+			}
+		}
+	}
+}
+
+func TestStopEvents(t *testing.T) {
+	j := newTestJob("0")
+	j.ctx, j.cancel = context.WithCancel(context.Background())
+
+	// Events arriving while the job gets stopped either finish before Stop
+	// returns or get ignored
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 100; n++ {
+				j.handleEvent(&Event{Type: EventBalance, Symbol: j.Symbol})
+			}
+		}()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := j.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if j.begin() {
+		t.Error("work got registered after the job got stopped")
+	}
+	wg.Wait()
+}
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"../utils/filesystem"
+	"../utils/log"
+	"../utils/values"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Intent is a counter order which is about to be placed for a filled order.
+type Intent struct {
+	Fill    *Order        `json:"fill"`
+	Request *OrderRequest `json:"request"`
+	Gain    *values.Float `json:"gain"`
+	Date    time.Time     `json:"date"`
+
+	// Parked intents failed with an error which won't resolve by itself. The
+	// reason and the error of the last failure are kept while retrying.
+	Attempts int    `json:"attempts"`
+	Parked   bool   `json:"parked"`
+	Reason   string `json:"reason,omitempty"`
+	Error    string `json:"error"`
+}
+
+// Journal is a write-ahead log of intents. An intent gets written before its
+// counter order is placed and removed as soon as the order got placed. All
+// remaining intents get replayed on startup.
+type Journal struct {
+	Intents map[string]*Intent `json:"intents"`
+
+	file string
+	mx   sync.Mutex
+}
+
+// NewJournal creates an empty journal. It will only be kept in memory if no
+// file is given.
+func NewJournal(file string) *Journal {
+	return &Journal{
+		Intents: make(map[string]*Intent),
+		file:    file,
+		mx:      sync.Mutex{},
+	}
+}
+
+// LoadJournal reads the journal of a previous run from the given file.
+func LoadJournal(file string) *Journal {
+	jl := NewJournal(file)
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return jl
+	}
+	if err := json.Unmarshal(b, jl); err != nil {
+		log.Error(err)
+	}
+	if jl.Intents == nil {
+		jl.Intents = make(map[string]*Intent)
+	}
+	return jl
+}
+
+// Add writes an intent, keyed by the id of its filled order. The journal
+// keeps a copy, the placement queue keeps changing the given intent.
+func (jl *Journal) Add(in *Intent) {
+	c := *in
+
+	jl.mx.Lock()
+	jl.Intents[in.Fill.Id] = &c
+	jl.save()
+	jl.mx.Unlock()
+}
+
+// Remove marks the intent of the given filled order as completed.
+func (jl *Journal) Remove(id string) {
+	jl.mx.Lock()
+	if _, ok := jl.Intents[id]; ok {
+		delete(jl.Intents, id)
+		jl.save()
+	}
+	jl.mx.Unlock()
+}
+
+// Pending returns copies of all intents which weren't completed, oldest
+// first.
+func (jl *Journal) Pending() []*Intent {
+	jl.mx.Lock()
+	intents := make([]*Intent, 0)
+	for _, in := range jl.Intents {
+		c := *in
+		intents = append(intents, &c)
+	}
+	jl.mx.Unlock()
+
+	sort.Slice(intents, func(a, b int) bool {
+		return intents[a].Date.Before(intents[b].Date)
+	})
+	return intents
+}
+
+// save replaces the journal file atomically, a crash can't leave a partially
+// written journal behind.
+func (jl *Journal) save() {
+	if jl.file == "" {
+		return
+	}
+
+	b, err := json.MarshalIndent(jl, "", "\t")
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	if _, err := filesystem.MakeDir(jl.file); err != nil {
+		log.Error(err)
+		return
+	}
+
+	tmp := jl.file + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	_, err = f.Write(b)
+	if err == nil {
+		err = f.Sync()
+	}
+	_ = f.Close()
+	if err == nil {
+		err = os.Rename(tmp, jl.file)
+	}
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+func (j *Job) journalFile() string {
+	return path.Join(j.OrderDir, "journal", j.Id+".json")
+}
+
+// counterOrderId derives the client order id of a counter order from the id
+// of its filled order. Placing the same counter order twice results in the
+// same client id, which gets rejected by the exchange. 32 hex characters are
+// accepted by every supported exchange.
+func counterOrderId(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:16])
+}
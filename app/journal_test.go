@@ -0,0 +1,170 @@
+package app
+
+import (
+	"../utils/values"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+// newTestIntent returns the intent of a filled buy order of 30 DOGE at 10,
+// countered by a sell order at 11.
+func newTestIntent(id string, date time.Time) *Intent {
+	return &Intent{
+		Fill: &Order{
+			Id:     id,
+			Symbol: "DOGEBTC",
+			Volume: values.NewFloatFromString("30"),
+			Price:  values.NewFloatFromString("10"),
+			Side:   SideBuy,
+			Status: StatusFilled,
+		},
+		Request: &OrderRequest{
+			Symbol:   "DOGEBTC",
+			Side:     SideSell,
+			Price:    values.NewFloatFromString("11"),
+			Amount:   values.NewFloatFromString("30"),
+			ClientId: counterOrderId(id),
+		},
+		Gain: values.NewFloatFromString("30"),
+		Date: date,
+	}
+}
+
+func TestJournalFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := path.Join(dir, "journal", "test.json")
+
+	jl := NewJournal(file)
+	jl.Add(newTestIntent("2", time.Unix(120, 0)))
+	jl.Add(newTestIntent("1", time.Unix(60, 0)))
+	jl.Add(newTestIntent("3", time.Unix(180, 0)))
+	jl.Remove("3")
+
+	pending := LoadJournal(file).Pending()
+	if len(pending) != 2 {
+		t.Fatalf("pending = %d, want 2", len(pending))
+	}
+	for i, id := range []string{"1", "2"} {
+		in := pending[i]
+		if in.Fill.Id != id || in.Request.ClientId != counterOrderId(id) || in.Request.Price.ToString() != "11.00000000" {
+			t.Errorf("intent %d = %s %s @ %s", i, in.Fill.Id, in.Request.ClientId, in.Request.Price.ToString())
+		}
+	}
+	if _, err := os.Stat(file + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+}
+
+func TestJournalKeepsCopies(t *testing.T) {
+	jl := NewJournal("")
+	in := newTestIntent("1", time.Now())
+	jl.Add(in)
+
+	// The placement queue keeps counting the attempts of its own intent
+	in.Attempts = 3
+	pending := jl.Pending()
+	if pending[0].Attempts != 0 {
+		t.Errorf("attempts = %d, want 0", pending[0].Attempts)
+	}
+
+	pending[0].Parked = true
+	if jl.Pending()[0].Parked {
+		t.Error("pending intent changed the journal")
+	}
+}
+
+// clientExchange is a paper account which looks up client ids of orders that
+// got closed already.
+type clientExchange struct {
+	*PaperExchange
+	closed map[string]*Order
+	err    error
+}
+
+func (e *clientExchange) GetOrderByClientId(symbol string, clientId string) (*Order, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	if orders, _ := e.GetOpenOrders(symbol); len(orders) > 0 && orders[0].ClientId == clientId {
+		return orders[0], nil
+	}
+	return e.closed[clientId], nil
+}
+
+func TestReplayJournal(t *testing.T) {
+	tests := []struct {
+		name    string
+		open    bool
+		getter  bool
+		closed  bool
+		err     error
+		orders  int
+		pending int
+	}{
+		// The counter order got placed before the bot got killed
+		{"already placed", true, false, false, nil, 1, 0},
+		{"not placed", false, false, false, nil, 1, 0},
+		{"client id unknown", false, true, false, nil, 1, 0},
+		{"placed and filled", false, true, true, nil, 0, 0},
+		{"lookup failed", false, true, false, errors.New("503 Service Unavailable"), 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := newTestJob("0")
+			j.simulate = true
+			paper := NewPaperAccount(&Provider{
+				Name:     "test",
+				Exchange: "paper",
+				Balances: map[string]*values.Float{"DOGE": values.NewFloatFromString("100")},
+			})
+			j.Exchange = paper
+
+			in := newTestIntent("1", time.Now())
+			j.AttachOrder(in.Fill)
+			j.journal.Add(in)
+
+			if tt.getter {
+				ex := &clientExchange{PaperExchange: paper, closed: make(map[string]*Order), err: tt.err}
+				if tt.closed {
+					ex.closed[in.Request.ClientId] = &Order{Id: "2", ClientId: in.Request.ClientId, Symbol: "DOGEBTC", Volume: in.Request.Amount, Price: in.Request.Price, Side: SideSell, Status: StatusFilled}
+				}
+				j.Exchange = ex
+			}
+			if tt.open {
+				if _, err := paper.PlaceOrder(in.Request); err != nil {
+					t.Fatal(err)
+				}
+			}
+			open, _ := paper.GetOpenOrders(j.Symbol)
+			j.replayJournal(open)
+
+			if n := len(j.journal.Pending()); n != tt.pending {
+				t.Errorf("pending = %d, want %d", n, tt.pending)
+			}
+			if _, err := j.GetOrder("1"); (err == nil) != (tt.pending > 0) {
+				t.Errorf("filled order attached = %v", err == nil)
+			}
+			if _, err := j.GetOrder("2"); (err == nil) != tt.closed {
+				t.Errorf("closed counter order attached = %v, want %v", err == nil, tt.closed)
+			}
+			orders, _ := paper.GetOpenOrders(j.Symbol)
+			if len(orders) != tt.orders {
+				t.Fatalf("open orders = %d, want %d", len(orders), tt.orders)
+			}
+			for _, o := range orders {
+				if o.ClientId != counterOrderId("1") || o.Side != SideSell || o.Price.ToString() != "11.00000000" {
+					t.Errorf("counter order = %s %s @ %s", o.ClientId, o.Side, o.Price.ToString())
+				}
+			}
+		})
+	}
+}
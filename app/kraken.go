@@ -0,0 +1,353 @@
+package app
+
+import (
+	"../api/kraken"
+	"../utils/log"
+	"../utils/values"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	RegisterExchange("kraken", NewKrakenExchange)
+}
+
+// KrakenExchange doesn't keep a Clock, Kraken signs requests with a nonce
+// which only has to increase and isn't compared to the server time.
+type KrakenExchange struct {
+	provider *Provider
+	client   *kraken.Config
+
+	// Order status updates only contain the changed attributes. All known
+	// orders are kept in order to emit complete events.
+	orders   map[string]*Order
+	executed map[string]*values.Float
+
+	// Symbols as used by the jobs, by pair name
+	symbols map[string]string
+	mx      sync.Mutex
+}
+
+func NewKrakenExchange(p *Provider) (Exchange, error) {
+	client := p.NewKrakenClient()
+	if client == nil {
+		return nil, errors.New(fmt.Sprintf("%s client could not be created", strings.ToUpper(p.Name)))
+	}
+
+	return &KrakenExchange{
+		provider: p,
+		client:   client,
+		orders:   make(map[string]*Order),
+		executed: make(map[string]*values.Float),
+		symbols:  make(map[string]string),
+		mx:       sync.Mutex{},
+	}, nil
+}
+
+// pair looks up the asset pair of a symbol and remembers the symbol spelling
+// in order to emit events for the same symbol.
+func (e *KrakenExchange) pair(symbol string) *kraken.AssetPair {
+	pair := e.client.GetPair(symbol)
+	if pair != nil {
+		e.mx.Lock()
+		e.symbols[pair.Name] = symbol
+		e.mx.Unlock()
+	}
+	return pair
+}
+
+func (e *KrakenExchange) GetFilter(symbol string) (*Filter, error) {
+	pair := e.pair(symbol)
+	if pair == nil {
+		return nil, errors.New(fmt.Sprintf("unknown symbol: %s", symbol))
+	}
+
+	filter := DefaultFilter()
+	filter.StepSize = values.NewFloatFromFloat64(math.Pow10(-pair.LotDecimals))
+	filter.TickSize = values.NewFloatFromFloat64(math.Pow10(-pair.PairDecimals))
+	if pair.TickSize.Gt(values.ZeroFloat) {
+		filter.TickSize = values.NewFloat(&pair.TickSize.Float)
+	}
+	filter.MinNotional = values.NewFloat(&pair.CostMin.Float)
+
+	return filter, nil
+}
+
+func (e *KrakenExchange) GetBalances() (map[string]*values.Float, error) {
+	return e.client.GetBalances()
+}
+
+func (e *KrakenExchange) GetOpenOrders(symbol string) ([]*Order, error) {
+	pair := e.pair(symbol)
+	if pair == nil {
+		return nil, errors.New(fmt.Sprintf("unknown symbol: %s", symbol))
+	}
+
+	orders, err := e.client.GetOpenOrders()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Order, 0)
+	for _, o := range orders {
+		if e.client.GetPair(o.Descr.Pair) == pair {
+			result = append(result, e.remember(symbol, o))
+		}
+	}
+	return result, nil
+}
+
+func (e *KrakenExchange) PlaceOrder(r *OrderRequest) (*Order, error) {
+	pair := e.pair(r.Symbol)
+	if pair == nil {
+		return nil, errors.New(fmt.Sprintf("unknown symbol: %s", r.Symbol))
+	}
+
+	id, err := e.client.AddOrder(pair, r.Side, r.Price, r.Amount, r.ClientId)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.remember(r.Symbol, &kraken.Order{
+		Id:      id,
+		ClOrdId: r.ClientId,
+		Status:  "pending",
+		Descr: kraken.OrderDescription{
+			Pair:      pair.AltName,
+			Type:      r.Side,
+			OrderType: "limit",
+			Price:     *r.Price,
+		},
+		Volume: *r.Amount,
+	}), nil
+}
+
+func (e *KrakenExchange) CancelOrder(symbol string, id string) error {
+	if err := e.client.CancelOrder(id); err != nil {
+		return err
+	}
+	e.forget(id)
+	return nil
+}
+
+// GetOrder returns the order with the given id, including closed orders.
+func (e *KrakenExchange) GetOrder(symbol string, id string) (*Order, error) {
+	o, err := e.client.QueryOrder(id)
+	if err != nil {
+		return nil, err
+	}
+	return e.closedOrder(symbol, o), nil
+}
+
+// GetOrderByClientId returns the order with the given client id, including
+// closed orders.
+func (e *KrakenExchange) GetOrderByClientId(symbol string, clientId string) (*Order, error) {
+	o, err := e.client.GetOrderByClientId(clientId)
+	if err != nil || o == nil {
+		return nil, err
+	}
+	return e.closedOrder(symbol, o), nil
+}
+
+func (e *KrakenExchange) GetTrades(symbol string, since time.Time) ([]*Trade, error) {
+	pair := e.pair(symbol)
+	if pair == nil {
+		return nil, errors.New(fmt.Sprintf("unknown symbol: %s", symbol))
+	}
+
+	trades, err := e.client.GetTrades(since)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Trade, 0)
+	for _, t := range trades {
+		if e.client.GetPair(t.Pair) != pair {
+			continue
+		}
+		result = append(result, &Trade{
+			Id:      t.Id,
+			OrderId: t.OrderTxId,
+			Symbol:  symbol,
+			Side:    t.Type,
+			Price:   values.NewFloat(&t.Price.Float),
+			Volume:  values.NewFloat(&t.Volume.Float),
+			Date:    time.Unix(0, int64(t.Time.ToFloat()*float64(time.Second))),
+		})
+	}
+	return result, nil
+}
+
+// closedOrder returns an order including its final status. Kraken closes an
+// order once it got filled.
+func (e *KrakenExchange) closedOrder(symbol string, o *kraken.Order) *Order {
+	order := &Order{
+		Id:       o.Id,
+		ClientId: o.ClOrdId,
+		Symbol:   symbol,
+		Volume:   values.NewFloat(&o.Volume.Float),
+		Price:    values.NewFloat(&o.Descr.Price.Float),
+		Total:    o.Volume.Mul(&o.Descr.Price),
+		Fee:      values.NewEmptyFloat(),
+		Side:     o.Descr.Type,
+		Status:   StatusNew,
+		Date:     time.Unix(0, int64(o.OpenTime.ToFloat()*float64(time.Second))),
+	}
+	order.setExecuted(values.NewFloat(&o.VolumeExec.Float))
+
+	switch o.Status {
+	case "open":
+		if o.VolumeExec.Sign() > 0 {
+			order.Status = StatusPartial
+		}
+	case "closed":
+		order.Status = StatusFilled
+	case "canceled", "expired":
+		order.Status = StatusCanceled
+	}
+	return order
+}
+
+func (e *KrakenExchange) remember(symbol string, o *kraken.Order) *Order {
+	order := &Order{
+		Id:       o.Id,
+		ClientId: o.ClOrdId,
+		Symbol:   symbol,
+		Volume:   values.NewFloat(&o.Volume.Float),
+		Price:    values.NewFloat(&o.Descr.Price.Float),
+		Total:    o.Volume.Mul(&o.Descr.Price),
+		Fee:      values.NewEmptyFloat(),
+		Side:     o.Descr.Type,
+		Status:   StatusNew,
+		Date:     time.Now(),
+	}
+
+	e.mx.Lock()
+	e.orders[o.Id] = order
+	e.mx.Unlock()
+
+	return order
+}
+
+func (e *KrakenExchange) forget(id string) *Order {
+	e.mx.Lock()
+	o, ok := e.orders[id]
+	if v, executed := e.executed[id]; ok && executed {
+		o.setExecuted(v)
+	}
+	delete(e.orders, id)
+	delete(e.executed, id)
+	e.mx.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return o
+}
+
+func (e *KrakenExchange) handleOrder(o *kraken.Order, handler EventHandler) {
+	if o.Descr.Pair != "" {
+		// A full order description is only sent for new orders
+		pair := e.client.GetPair(o.Descr.Pair)
+		if pair == nil {
+			return
+		}
+		e.mx.Lock()
+		symbol, watched := e.symbols[pair.Name]
+		_, known := e.orders[o.Id]
+		e.mx.Unlock()
+		if !watched {
+			return
+		}
+		order := e.remember(symbol, o)
+		if !known || o.Status == "pending" {
+			handler(&Event{Type: EventNew, Symbol: symbol, Order: order})
+		}
+	}
+
+	switch o.Status {
+	case "closed":
+		if order := e.forget(o.Id); order != nil {
+			order.Status = StatusFilled
+			order.Date = time.Now()
+			handler(&Event{Type: EventFilled, Symbol: order.Symbol, Order: order})
+		}
+	case "canceled", "expired":
+		if order := e.forget(o.Id); order != nil {
+			order.Status = StatusCanceled
+			handler(&Event{Type: EventCanceled, Symbol: order.Symbol, Order: order})
+		}
+	}
+}
+
+func (e *KrakenExchange) handleTrade(t *kraken.Trade, handler EventHandler) {
+	e.mx.Lock()
+	order, ok := e.orders[t.OrderTxId]
+	if !ok {
+		e.mx.Unlock()
+		return
+	}
+	executed := values.NewFloat(&t.Volume.Float)
+	if v, ok := e.executed[t.OrderTxId]; ok {
+		executed = v.Add(executed)
+	}
+	e.executed[t.OrderTxId] = executed
+	e.mx.Unlock()
+
+	log.Info(fmt.Sprintf("%s ORDER UPDATE: %s %s @ %s - %.8f", strings.ToUpper(e.provider.Name), order.Symbol, t.OrderTxId, t.Type, t.Volume.ToFloat()))
+
+	// The trade might arrive before the closed status of its order
+	if !executed.Lt(order.Volume) {
+		if order := e.forget(t.OrderTxId); order != nil {
+			order.Status = StatusFilled
+			order.Date = time.Now()
+			handler(&Event{Type: EventFilled, Symbol: order.Symbol, Order: order})
+		}
+	} else {
+		partial := *order
+		partial.Status = StatusPartial
+		partial.setExecuted(executed)
+		handler(&Event{Type: EventPartial, Symbol: partial.Symbol, Order: &partial})
+	}
+}
+
+func (e *KrakenExchange) handleAccountUpdates(upd kraken.AccountUpd, handler EventHandler) {
+	for _, o := range upd.Orders {
+		e.handleOrder(o, handler)
+	}
+
+	for _, t := range upd.Trades {
+		e.handleTrade(t, handler)
+	}
+}
+
+func (e *KrakenExchange) Watch(ctx context.Context, handler EventHandler) {
+	AcUpdChan := make(chan kraken.AccountUpd, 128)
+	stopChan := make(chan bool)
+
+	go func() {
+		for upd := range AcUpdChan {
+			if upd.Subscribed {
+				handler(&Event{Type: EventConnected})
+			}
+			e.handleAccountUpdates(upd, handler)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		close(stopChan)
+	}()
+
+	for ctx.Err() == nil {
+		log.Success(fmt.Sprintf("Subscribing to %s account update events..", strings.ToUpper(e.provider.Name)))
+		if err := e.client.SubscribeAccount(AcUpdChan, stopChan); err != nil {
+			log.Error(err)
+			sleep(ctx, time.Second)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package app
+
+import (
+	"../api/kucoin"
+	"../utils/log"
+	"../utils/values"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterExchange("kucoin", NewKucoinExchange)
+}
+
+type KucoinExchange struct {
+	provider *Provider
+	client   *kucoin.Config
+	clock    *Clock
+}
+
+func NewKucoinExchange(p *Provider) (Exchange, error) {
+	client := p.NewKucoinClient()
+	if client == nil {
+		return nil, errors.New(fmt.Sprintf("%s client could not be created", strings.ToUpper(p.Name)))
+	}
+
+	e := &KucoinExchange{
+		provider: p,
+		client:   client,
+	}
+
+	// The timestamp of a signed request is the local time minus the offset
+	e.clock = NewClock(p.Name, client.GetServerTime, client.SetTimeOffset)
+	if err := e.clock.Sync(); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// SyncClock measures the offset to the KuCoin server time.
+func (e *KucoinExchange) SyncClock() error {
+	return e.clock.Sync()
+}
+
+func (e *KucoinExchange) GetFilter(symbol string) (*Filter, error) {
+	s := e.client.GetSymbol(symbol)
+	if s == nil {
+		return nil, errors.New(fmt.Sprintf("unknown symbol: %s", symbol))
+	}
+
+	filter := DefaultFilter()
+	filter.StepSize = values.NewFloat(&s.BaseIncrement.Float)
+	filter.TickSize = values.NewFloat(&s.PriceIncrement.Float)
+	filter.MinNotional = values.NewFloat(&s.MinFunds.Float)
+
+	return filter, nil
+}
+
+func (e *KucoinExchange) GetBalances() (map[string]*values.Float, error) {
+	return e.client.GetBalances()
+}
+
+func (e *KucoinExchange) GetOpenOrders(symbol string) ([]*Order, error) {
+	orders, err := e.client.GetOpenOrders(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Order, 0)
+	for _, o := range orders {
+		result = append(result, e.newOrder(o))
+	}
+	return result, nil
+}
+
+func (e *KucoinExchange) PlaceOrder(r *OrderRequest) (*Order, error) {
+	id, err := e.client.PlaceOrder(r.Symbol, r.Side, r.Price, r.Amount, r.ClientId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Order{
+		Id:       id,
+		ClientId: r.ClientId,
+		Symbol:   r.Symbol,
+		Volume:   r.Amount,
+		Price:    r.Price,
+		Total:    r.Amount.Mul(r.Price),
+		Fee:      values.NewEmptyFloat(),
+		Side:     r.Side,
+		Status:   StatusNew,
+		Date:     time.Now(),
+	}, nil
+}
+
+func (e *KucoinExchange) CancelOrder(symbol string, id string) error {
+	return e.client.CancelOrder(id)
+}
+
+// GetOrder returns the order with the given id, including closed orders.
+func (e *KucoinExchange) GetOrder(symbol string, id string) (*Order, error) {
+	o, err := e.client.GetOrder(id)
+	if err != nil {
+		return nil, err
+	}
+	return e.closedOrder(o), nil
+}
+
+// GetOrderByClientId returns the order with the given client id, including
+// closed orders.
+func (e *KucoinExchange) GetOrderByClientId(symbol string, clientId string) (*Order, error) {
+	o, err := e.client.GetOrderByClientOid(clientId)
+	if err != nil || o == nil {
+		return nil, err
+	}
+	return e.closedOrder(o), nil
+}
+
+func (e *KucoinExchange) GetTrades(symbol string, since time.Time) ([]*Trade, error) {
+	fills, err := e.client.GetFills(symbol, since)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Trade, 0)
+	for _, f := range fills {
+		result = append(result, &Trade{
+			Id:      f.TradeId,
+			OrderId: f.OrderId,
+			Symbol:  f.Symbol,
+			Side:    f.Side,
+			Price:   values.NewFloat(&f.Price.Float),
+			Volume:  values.NewFloat(&f.Size.Float),
+			Date:    time.Unix(0, f.CreatedAt*int64(time.Millisecond)),
+		})
+	}
+	return result, nil
+}
+
+func (e *KucoinExchange) newOrder(o *kucoin.Order) *Order {
+	volume := values.NewFloat(&o.Size.Float)
+	price := values.NewFloat(&o.Price.Float)
+
+	order := &Order{
+		Id:       o.Id,
+		ClientId: o.ClientOid,
+		Symbol:   o.Symbol,
+		Volume:   volume,
+		Price:    price,
+		Total:    volume.Mul(price),
+		Fee:      values.NewEmptyFloat(),
+		Side:     o.Side,
+		Status:   StatusNew,
+		Date:     time.Unix(0, o.CreatedAt*int64(time.Millisecond)),
+	}
+	order.setExecuted(values.NewFloat(&o.DealSize.Float))
+
+	return order
+}
+
+// closedOrder returns an order including its final status. An order which
+// isn't active anymore got either canceled or filled.
+func (e *KucoinExchange) closedOrder(o *kucoin.Order) *Order {
+	order := e.newOrder(o)
+	switch {
+	case o.IsActive && o.DealSize.Sign() > 0:
+		order.Status = StatusPartial
+	case !o.IsActive && o.CancelExist:
+		order.Status = StatusCanceled
+	case !o.IsActive:
+		order.Status = StatusFilled
+	}
+	return order
+}
+
+func (e *KucoinExchange) handleOrderChange(upd kucoin.OrderChange, handler EventHandler) {
+	if upd.OrderType != "limit" {
+		return
+	}
+	symbol := upd.Symbol
+
+	volume := values.NewFloat(&upd.Size.Float)
+	price := values.NewFloat(&upd.Price.Float)
+	order := &Order{
+		Id:       upd.OrderId,
+		ClientId: upd.ClientOid,
+		Symbol:   upd.Symbol,
+		Volume:   volume,
+		Price:    price,
+		Total:    volume.Mul(price),
+		Fee:      values.NewEmptyFloat(),
+		Side:     upd.Side,
+		Date:     time.Unix(0, upd.Ts),
+	}
+	order.setExecuted(values.NewFloat(&upd.FilledSize.Float))
+
+	switch upd.Type {
+	case "open":
+		order.Status = StatusNew
+		handler(&Event{Type: EventNew, Symbol: symbol, Order: order})
+	case "filled":
+		order.Status = StatusFilled
+		handler(&Event{Type: EventFilled, Symbol: symbol, Order: order})
+	case "canceled":
+		order.Status = StatusCanceled
+		handler(&Event{Type: EventCanceled, Symbol: symbol, Order: order})
+	case "match":
+		log.Info(fmt.Sprintf("%s ORDER UPDATE: %s %s @ %s - %.8f remaining", strings.ToUpper(e.provider.Name), symbol, upd.OrderId, upd.Side, upd.RemainSize.ToFloat()))
+		if upd.RemainSize.Sign() > 0 {
+			order.Status = StatusPartial
+			handler(&Event{Type: EventPartial, Symbol: symbol, Order: order})
+		}
+	}
+}
+
+func (e *KucoinExchange) Watch(ctx context.Context, handler EventHandler) {
+	go e.clock.Run(ctx)
+
+	OrderChan := make(chan kucoin.OrderChange, 128)
+	stopChan := make(chan bool)
+
+	go func() {
+		for upd := range OrderChan {
+			e.handleOrderChange(upd, handler)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		close(stopChan)
+	}()
+
+	for ctx.Err() == nil {
+		log.Success(fmt.Sprintf("Subscribing to %s account update events..", strings.ToUpper(e.provider.Name)))
+		handler(&Event{Type: EventConnected})
+		if err := e.client.SubscribeOrders(OrderChan, stopChan); err != nil {
+			log.Error(err)
+			sleep(ctx, time.Second)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"../api/kucoin"
+	"../utils/values"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestKucoinOrderChange(t *testing.T) {
+	tests := []struct {
+		name      string
+		kind      string
+		orderType string
+		filled    string
+		remaining string
+		event     EventType
+		status    string
+	}{
+		{"open", "open", "limit", "0", "30", EventNew, StatusNew},
+		{"match", "match", "limit", "10", "20", EventPartial, StatusPartial},
+		{"last match", "match", "limit", "30", "0", "", ""},
+		{"filled", "filled", "limit", "30", "0", EventFilled, StatusFilled},
+		{"canceled", "canceled", "limit", "10", "0", EventCanceled, StatusCanceled},
+		{"update", "update", "limit", "0", "30", "", ""},
+		{"market order", "filled", "market", "30", "0", "", ""},
+	}
+
+	e := &KucoinExchange{provider: &Provider{Name: "kucoin-test"}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := make([]*Event, 0)
+			e.handleOrderChange(kucoin.OrderChange{
+				Symbol:     "DOGE-BTC",
+				OrderType:  tt.orderType,
+				Side:       "buy",
+				OrderId:    "5efab07953bdea00089965d2",
+				ClientOid:  "abc",
+				Type:       tt.kind,
+				Price:      *values.NewFloatFromString("0.00000301"),
+				Size:       *values.NewFloatFromString("30"),
+				FilledSize: *values.NewFloatFromString(tt.filled),
+				RemainSize: *values.NewFloatFromString(tt.remaining),
+				Ts:         1593487481683297666,
+			}, func(evt *Event) { events = append(events, evt) })
+
+			if tt.event == "" {
+				if len(events) != 0 {
+					t.Errorf("events = %d, want 0", len(events))
+				}
+				return
+			}
+			if len(events) != 1 {
+				t.Fatalf("events = %d, want 1", len(events))
+			}
+			evt, o := events[0], events[0].Order
+			if evt.Type != tt.event || evt.Symbol != "DOGE-BTC" || o.Status != tt.status {
+				t.Errorf("event = %s %s %s", evt.Type, evt.Symbol, o.Status)
+			}
+			if o.Id != "5efab07953bdea00089965d2" || o.ClientId != "abc" || o.Side != SideBuy || o.Date.Unix() != 1593487481 {
+				t.Errorf("order = %s %s %s %d", o.Id, o.ClientId, o.Side, o.Date.Unix())
+			}
+			if o.Volume.ToString() != "30.00000000" || o.Price.ToString() != "0.00000301" || !o.Executed.Eq(values.NewFloatFromString(tt.filled)) {
+				t.Errorf("order = %s @ %s, executed %s", o.Volume.ToString(), o.Price.ToString(), o.Executed.ToString())
+			}
+		})
+	}
+}
+
+func TestKucoinGetOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		active      bool
+		cancelExist bool
+		dealSize    string
+		want        string
+	}{
+		{"open", true, false, "0", StatusNew},
+		{"partially filled", true, false, "10", StatusPartial},
+		{"filled", false, false, "30", StatusFilled},
+		{"canceled", false, true, "10", StatusCanceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v1/orders/5efab07953bdea00089965d2" {
+					t.Errorf("path = %s", r.URL.Path)
+				}
+				fmt.Fprintf(w, `{"code":"200000","data":{"id":"5efab07953bdea00089965d2","clientOid":"abc","symbol":"DOGE-BTC","side":"buy","type":"limit","price":"0.00000301","size":"30","dealSize":"%s","isActive":%t,"cancelExist":%t,"createdAt":1593487481683}}`, tt.dealSize, tt.active, tt.cancelExist)
+			}))
+			defer srv.Close()
+
+			client := kucoin.NewKucoinApi("key", "secret", "passphrase")
+			client.RestEndpoint = srv.URL
+			e := &KucoinExchange{provider: &Provider{Name: "kucoin-test"}, client: client}
+
+			o, err := e.GetOrder("DOGE-BTC", "5efab07953bdea00089965d2")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if o.Id != "5efab07953bdea00089965d2" || o.ClientId != "abc" || o.Status != tt.want || !o.Executed.Eq(values.NewFloatFromString(tt.dealSize)) {
+				t.Errorf("order = %s %s %s %s", o.Id, o.ClientId, o.Status, o.Executed.ToString())
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package app
+
+import (
+	"../utils/log"
+	"../utils/values"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// handleEvent applies the mirror strategy: every filled order gets countered
+// by an order on the opposite side, one step away from the filled price.
+func (j *Job) handleEvent(evt *Event) {
+	if !j.begin() {
+		// Shutting down, missed events get recovered on the next start
+		return
+	}
+	defer j.work.Done()
+
+	switch evt.Type {
+	case EventNew:
+		j.AttachOrder(evt.Order)
+	case EventCanceled:
+		if !j.counterRemainder(evt.Order) {
+			j.DetachOrder(evt.Order.Id)
+		}
+	case EventPartial:
+		j.handlePartial(evt.Order)
+		j.rearm(evt.Order.Price)
+	case EventFilled:
+		j.handleFill(evt.Order)
+		j.rearm(evt.Order.Price)
+	case EventConnected:
+		go j.Recover()
+	case EventBalance:
+		for asset, free := range evt.Balance {
+			if free.Lt(j.getBalance(asset)) {
+				j.setBalance(asset, free)
+				log.Info(fmt.Sprintf("%s AVAILABLE BALANCE %.8f %s", strings.ToUpper(j.Provider.Name), free.ToFloat(), asset))
+			}
+		}
+	}
+}
+
+// placeSellOrder creates a new sell order for a filled buy order.
+func (j *Job) placeSellOrder(o *Order) {
+	price := j.sellPrice(o.Price)
+
+	dif := o.Volume.Div(values.HundredFloat)
+	buyFee := dif.Mul(&j.Fee)
+
+	availableAmount := o.Volume.Sub(buyFee)
+	sellAmount := j.validateAmount(availableAmount)
+
+	if amount, ok := j.baseSellAmount(o, price, availableAmount); ok {
+		sellAmount = amount
+		log.Info(fmt.Sprintf("%s KEPT %.8f %s", strings.ToUpper(j.Provider.Name), availableAmount.Sub(sellAmount).ToFloat(), j.Secondary))
+	} else if j.getBalance(j.Secondary).Gt(buyFee) {
+		sellAmount = o.Volume
+
+		j.subBalance(j.Secondary, buyFee)
+		log.Info(fmt.Sprintf("%s LEND %.8f %s", strings.ToUpper(j.Provider.Name), buyFee, j.Secondary))
+	} else {
+		dif := availableAmount.Sub(sellAmount)
+
+		j.addBalance(j.Secondary, dif)
+		log.Info(fmt.Sprintf("%s GAVE %.8f %s", strings.ToUpper(j.Provider.Name), dif, j.Secondary))
+	}
+
+	total := price.Mul(sellAmount)
+
+	// The saved order carries the total of its counter order
+	j.execute(&Intent{
+		Fill: &Order{
+			Id:     o.Id,
+			Symbol: o.Symbol,
+			Volume: o.Volume,
+			Price:  o.Price,
+			Total:  total,
+			Fee:    buyFee,
+			Side:   SideBuy,
+			Status: fillStatus(o),
+			Date:   time.Now(),
+		},
+		Request: &OrderRequest{
+			Symbol:   j.Symbol,
+			Side:     SideSell,
+			Price:    price,
+			Amount:   sellAmount,
+			ClientId: counterOrderId(o.Id),
+		},
+		Gain: total.Sub(o.Volume.Mul(o.Price)),
+		Date: time.Now(),
+	})
+}
+
+// placeBuyOrder creates a new buy order for a filled sell order. The share
+// of a partially filled order reduces the buy volume accordingly.
+func (j *Job) placeBuyOrder(o *Order, share *values.Float) {
+	price := j.buyPrice(o.Price)
+
+	amount := j.buyVolume().Div(price)
+	if share != nil {
+		amount = amount.Mul(share)
+	}
+	amount = j.validateAmount(amount)
+	total := price.Mul(amount)
+
+	dif := o.Volume.Div(values.HundredFloat)
+	sellFee := dif.Mul(&j.Fee)
+
+	j.execute(&Intent{
+		Fill: &Order{
+			Id:     o.Id,
+			Symbol: o.Symbol,
+			Volume: o.Volume,
+			Price:  o.Price,
+			Total:  total,
+			Fee:    sellFee,
+			Side:   SideSell,
+			Status: fillStatus(o),
+			Date:   time.Now(),
+		},
+		Request: &OrderRequest{
+			Symbol:   j.Symbol,
+			Side:     SideBuy,
+			Price:    price,
+			Amount:   amount,
+			ClientId: counterOrderId(o.Id),
+		},
+		Gain: o.Volume.Mul(o.Price).Sub(total),
+		Date: time.Now(),
+	})
+}
+
+// execute queues the counter order of an intent. The intent gets journaled
+// first, which allows to replay it if the bot gets killed in between. If the
+// queue is full, the intent gets parked instead of blocking the caller.
+// Simulated jobs place the order right away.
+func (j *Job) execute(in *Intent) {
+	in.Attempts = 0
+	in.Parked = false
+	j.journal.Add(in)
+
+	if j.queue == nil {
+		j.place(in)
+		return
+	}
+
+	j.queueMx.Lock()
+	defer j.queueMx.Unlock()
+	if j.queueClosed {
+		// The queue got drained already, the intent gets replayed on the next start
+		log.Warn(fmt.Sprintf("%s ORDER POSTPONED: %s", strings.ToUpper(j.Provider.Name), in.Fill.Id))
+		return
+	}
+	j.work.Add(1)
+	select {
+	case j.queue <- in:
+	default:
+		// Events mustn't wait for the placements, the intent gets retried
+		// with the parked orders
+		j.work.Done()
+		j.park(in, errorQueueFull, errors.New(fmt.Sprintf("%d counter orders are queued already", cap(j.queue))))
+	}
+}
+
+// complete finishes an intent whose counter order got placed.
+func (j *Job) complete(in *Intent, order *Order) {
+	r := in.Request
+	log.Success(fmt.Sprintf("%s ORDER CREATED: %s", strings.ToUpper(j.Provider.Name), order.Id))
+
+	j.finish(in)
+	// Compounded after the intent got removed, a replayed intent mustn't
+	// compound twice
+	j.compound(in.Fill)
+
+	j.NotifyOrder(r.Price.ToFloat(), r.Amount.ToFloat(), r.Price.Mul(r.Amount).ToFloat(), in.Gain.ToFloat(), r.Side)
+}
+
+// finish persists the filled order of an intent and removes the intent from
+// the journal afterwards. If the bot gets killed in between, the intent gets
+// replayed and completed again.
+func (j *Job) finish(in *Intent) {
+	j.SaveOrder(in.Fill)
+	if in.Fill.Status != StatusPartial {
+		j.DetachOrder(in.Fill.Id)
+	}
+	j.journal.Remove(in.Fill.Id)
+}
+
+// fillStatus returns the status of the fill record of a counter order.
+func fillStatus(o *Order) string {
+	if o.Status == StatusPartial {
+		return StatusPartial
+	}
+	return StatusFilled
+}
+
+// replayJournal places the counter orders of all intents which weren't
+// completed by a previous run. Counter orders which are already open only
+// get completed. Exchanges such as Binance reject a duplicate client id only
+// among open orders, the client id of every other intent gets looked up
+// before its counter order gets placed again.
+func (j *Job) replayJournal(open []*Order) {
+	getter, _ := j.Exchange.(ClientOrderGetter)
+
+	for _, in := range j.journal.Pending() {
+		j.markFilled(in.Fill.Id)
+
+		placed := false
+		for _, o := range open {
+			if o.ClientId != "" && o.ClientId == in.Request.ClientId {
+				j.complete(in, o)
+				placed = true
+				break
+			}
+		}
+		if placed {
+			continue
+		}
+
+		if getter != nil && in.Request.ClientId != "" {
+			o, err := getter.GetOrderByClientId(j.Symbol, in.Request.ClientId)
+			if err != nil {
+				// Placing it blindly might duplicate it, the intent gets
+				// replayed on the next start
+				log.Error(err)
+				log.Warn(fmt.Sprintf("%s ORDER POSTPONED: %s", strings.ToUpper(j.Provider.Name), in.Fill.Id))
+				continue
+			}
+			if o != nil {
+				// The order got closed in the meantime, the recovery
+				// handles its final status
+				j.AttachOrder(o)
+				j.complete(in, o)
+				continue
+			}
+		}
+
+		log.Warn(fmt.Sprintf("%s REPLAYING ORDER: %s", strings.ToUpper(j.Provider.Name), in.Fill.Id))
+		j.execute(in)
+	}
+}
+
+// validateAmount rounds the given amount down to the symbol lot size.
+func (j *Job) validateAmount(amount *values.Float) *values.Float {
+	return amount.Truncate(j.getFilter().StepSize)
+}
@@ -0,0 +1,241 @@
+package app
+
+import (
+	"../api/okx"
+	"../utils/log"
+	"../utils/values"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterExchange("okx", NewOkxExchange)
+}
+
+type OkxExchange struct {
+	provider *Provider
+	client   *okx.Config
+	clock    *Clock
+}
+
+func NewOkxExchange(p *Provider) (Exchange, error) {
+	client := p.NewOkxClient()
+	if client == nil {
+		return nil, errors.New(fmt.Sprintf("%s client could not be created", strings.ToUpper(p.Name)))
+	}
+
+	e := &OkxExchange{
+		provider: p,
+		client:   client,
+	}
+
+	// The timestamp of a signed request is the local time minus the offset
+	e.clock = NewClock(p.Name, client.GetServerTime, client.SetTimeOffset)
+	if err := e.clock.Sync(); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// SyncClock measures the offset to the OKX server time.
+func (e *OkxExchange) SyncClock() error {
+	return e.clock.Sync()
+}
+
+func (e *OkxExchange) GetFilter(symbol string) (*Filter, error) {
+	i := e.client.GetInstrument(symbol)
+	if i == nil {
+		return nil, errors.New(fmt.Sprintf("unknown symbol: %s", symbol))
+	}
+
+	filter := DefaultFilter()
+	filter.StepSize = values.NewFloat(&i.LotSz.Float)
+	filter.TickSize = values.NewFloat(&i.TickSz.Float)
+
+	// OKX limits the order size instead of the order value, which gets
+	// converted at the last price
+	if t, err := e.client.GetTicker(symbol); err == nil {
+		filter.MinNotional = i.MinSz.Mul(&t.Last)
+	} else {
+		log.Warn(fmt.Sprintf("%s MINIMUM ORDER VALUE UNKNOWN: %s", strings.ToUpper(e.provider.Name), err.Error()))
+	}
+
+	return filter, nil
+}
+
+func (e *OkxExchange) GetBalances() (map[string]*values.Float, error) {
+	return e.client.GetBalances()
+}
+
+func (e *OkxExchange) GetOpenOrders(symbol string) ([]*Order, error) {
+	orders, err := e.client.GetOpenOrders(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Order, 0)
+	for _, o := range orders {
+		if o.OrdType != "limit" {
+			continue
+		}
+		result = append(result, e.newOrder(o))
+	}
+	return result, nil
+}
+
+func (e *OkxExchange) PlaceOrder(r *OrderRequest) (*Order, error) {
+	id, err := e.client.PlaceOrder(r.Symbol, r.Side, r.Price, r.Amount, r.ClientId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Order{
+		Id:       id,
+		ClientId: r.ClientId,
+		Symbol:   r.Symbol,
+		Volume:   r.Amount,
+		Price:    r.Price,
+		Total:    r.Amount.Mul(r.Price),
+		Fee:      values.NewEmptyFloat(),
+		Side:     r.Side,
+		Status:   StatusNew,
+		Date:     time.Now(),
+	}, nil
+}
+
+func (e *OkxExchange) CancelOrder(symbol string, id string) error {
+	return e.client.CancelOrder(symbol, id)
+}
+
+// GetOrder returns the order with the given id, including closed orders.
+func (e *OkxExchange) GetOrder(symbol string, id string) (*Order, error) {
+	o, err := e.client.GetOrder(symbol, id)
+	if err != nil {
+		return nil, err
+	}
+	return e.closedOrder(o), nil
+}
+
+// GetOrderByClientId returns the order with the given client id, including
+// closed orders.
+func (e *OkxExchange) GetOrderByClientId(symbol string, clientId string) (*Order, error) {
+	o, err := e.client.GetOrderByClientId(symbol, clientId)
+	if err != nil || o == nil {
+		return nil, err
+	}
+	return e.closedOrder(o), nil
+}
+
+func (e *OkxExchange) GetTrades(symbol string, since time.Time) ([]*Trade, error) {
+	fills, err := e.client.GetFills(symbol, since)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Trade, 0)
+	for _, f := range fills {
+		ts, _ := strconv.ParseInt(f.Ts, 10, 64)
+		result = append(result, &Trade{
+			Id:      f.TradeId,
+			OrderId: f.OrdId,
+			Symbol:  f.InstId,
+			Side:    f.Side,
+			Price:   values.NewFloat(&f.FillPx.Float),
+			Volume:  values.NewFloat(&f.FillSz.Float),
+			Date:    time.Unix(0, ts*int64(time.Millisecond)),
+		})
+	}
+	return result, nil
+}
+
+func (e *OkxExchange) newOrder(o *okx.Order) *Order {
+	volume := values.NewFloat(&o.Sz.Float)
+	price := values.NewFloat(&o.Px.Float)
+	ts, _ := strconv.ParseInt(o.CTime, 10, 64)
+
+	order := &Order{
+		Id:       o.OrdId,
+		ClientId: o.ClOrdId,
+		Symbol:   o.InstId,
+		Volume:   volume,
+		Price:    price,
+		Total:    volume.Mul(price),
+		Fee:      values.NewEmptyFloat(),
+		Side:     o.Side,
+		Status:   StatusNew,
+		Date:     time.Unix(0, ts*int64(time.Millisecond)),
+	}
+	order.setExecuted(values.NewFloat(&o.AccFillSz.Float))
+
+	return order
+}
+
+// closedOrder returns an order including its final state.
+func (e *OkxExchange) closedOrder(o *okx.Order) *Order {
+	order := e.newOrder(o)
+	switch o.State {
+	case "partially_filled":
+		order.Status = StatusPartial
+	case "filled":
+		order.Status = StatusFilled
+	case "canceled", "mmp_canceled":
+		order.Status = StatusCanceled
+	}
+	return order
+}
+
+func (e *OkxExchange) handleOrder(o okx.Order, handler EventHandler) {
+	if o.OrdType != "limit" {
+		return
+	}
+	symbol := o.InstId
+
+	order := e.newOrder(&o)
+	switch o.State {
+	case "live":
+		handler(&Event{Type: EventNew, Symbol: symbol, Order: order})
+	case "filled":
+		order.Status = StatusFilled
+		order.Date = time.Now()
+		handler(&Event{Type: EventFilled, Symbol: symbol, Order: order})
+	case "canceled":
+		order.Status = StatusCanceled
+		handler(&Event{Type: EventCanceled, Symbol: symbol, Order: order})
+	case "partially_filled":
+		order.Status = StatusPartial
+		handler(&Event{Type: EventPartial, Symbol: symbol, Order: order})
+	default:
+		log.Info(fmt.Sprintf("%s ORDER UPDATE: %s %s @ %s - %.8f of %.8f", strings.ToUpper(e.provider.Name), symbol, o.OrdId, o.Side, o.AccFillSz.ToFloat(), o.Sz.ToFloat()))
+	}
+}
+
+func (e *OkxExchange) Watch(ctx context.Context, handler EventHandler) {
+	go e.clock.Run(ctx)
+
+	OrderChan := make(chan okx.Order, 128)
+	stopChan := make(chan bool)
+
+	go func() {
+		for o := range OrderChan {
+			e.handleOrder(o, handler)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		close(stopChan)
+	}()
+
+	for ctx.Err() == nil {
+		log.Success(fmt.Sprintf("Subscribing to %s account update events..", strings.ToUpper(e.provider.Name)))
+		handler(&Event{Type: EventConnected})
+		if err := e.client.SubscribeOrders(OrderChan, stopChan); err != nil {
+			log.Error(err)
+			sleep(ctx, time.Second)
+		}
+	}
+}
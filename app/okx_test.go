@@ -0,0 +1,90 @@
+package app
+
+import (
+	"../api/okx"
+	"../utils/values"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOkxFilter(t *testing.T) {
+	tests := []struct {
+		name        string
+		ticker      string
+		minNotional string
+	}{
+		// The minimum size of 10 DOGE at the last price
+		{"last price", `{"code":"0","msg":"","data":[{"instId":"DOGE-BTC","last":"0.000003"}]}`, "0.00003000"},
+		{"ticker failed", `{"code":"50001","msg":"Service temporarily unavailable","data":[]}`, "0.00000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.ticker)
+			}))
+			defer srv.Close()
+
+			client := okx.NewOkxApi("key", "secret", "passphrase")
+			client.RestEndpoint = srv.URL
+			client.Instruments["DOGE-BTC"] = &okx.Instrument{
+				InstId: "DOGE-BTC",
+				LotSz:  *values.NewFloatFromString("0.0001"),
+				TickSz: *values.NewFloatFromString("0.00000001"),
+				MinSz:  *values.NewFloatFromString("10"),
+			}
+			e := &OkxExchange{provider: &Provider{Name: "okx-test"}, client: client}
+
+			f, err := e.GetFilter("DOGE-BTC")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.StepSize.ToString() != "0.00010000" || f.TickSize.ToString() != "0.00000001" {
+				t.Errorf("filter = %s, %s", f.StepSize.ToString(), f.TickSize.ToString())
+			}
+			if f.MinNotional.ToString() != tt.minNotional {
+				t.Errorf("min notional = %s, want %s", f.MinNotional.ToString(), tt.minNotional)
+			}
+
+			if _, err := e.GetFilter("BTC-USDT"); err == nil {
+				t.Error("an unknown symbol got a filter")
+			}
+		})
+	}
+}
+
+func TestOkxGetOrder(t *testing.T) {
+	tests := []struct {
+		state    string
+		executed string
+		want     string
+	}{
+		{"live", "0", StatusNew},
+		{"partially_filled", "10", StatusPartial},
+		{"filled", "30", StatusFilled},
+		{"canceled", "10", StatusCanceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.state, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `{"code":"0","msg":"","data":[{"instId":"DOGE-BTC","ordId":"312269865356374016","clOrdId":"abc","side":"buy","ordType":"limit","px":"0.00000301","sz":"30","accFillSz":"%s","state":"%s","cTime":"1597026383085"}]}`, tt.executed, tt.state)
+			}))
+			defer srv.Close()
+
+			client := okx.NewOkxApi("key", "secret", "passphrase")
+			client.RestEndpoint = srv.URL
+			e := &OkxExchange{provider: &Provider{Name: "okx-test"}, client: client}
+
+			o, err := e.GetOrder("DOGE-BTC", "312269865356374016")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if o.Id != "312269865356374016" || o.ClientId != "abc" || o.Status != tt.want || !o.Executed.Eq(values.NewFloatFromString(tt.executed)) {
+				t.Errorf("order = %s %s %s %s", o.Id, o.ClientId, o.Status, o.Executed.ToString())
+			}
+		})
+	}
+}
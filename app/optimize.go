@@ -0,0 +1,327 @@
+package app
+
+import (
+	"../utils/values"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// OptimizeConfig holds the options of the optimize command. Every parameter
+// range is given as "from:to:increment" or as a single value.
+type OptimizeConfig struct {
+	Job      string
+	Trades   string
+	Format   string
+	Step     string
+	BuyStep  string
+	SellStep string
+	Volume   string
+	StepSize string
+	TickSize string
+	Low      string
+	High     string
+	Sort     string
+	Top      int
+	Workers  int
+}
+
+// Optimizer runs a backtest for every parameter combination.
+type Optimizer struct {
+	*OptimizeConfig
+
+	job     *Job
+	candles []*Candle
+	filter  *Filter
+}
+
+// OptimizeParams is a single parameter combination.
+type OptimizeParams struct {
+	Step     *values.Float
+	BuyStep  *values.Float
+	SellStep *values.Float
+	Volume   *values.Float
+}
+
+type OptimizeResult struct {
+	*OptimizeParams
+	*BacktestResult
+}
+
+type OptimizeReport struct {
+	Results   []*OptimizeResult
+	Tested    int
+	Discarded int
+	BreakEven *values.Float
+}
+
+func DefaultOptimizeConfig() *OptimizeConfig {
+	return &OptimizeConfig{
+		Sort:    "profit",
+		Top:     10,
+		Workers: runtime.NumCPU(),
+	}
+}
+
+// AddFlags adds configuration flags to the given FlagSet.
+func (c *OptimizeConfig) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Job, "job", c.Job, "Job configuration file")
+	fs.StringVar(&c.Trades, "trades", c.Trades, "CSV file containing trade ticks or OHLC candles")
+	fs.StringVar(&c.Format, "format", c.Format, "Format of the trade file \"candles\" or \"trades\" (default: picked from the header)")
+	fs.StringVar(&c.Step, "step", c.Step, "Step range (from:to:increment) (default: job step)")
+	fs.StringVar(&c.BuyStep, "buy-step", c.BuyStep, "Buy step range (from:to:increment) (default: job buy-step)")
+	fs.StringVar(&c.SellStep, "sell-step", c.SellStep, "Sell step range (from:to:increment) (default: job sell-step)")
+	fs.StringVar(&c.Volume, "volume", c.Volume, "Volume range (from:to:increment) (default: job volume)")
+	fs.StringVar(&c.StepSize, "step-size", c.StepSize, "Quantity step size (lot size) of the symbol")
+	fs.StringVar(&c.TickSize, "tick-size", c.TickSize, "Price tick size of the symbol")
+	fs.StringVar(&c.Low, "low", c.Low, "Lowest grid price (default: lowest price found)")
+	fs.StringVar(&c.High, "high", c.High, "Highest grid price (default: highest price found)")
+	fs.StringVar(&c.Sort, "sort", c.Sort, "Rank results by \"profit\", \"capital\" (profit per capital) or \"fills\"")
+	fs.IntVar(&c.Top, "top", c.Top, "Number of results to show")
+	fs.IntVar(&c.Workers, "workers", c.Workers, "Number of parallel simulations")
+}
+
+func NewOptimizer(c *OptimizeConfig) (*Optimizer, error) {
+	if c.Job == "" || c.Trades == "" {
+		return nil, errors.New("a job and a trade file are required")
+	}
+
+	j, err := LoadJobFromFile(c.Job)
+	if err != nil {
+		return nil, err
+	}
+
+	candles, err := LoadCandles(c.Trades, c.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	f := DefaultFilter()
+	if c.StepSize != "" {
+		f.StepSize = values.NewFloatFromString(c.StepSize)
+	}
+	if c.TickSize != "" {
+		f.TickSize = values.NewFloatFromString(c.TickSize)
+	}
+
+	return &Optimizer{
+		OptimizeConfig: c,
+		job:            j,
+		candles:        candles,
+		filter:         f,
+	}, nil
+}
+
+// Combinations returns every parameter combination of the configured ranges.
+func (o *Optimizer) Combinations() ([]*OptimizeParams, error) {
+	steps, err := parseRange(o.Step, &o.job.Step)
+	if err != nil {
+		return nil, err
+	}
+	buySteps, err := parseRange(o.BuyStep, &o.job.BuyStep)
+	if err != nil {
+		return nil, err
+	}
+	sellSteps, err := parseRange(o.SellStep, &o.job.SellStep)
+	if err != nil {
+		return nil, err
+	}
+	volumes, err := parseRange(o.Volume, &o.job.Volume)
+	if err != nil {
+		return nil, err
+	}
+
+	params := make([]*OptimizeParams, 0)
+	for _, step := range steps {
+		for _, buyStep := range buySteps {
+			for _, sellStep := range sellSteps {
+				for _, volume := range volumes {
+					params = append(params, &OptimizeParams{
+						Step:     step,
+						BuyStep:  buyStep,
+						SellStep: sellStep,
+						Volume:   volume,
+					})
+				}
+			}
+		}
+	}
+	return params, nil
+}
+
+// Run simulates every combination in parallel. Combinations whose steps
+// can't cover the trading fees at the highest price are discarded without
+// a simulation, unprofitable ones after it.
+func (o *Optimizer) Run() (*OptimizeReport, error) {
+	params, err := o.Combinations()
+	if err != nil {
+		return nil, err
+	}
+
+	high := o.candles[0].High
+	for _, c := range o.candles {
+		if c.High.Gt(high) {
+			high = c.High
+		}
+	}
+	if o.High != "" {
+		high = values.NewFloatFromString(o.High)
+	}
+
+	// A round trip pays the fee twice
+	breakEven := high.Div(values.HundredFloat).Mul(&o.job.Fee).Mul(values.NewFloatFromFloat64(2))
+	report := &OptimizeReport{
+		Results:   make([]*OptimizeResult, 0),
+		BreakEven: breakEven,
+	}
+
+	queue := make(chan *OptimizeParams)
+	mx := sync.Mutex{}
+	wg := sync.WaitGroup{}
+
+	workers := o.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range queue {
+				r := o.simulate(p)
+
+				mx.Lock()
+				report.Tested++
+				if r.Profit.Gt(values.ZeroFloat) {
+					report.Results = append(report.Results, &OptimizeResult{OptimizeParams: p, BacktestResult: r})
+				} else {
+					report.Discarded++
+				}
+				mx.Unlock()
+			}
+		}()
+	}
+
+	for _, p := range params {
+		j := o.newJob(p)
+		if !j.getStepAt(SideBuy, high).Gt(breakEven) || !j.getStepAt(SideSell, high).Gt(breakEven) {
+			report.Discarded++
+			continue
+		}
+		queue <- p
+	}
+	close(queue)
+	wg.Wait()
+
+	sort.SliceStable(report.Results, func(a, b int) bool {
+		ra, rb := report.Results[a], report.Results[b]
+		switch o.Sort {
+		case "capital":
+			return ra.ProfitPerCapital().Gt(rb.ProfitPerCapital())
+		case "fills":
+			return ra.BuyFills+ra.SellFills > rb.BuyFills+rb.SellFills
+		default:
+			return ra.Profit.Gt(rb.Profit)
+		}
+	})
+
+	return report, nil
+}
+
+func (o *Optimizer) simulate(p *OptimizeParams) *BacktestResult {
+	b := NewBacktest(o.newJob(p), o.candles)
+	b.Job.setFilter(o.filter)
+	if o.Low != "" {
+		b.Low = values.NewFloatFromString(o.Low)
+	}
+	if o.High != "" {
+		b.High = values.NewFloatFromString(o.High)
+	}
+
+	r, _ := b.Run()
+	return r
+}
+
+func (o *Optimizer) newJob(p *OptimizeParams) *Job {
+	j := NewDefaultJob()
+	j.Id = o.job.Id
+	j.Symbol = o.job.Symbol
+	j.Primary = o.job.Primary
+	j.Fee = o.job.Fee
+	j.Step = *p.Step
+	j.BuyStep = *p.BuyStep
+	j.SellStep = *p.SellStep
+	j.StepPercent = o.job.StepPercent
+	j.BuyStepPercent = o.job.BuyStepPercent
+	j.SellStepPercent = o.job.SellStepPercent
+	j.MinPrice = o.job.MinPrice
+	j.MaxPrice = o.job.MaxPrice
+	j.Rearm = o.job.Rearm
+	j.Compound = o.job.Compound
+	j.MaxVolume = o.job.MaxVolume
+	j.ProfitAsset = o.job.ProfitAsset
+	j.Volume = *p.Volume
+	j.Init()
+
+	return j
+}
+
+// parseRange parses "from:to:increment" into all values of the range.
+func parseRange(s string, def *values.Float) ([]*values.Float, error) {
+	if s == "" {
+		return []*values.Float{values.NewFloat(&def.Float)}, nil
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) == 1 {
+		return []*values.Float{values.NewFloatFromString(parts[0])}, nil
+	} else if len(parts) != 3 {
+		return nil, errors.New(fmt.Sprintf("invalid range: %s", s))
+	}
+
+	from := values.NewFloatFromString(parts[0])
+	to := values.NewFloatFromString(parts[1])
+	inc := values.NewFloatFromString(parts[2])
+	if !inc.Gt(values.ZeroFloat) || to.Lt(from) {
+		return nil, errors.New(fmt.Sprintf("invalid range: %s", s))
+	}
+
+	n := int(math.Floor(to.Sub(from).Div(inc).ToFloat()+1e-9)) + 1
+	result := make([]*values.Float, 0)
+	for i := 0; i < n; i++ {
+		result = append(result, from.Add(inc.Mul(values.NewFloatFromFloat64(float64(i)))))
+	}
+	return result, nil
+}
+
+func (r *OptimizeReport) String(top int) string {
+	text := fmt.Sprintf("#### Optimization - %d combinations tested, %d discarded\n", r.Tested+r.Discarded, r.Discarded)
+	text = text + fmt.Sprintf("Steps below %s don't cover the trading fees at the highest price.\n", r.BreakEven.ToPrecision(10))
+
+	if len(r.Results) == 0 {
+		return text + "\nNo profitable combination found.\n"
+	}
+
+	text = text + `
+| Step | Buy Step | Sell Step | Volume | Profit | P% | Fills | Round Trips | Peak Capital |
+|:-----|:---------|:----------|:-------|:-------|:---|:------|:------------|:-------------|`
+	for i, res := range r.Results {
+		if i >= top {
+			break
+		}
+		text = text + fmt.Sprintf("\n| %.8f | %.8f | %.8f | %.8f | %.8f | %.4f%% | %d | %d | %.8f |",
+			res.Step.ToFloat(), res.BuyStep.ToFloat(), res.SellStep.ToFloat(), res.Volume.ToFloat(),
+			res.Profit.ToFloat(), res.ProfitPerCapital().ToFloat(), res.BuyFills+res.SellFills, res.RoundTrips, res.PeakCapital.ToFloat())
+	}
+
+	best := r.Results[0]
+	text = text + fmt.Sprintf("\n\nBest grid: \"step\": \"%s\", \"buy-step\": \"%s\", \"sell-step\": \"%s\", \"volume\": \"%s\"\n",
+		best.Step.ToString(), best.BuyStep.ToString(), best.SellStep.ToString(), best.Volume.ToString())
+
+	return text
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"../utils/values"
+	"fmt"
+	"testing"
+)
+
+func TestOptimizerBreakEven(t *testing.T) {
+	// The highest price is 12, a round trip pays the fee twice
+	tests := []struct {
+		fee       string
+		breakEven string
+		tested    int
+	}{
+		{"0", "0.00000000", 3},
+		{"4", "0.96000000", 3},
+		{"5", "1.20000000", 2},
+		{"10", "2.40000000", 1},
+		{"15", "3.60000000", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fee, func(t *testing.T) {
+			o := &Optimizer{
+				OptimizeConfig: &OptimizeConfig{Step: "1:3:1", Low: "8", High: "12", Workers: 2},
+				job:            newTestJob(tt.fee),
+				candles:        newTestTicks(10, 11, 10, 11, 10),
+				filter:         DefaultFilter(),
+			}
+
+			r, err := o.Run()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprintf("%.8f", r.BreakEven.ToFloat()); got != tt.breakEven {
+				t.Errorf("break even = %s, want %s", got, tt.breakEven)
+			}
+			if r.Tested != tt.tested {
+				t.Errorf("tested = %d, want %d", r.Tested, tt.tested)
+			}
+			// Every combination is either kept or discarded
+			if r.Discarded+len(r.Results) != 3 {
+				t.Errorf("discarded %d and kept %d of 3 combinations", r.Discarded, len(r.Results))
+			}
+		})
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	def := values.NewFloatFromString("5")
+	tests := []struct {
+		in   string
+		want []string
+		err  bool
+	}{
+		{"", []string{"5"}, false},
+		{"2", []string{"2"}, false},
+		{"1:3:1", []string{"1", "2", "3"}, false},
+		{"0.1:0.3:0.1", []string{"0.1", "0.2", "0.3"}, false},
+		{"1:2", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseRange(tt.in, def)
+		if (err != nil) != tt.err {
+			t.Errorf("parseRange(%q) error = %v", tt.in, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("parseRange(%q) = %d values, want %d", tt.in, len(got), len(tt.want))
+			continue
+		}
+		for i, w := range tt.want {
+			if !got[i].Eq(values.NewFloatFromString(w)) {
+				t.Errorf("parseRange(%q)[%d] = %s, want %s", tt.in, i, got[i].ToString(), w)
+			}
+		}
+	}
+}
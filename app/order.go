@@ -2,23 +2,101 @@ package app
 
 import (
 	"../utils/values"
+	"encoding/json"
+	"strings"
 	"time"
 )
 
+const (
+	SideBuy  = "buy"
+	SideSell = "sell"
+
+	StatusNew      = "new"
+	StatusFilled   = "filled"
+	StatusCanceled = "canceled"
+
+	// Counter orders placed for a part of an order carry this status
+	StatusPartial = "partially-filled"
+)
+
 type Order struct {
-	Id     int64         `json:"id"`
-	Volume *values.Float `json:"volume"`
-	Price  *values.Float `json:"price"`
-	Total  *values.Float `json:"total"`
-	Fee    *values.Float `json:"fee"`
-	Side   string        `json:"side"`   // "sell" or "buy"
-	Status string        `json:"status"` // "new", "filled", "canceled" or "other"
-	Date   time.Time     `json:"date"`
+	Id       string        `json:"id"`
+	ClientId string        `json:"client-id"`
+	Symbol   string        `json:"symbol"`
+	Volume   *values.Float `json:"volume"`
+	Price    *values.Float `json:"price"`
+	Total    *values.Float `json:"total"`
+	Fee      *values.Float `json:"fee"`
+	Side     string        `json:"side"`   // "sell" or "buy"
+	Status   string        `json:"status"` // "new", "filled", "canceled" or "other"
+	Date     time.Time     `json:"date"`
+
+	// Executed and remaining volume, unknown if nil
+	Executed  *values.Float `json:"executed,omitempty"`
+	Remaining *values.Float `json:"remaining,omitempty"`
+	// Executed volume which got countered already
+	Countered *values.Float `json:"countered,omitempty"`
+}
+
+// Trade is a single execution of an order.
+type Trade struct {
+	Id      string
+	OrderId string
+	Symbol  string
+	Side    string
+	Price   *values.Float
+	Volume  *values.Float
+	Date    time.Time
+}
+
+// OrderRequest describes a limit order which should be placed on an exchange.
+// The client id is optional, exchanges reject a second open order with the
+// same client id.
+type OrderRequest struct {
+	Symbol   string        `json:"symbol"`
+	Side     string        `json:"side"`
+	Price    *values.Float `json:"price"`
+	Amount   *values.Float `json:"amount"`
+	ClientId string        `json:"client-id"`
+}
+
+// UnmarshalJSON accepts numeric order ids, which got stored by previous
+// versions.
+func (o *Order) UnmarshalJSON(b []byte) error {
+	type order Order
+	aux := &struct {
+		Id json.RawMessage `json:"id"`
+		*order
+	}{order: (*order)(o)}
+	if err := json.Unmarshal(b, aux); err != nil {
+		return err
+	}
+
+	id := strings.TrimSpace(string(aux.Id))
+	if strings.HasPrefix(id, `"`) {
+		return json.Unmarshal(aux.Id, &o.Id)
+	}
+	if id != "" && id != "null" {
+		o.Id = id
+	}
+	return nil
+}
+
+// fileName returns the name of the order file. Ids of partial fills contain
+// the executed volume behind a colon, which isn't allowed on every filesystem.
+func (o *Order) fileName() string {
+	return strings.NewReplacer(":", "_", "/", "_", "\\", "_").Replace(o.Id) + ".json"
+}
+
+// setExecuted updates the executed and the remaining volume of an order.
+func (o *Order) setExecuted(executed *values.Float) {
+	o.Executed = executed
+	o.Remaining = o.Volume.Sub(executed)
 }
 
 func NewDefaultOrder() *Order {
 	return &Order{
-		Id:     0,
+		Id:     "",
 		Volume: values.NewEmptyFloat(),
 		Price:  values.NewEmptyFloat(),
 		Total:  values.NewEmptyFloat(),
@@ -26,4 +104,4 @@ func NewDefaultOrder() *Order {
 		Side:   "",
 		Date:   time.Time{},
 	}
-}
\ No newline at end of file
+}
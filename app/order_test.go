@@ -0,0 +1,53 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestOrderUnmarshalId(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		id   string
+	}{
+		{"string", `{"id": "OQCLML-BW3P3-BUCMWZ", "side": "buy"}`, "OQCLML-BW3P3-BUCMWZ"},
+		{"number", `{"id": 1234567890, "side": "buy"}`, "1234567890"},
+		{"large number", `{"id": 9007199254740993, "side": "buy"}`, "9007199254740993"},
+		{"missing", `{"side": "buy"}`, ""},
+		{"null", `{"id": null, "side": "buy"}`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := NewDefaultOrder()
+			if err := json.Unmarshal([]byte(tt.json), o); err != nil {
+				t.Fatal(err)
+			}
+			if o.Id != tt.id {
+				t.Errorf("id = %q, want %q", o.Id, tt.id)
+			}
+			if o.Side != SideBuy {
+				t.Errorf("side = %q, want %q", o.Side, SideBuy)
+			}
+		})
+	}
+}
+
+func TestOrderFileName(t *testing.T) {
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{"123", "123.json"},
+		{"123:0.5", "123_0.5.json"},
+		{"a/b\\c", "a_b_c.json"},
+	}
+
+	for _, tt := range tests {
+		o := &Order{Id: tt.id}
+		if got := o.fileName(); got != tt.want {
+			t.Errorf("fileName(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
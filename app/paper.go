@@ -0,0 +1,422 @@
+package app
+
+import (
+	"../utils/config"
+	"../utils/log"
+	"../utils/values"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/adshao/go-binance/v2"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// Quote assets used to split a symbol without a separator such as "DOGEBTC"
+	paperQuoteAssets = []string{"BTC", "ETH", "BNB", "USDT", "BUSD", "USDC", "EUR", "USD"}
+
+	paperExchanges   = make(map[string]*PaperExchange)
+	paperExchangesMx = sync.Mutex{}
+)
+
+func init() {
+	RegisterExchange("paper", NewPaperExchange)
+}
+
+// PaperExchange simulates a matching engine in-process. Resting limit orders
+// get filled as soon as the price feed crosses them. All jobs sharing the same
+// provider trade on the same virtual account.
+type PaperExchange struct {
+	*config.Config `json:"-"`
+
+	Sequence int64                    `json:"sequence"`
+	Balances map[string]*values.Float `json:"balances"`
+	Orders   []*Order                 `json:"orders"`
+
+	provider  *Provider
+	fee       *values.Float
+	persist   bool
+	handlers  map[string][]EventHandler
+	listeners map[int]EventHandler
+	lastId    int
+	feeds     map[string]context.Context
+	mx        sync.Mutex
+}
+
+func NewPaperExchange(p *Provider) (Exchange, error) {
+	paperExchangesMx.Lock()
+	defer paperExchangesMx.Unlock()
+
+	// A changed provider configuration replaces the account, the state gets
+	// loaded again
+	if e, ok := paperExchanges[p.Name]; ok && reflect.DeepEqual(e.provider, p) {
+		return e, nil
+	}
+
+	e := NewPaperAccount(p)
+	e.persist = true
+
+	state := p.State
+	if state == "" {
+		dir, _ := os.Getwd()
+		state = path.Join(dir, "data", "paper", p.Name+".json")
+	}
+
+	e.Config = config.NewConfig()
+	e.Config.Silent = true
+	e.Config.SetContext(e)
+	e.Config.Load(state)
+
+	paperExchanges[p.Name] = e
+
+	return e, nil
+}
+
+// NewPaperAccount creates a new virtual account which will neither be
+// persisted nor shared.
+func NewPaperAccount(p *Provider) *PaperExchange {
+	e := &PaperExchange{
+		Config:    nil,
+		Sequence:  0,
+		Balances:  make(map[string]*values.Float),
+		Orders:    make([]*Order, 0),
+		provider:  p,
+		fee:       values.NewFloat(&p.Fee.Float),
+		persist:   false,
+		handlers:  make(map[string][]EventHandler),
+		listeners: make(map[int]EventHandler),
+		feeds:     make(map[string]context.Context),
+		mx:        sync.Mutex{},
+	}
+	for asset, b := range p.Balances {
+		e.Balances[asset] = values.NewFloat(&b.Float)
+	}
+	return e
+}
+
+func (e *PaperExchange) GetFilter(symbol string) (*Filter, error) {
+	return DefaultFilter(), nil
+}
+
+func (e *PaperExchange) GetBalances() (map[string]*values.Float, error) {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
+	balances := make(map[string]*values.Float)
+	for asset, b := range e.Balances {
+		balances[asset] = values.NewFloat(&b.Float)
+	}
+	return balances, nil
+}
+
+func (e *PaperExchange) GetOpenOrders(symbol string) ([]*Order, error) {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
+	// The jobs get copies, they keep changing the orders they track
+	orders := make([]*Order, 0)
+	for _, o := range e.Orders {
+		if o.Symbol == symbol {
+			c := *o
+			orders = append(orders, &c)
+		}
+	}
+	return orders, nil
+}
+
+func (e *PaperExchange) PlaceOrder(r *OrderRequest) (*Order, error) {
+	base, quote := e.splitSymbol(r.Symbol)
+
+	e.mx.Lock()
+	if r.ClientId != "" {
+		for _, o := range e.Orders {
+			if o.ClientId == r.ClientId {
+				e.mx.Unlock()
+				return nil, errors.New(fmt.Sprintf("duplicate client order id: %s", r.ClientId))
+			}
+		}
+	}
+
+	total := r.Amount.Mul(r.Price)
+	asset, required := quote, total
+	if r.Side == SideSell {
+		asset, required = base, r.Amount
+	}
+	if e.balance(asset).Lt(required) {
+		e.mx.Unlock()
+		return nil, errors.New(fmt.Sprintf("insufficient %s balance for requested action", asset))
+	}
+	e.Balances[asset] = e.balance(asset).Sub(required)
+
+	e.Sequence++
+	o := &Order{
+		Id:       strconv.FormatInt(e.Sequence, 10),
+		ClientId: r.ClientId,
+		Symbol:   r.Symbol,
+		Volume:   r.Amount,
+		Price:    r.Price,
+		Total:    total,
+		Fee:      values.NewEmptyFloat(),
+		Side:     r.Side,
+		Status:   StatusNew,
+		Date:     time.Now(),
+	}
+	e.Orders = append(e.Orders, o)
+	balances := e.balances(base, quote)
+	e.mx.Unlock()
+
+	e.save()
+	e.dispatch(r.Symbol, &Event{Type: EventNew, Symbol: r.Symbol, Order: o})
+	e.dispatch(r.Symbol, &Event{Type: EventBalance, Symbol: r.Symbol, Balance: balances})
+
+	c := *o
+	return &c, nil
+}
+
+func (e *PaperExchange) CancelOrder(symbol string, id string) error {
+	base, quote := e.splitSymbol(symbol)
+
+	e.mx.Lock()
+	o := e.remove(id)
+	if o == nil {
+		e.mx.Unlock()
+		return errors.New("unknown order")
+	}
+	if o.Side == SideSell {
+		e.Balances[base] = e.balance(base).Add(o.Volume)
+	} else {
+		e.Balances[quote] = e.balance(quote).Add(o.Total)
+	}
+	o.Status = StatusCanceled
+	balances := e.balances(base, quote)
+	e.mx.Unlock()
+
+	e.save()
+	e.dispatch(symbol, &Event{Type: EventCanceled, Symbol: symbol, Order: o})
+	e.dispatch(symbol, &Event{Type: EventBalance, Symbol: symbol, Balance: balances})
+
+	return nil
+}
+
+// GetTrades returns no trades, since the paper exchange delivers every event
+// in-process and none of them can get lost.
+func (e *PaperExchange) GetTrades(symbol string, since time.Time) ([]*Trade, error) {
+	return make([]*Trade, 0), nil
+}
+
+// Subscribe registers an event handler for the given symbol without
+// starting a price feed.
+func (e *PaperExchange) Subscribe(symbol string, handler EventHandler) {
+	e.mx.Lock()
+	e.handlers[symbol] = append(e.handlers[symbol], handler)
+	e.mx.Unlock()
+}
+
+// Watch registers an event handler for every symbol of the account until the
+// context gets canceled.
+func (e *PaperExchange) Watch(ctx context.Context, handler EventHandler) {
+	e.mx.Lock()
+	e.lastId++
+	id := e.lastId
+	e.listeners[id] = handler
+	e.mx.Unlock()
+
+	<-ctx.Done()
+
+	e.mx.Lock()
+	delete(e.listeners, id)
+	e.mx.Unlock()
+}
+
+// WatchSymbol starts the price feed of the given symbol, if it isn't running yet.
+func (e *PaperExchange) WatchSymbol(ctx context.Context, symbol string) {
+	e.mx.Lock()
+	running := e.feeds[symbol] != nil && e.feeds[symbol].Err() == nil
+	if !running {
+		e.feeds[symbol] = ctx
+	}
+	e.mx.Unlock()
+
+	if running || e.provider.Feed == "" {
+		return
+	}
+
+	switch e.provider.Feed {
+	case "binance":
+		go e.watchBinanceFeed(ctx, symbol)
+	default:
+		log.Error(fmt.Sprintf("%s unknown price feed: %s", strings.ToUpper(e.provider.Name), e.provider.Feed))
+	}
+}
+
+// Tick feeds a new market price into the matching engine. Every resting
+// order crossed by the price gets filled at its limit price.
+func (e *PaperExchange) Tick(symbol string, price *values.Float) {
+	base, quote := e.splitSymbol(symbol)
+
+	e.mx.Lock()
+	filled := make([]*Order, 0)
+	open := make([]*Order, 0)
+	for _, o := range e.Orders {
+		if o.Symbol == symbol && ((o.Side == SideBuy && !price.Gt(o.Price)) || (o.Side == SideSell && !price.Lt(o.Price))) {
+			filled = append(filled, o)
+		} else {
+			open = append(open, o)
+		}
+	}
+	if len(filled) == 0 {
+		e.mx.Unlock()
+		return
+	}
+	e.Orders = open
+
+	// Orders closest to the previous price get filled first
+	sort.SliceStable(filled, func(a, b int) bool {
+		if filled[a].Side == SideBuy && filled[b].Side == SideBuy {
+			return filled[a].Price.Gt(filled[b].Price)
+		}
+		return filled[a].Price.Lt(filled[b].Price)
+	})
+
+	for _, o := range filled {
+		if o.Side == SideBuy {
+			o.Fee = o.Volume.Div(values.HundredFloat).Mul(e.fee)
+			e.Balances[base] = e.balance(base).Add(o.Volume.Sub(o.Fee))
+		} else {
+			o.Fee = o.Total.Div(values.HundredFloat).Mul(e.fee)
+			e.Balances[quote] = e.balance(quote).Add(o.Total.Sub(o.Fee))
+		}
+		o.Status = StatusFilled
+		o.Date = time.Now()
+	}
+	balances := e.balances(base, quote)
+	e.mx.Unlock()
+
+	e.save()
+	for _, o := range filled {
+		e.dispatch(symbol, &Event{Type: EventFilled, Symbol: symbol, Order: o})
+	}
+	e.dispatch(symbol, &Event{Type: EventBalance, Symbol: symbol, Balance: balances})
+}
+
+func (e *PaperExchange) watchBinanceFeed(ctx context.Context, symbol string) {
+	for ctx.Err() == nil {
+		log.Success(fmt.Sprintf("Subscribing to %s %s price feed..", strings.ToUpper(e.provider.Name), symbol))
+		doneC, stopC, err := binance.WsTradeServe(symbol, func(evt *binance.WsTradeEvent) {
+			e.Tick(symbol, values.NewFloatFromString(evt.Price))
+		}, func(err error) {
+			log.Error(err)
+		})
+		if err != nil {
+			log.Error(err)
+			sleep(ctx, time.Second)
+			continue
+		}
+		select {
+		case <-doneC:
+		case <-ctx.Done():
+			close(stopC)
+			<-doneC
+		}
+	}
+
+	e.mx.Lock()
+	if e.feeds[symbol] == ctx {
+		delete(e.feeds, symbol)
+	}
+	e.mx.Unlock()
+}
+
+// dispatch delivers an event to every handler of the symbol. Each handler
+// gets its own copy of the order.
+func (e *PaperExchange) dispatch(symbol string, evt *Event) {
+	e.mx.Lock()
+	handlers := make([]EventHandler, 0)
+	for _, l := range e.listeners {
+		handlers = append(handlers, l)
+	}
+	handlers = append(handlers, e.handlers[symbol]...)
+	e.mx.Unlock()
+
+	for _, h := range handlers {
+		c := *evt
+		if evt.Order != nil {
+			o := *evt.Order
+			c.Order = &o
+		}
+		h(&c)
+	}
+}
+
+func (e *PaperExchange) save() {
+	if !e.persist {
+		return
+	}
+
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
+	if _, err := e.Config.Save(); err != nil {
+		log.Error(err)
+	}
+}
+
+func (e *PaperExchange) remove(id string) *Order {
+	for i, o := range e.Orders {
+		if o.Id == id {
+			e.Orders = append(e.Orders[:i], e.Orders[i+1:]...)
+			return o
+		}
+	}
+	return nil
+}
+
+func (e *PaperExchange) balance(asset string) *values.Float {
+	if b, ok := e.Balances[asset]; ok {
+		return b
+	}
+	return values.NewEmptyFloat()
+}
+
+func (e *PaperExchange) balances(assets ...string) map[string]*values.Float {
+	balances := make(map[string]*values.Float)
+	for _, asset := range assets {
+		balances[asset] = values.NewFloat(&e.balance(asset).Float)
+	}
+	return balances
+}
+
+// splitSymbol returns the base and quote asset of a symbol. Poloniex styled
+// symbols ("BTC_DOGE") list the quote asset first.
+func (e *PaperExchange) splitSymbol(symbol string) (string, string) {
+	s := strings.ToUpper(symbol)
+	if parts := strings.SplitN(s, "_", 2); len(parts) == 2 {
+		return parts[1], parts[0]
+	}
+	for _, sep := range []string{"-", "/"} {
+		if parts := strings.SplitN(s, sep, 2); len(parts) == 2 {
+			return parts[0], parts[1]
+		}
+	}
+
+	candidates := append([]string{}, paperQuoteAssets...)
+	e.mx.Lock()
+	for asset := range e.Balances {
+		candidates = append(candidates, strings.ToUpper(asset))
+	}
+	e.mx.Unlock()
+
+	for _, quote := range candidates {
+		if strings.HasSuffix(s, quote) && len(s) > len(quote) {
+			return strings.TrimSuffix(s, quote), quote
+		}
+	}
+	return s, ""
+}
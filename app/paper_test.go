@@ -0,0 +1,156 @@
+package app
+
+import (
+	"../utils/values"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestNewPaperExchangeShared(t *testing.T) {
+	dir, err := ioutil.TempDir("", "paper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	provider := func(fee string) *Provider {
+		return &Provider{
+			Name:     "paper-shared",
+			Exchange: "paper",
+			Fee:      *values.NewFloatFromString(fee),
+			Balances: map[string]*values.Float{"BTC": values.NewFloatFromString("1")},
+			State:    path.Join(dir, "paper-shared.json"),
+		}
+	}
+
+	a, _ := NewPaperExchange(provider("0.1"))
+	b, _ := NewPaperExchange(provider("0.1"))
+	if a != b {
+		t.Error("an equal provider got a new account")
+	}
+
+	c, _ := NewPaperExchange(provider("0.2"))
+	if c == a {
+		t.Fatal("a changed provider kept the previous account")
+	}
+	if fee := c.(*PaperExchange).fee.ToString(); fee != "0.20000000" {
+		t.Errorf("fee = %s, want 0.20000000", fee)
+	}
+	if d, _ := NewPaperExchange(provider("0.2")); d != c {
+		t.Error("the replaced account isn't shared")
+	}
+}
+
+func TestPaperTick(t *testing.T) {
+	// A buy of 10 DOGE at 9 and a sell of 10 DOGE at 11 rest on the book
+	tests := []struct {
+		name   string
+		prices []string
+		filled []string
+		doge   string
+		btc    string
+	}{
+		{"not crossed", []string{"10", "9.01", "10.99"}, []string{}, "90.00000000", "910.00000000"},
+		{"buy touched", []string{"9"}, []string{SideBuy}, "99.90000000", "910.00000000"},
+		{"buy crossed", []string{"8"}, []string{SideBuy}, "99.90000000", "910.00000000"},
+		{"sell touched", []string{"11"}, []string{SideSell}, "90.00000000", "1018.90000000"},
+		{"both", []string{"12", "8"}, []string{SideSell, SideBuy}, "99.90000000", "1018.90000000"},
+		{"filled once", []string{"8", "7"}, []string{SideBuy}, "99.90000000", "910.00000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewPaperAccount(&Provider{
+				Name:     "test",
+				Exchange: "paper",
+				Fee:      *values.NewFloatFromString("1"),
+				Balances: map[string]*values.Float{"DOGE": values.NewFloatFromString("100"), "BTC": values.NewFloatFromString("1000")},
+			})
+			filled := make([]string, 0)
+			e.Subscribe("DOGEBTC", func(evt *Event) {
+				if evt.Type == EventFilled {
+					filled = append(filled, evt.Order.Side)
+				}
+			})
+
+			for _, side := range []string{SideBuy, SideSell} {
+				price := "9"
+				if side == SideSell {
+					price = "11"
+				}
+				if _, err := e.PlaceOrder(&OrderRequest{Symbol: "DOGEBTC", Side: side, Price: values.NewFloatFromString(price), Amount: values.NewFloatFromString("10")}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, p := range tt.prices {
+				e.Tick("DOGEBTC", values.NewFloatFromString(p))
+			}
+
+			if !reflect.DeepEqual(filled, tt.filled) {
+				t.Errorf("filled = %v, want %v", filled, tt.filled)
+			}
+			orders, _ := e.GetOpenOrders("DOGEBTC")
+			if len(orders) != 2-len(tt.filled) {
+				t.Errorf("open orders = %d, want %d", len(orders), 2-len(tt.filled))
+			}
+			b, _ := e.GetBalances()
+			if b["DOGE"].ToString() != tt.doge || b["BTC"].ToString() != tt.btc {
+				t.Errorf("balances = %s DOGE, %s BTC, want %s DOGE, %s BTC", b["DOGE"].ToString(), b["BTC"].ToString(), tt.doge, tt.btc)
+			}
+		})
+	}
+}
+
+func TestPaperTickOrder(t *testing.T) {
+	// Crossed orders get filled closest to the previous price first
+	e := NewPaperAccount(&Provider{
+		Name:     "test",
+		Exchange: "paper",
+		Balances: map[string]*values.Float{"DOGE": values.NewFloatFromString("100"), "BTC": values.NewFloatFromString("1000")},
+	})
+	prices := make([]string, 0)
+	e.Subscribe("DOGEBTC", func(evt *Event) {
+		if evt.Type == EventFilled {
+			prices = append(prices, evt.Order.Price.ToString())
+		}
+	})
+
+	for _, p := range []string{"7", "9", "8"} {
+		e.PlaceOrder(&OrderRequest{Symbol: "DOGEBTC", Side: SideBuy, Price: values.NewFloatFromString(p), Amount: values.NewFloatFromString("1")})
+	}
+	e.Tick("DOGEBTC", values.NewFloatFromString("6"))
+
+	want := []string{"9.00000000", "8.00000000", "7.00000000"}
+	if !reflect.DeepEqual(prices, want) {
+		t.Errorf("fills = %v, want %v", prices, want)
+	}
+}
+
+func TestPaperCopies(t *testing.T) {
+	e := NewPaperAccount(&Provider{
+		Name:     "test",
+		Exchange: "paper",
+		Balances: map[string]*values.Float{"BTC": values.NewFloatFromString("1000")},
+	})
+	e.Subscribe("DOGEBTC", func(evt *Event) {
+		if evt.Order != nil {
+			evt.Order.Status = "changed"
+		}
+	})
+
+	placed, err := e.PlaceOrder(&OrderRequest{Symbol: "DOGEBTC", Side: SideBuy, Price: values.NewFloatFromString("9"), Amount: values.NewFloatFromString("1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	placed.Status = "changed"
+	orders, _ := e.GetOpenOrders("DOGEBTC")
+	orders[0].Status = "changed"
+
+	// Neither the handlers nor the callers change the account
+	if orders, _ := e.GetOpenOrders("DOGEBTC"); orders[0].Status != StatusNew {
+		t.Errorf("status = %s, want %s", orders[0].Status, StatusNew)
+	}
+}
@@ -0,0 +1,165 @@
+package app
+
+import (
+	"../utils/log"
+	"../utils/values"
+	"fmt"
+	"strings"
+)
+
+const (
+	// PartialAggregate counters all partial fills of an order at once, as
+	// soon as the order got filled or canceled.
+	PartialAggregate = "aggregate"
+	// PartialEach counters every partial fill which reaches the minimum
+	// notional of the symbol.
+	PartialEach = "each"
+)
+
+// handleFill counters a filled order. Only the volume which wasn't countered
+// by a partial fill before is left.
+func (j *Job) handleFill(o *Order) {
+	countered := j.counteredVolume(o.Id)
+	if countered.Sign() == 0 {
+		j.counter(o, o.Id, o.Volume)
+		return
+	}
+
+	volume := o.Volume.Sub(countered)
+	if volume.Sign() > 0 {
+		j.counter(o, o.Id, volume)
+	}
+}
+
+// handlePartial tracks the executed volume of an order. Depending on the
+// partial fill policy of the job the executed volume gets countered right
+// away.
+func (j *Job) handlePartial(o *Order) {
+	if o.Executed == nil {
+		return
+	}
+
+	j.mx.Lock()
+	tracked, ok := j.orders[o.Id]
+	if !ok || (tracked.Executed != nil && !o.Executed.Gt(tracked.Executed)) {
+		// Unknown order or an update which was received already
+		j.mx.Unlock()
+		return
+	}
+	tracked.setExecuted(o.Executed)
+	countered := values.NewEmptyFloat()
+	if tracked.Countered != nil {
+		countered = tracked.Countered
+	}
+	j.mx.Unlock()
+
+	log.Info(fmt.Sprintf("%s ORDER PARTIALLY FILLED: %s %.8f of %.8f", strings.ToUpper(j.Provider.Name), o.Id, tracked.Executed.ToFloat(), tracked.Volume.ToFloat()))
+
+	if j.Partial == PartialEach {
+		// The rest of the order has to be large enough to be countered later on
+		volume := tracked.Executed.Sub(countered)
+		if j.counterable(volume, tracked.Price) && j.counterable(tracked.Remaining, tracked.Price) {
+			j.mx.Lock()
+			tracked.Countered = tracked.Executed
+			j.mx.Unlock()
+
+			j.counter(tracked, fmt.Sprintf("%s:%s", o.Id, tracked.Executed.ToString()), volume)
+		}
+	}
+
+	j.saveOpenOrders()
+}
+
+// counterRemainder counters the executed volume of a canceled order which
+// wasn't countered yet. It reports whether a counter order got placed, which
+// removes the canceled order on completion.
+func (j *Job) counterRemainder(o *Order) bool {
+	tracked, err := j.GetOrder(o.Id)
+	if err != nil {
+		return false
+	}
+
+	j.mx.Lock()
+	executed := tracked.Executed
+	if o.Executed != nil && (executed == nil || o.Executed.Gt(executed)) {
+		executed = o.Executed
+	}
+	countered := values.NewEmptyFloat()
+	if tracked.Countered != nil {
+		countered = tracked.Countered
+	}
+	j.mx.Unlock()
+
+	if executed == nil || !executed.Gt(countered) {
+		return false
+	}
+
+	volume := executed.Sub(countered)
+	if !j.counterable(volume, tracked.Price) {
+		log.Warn(fmt.Sprintf("%s PARTIAL FILL TOO SMALL TO COUNTER: %s %.8f", strings.ToUpper(j.Provider.Name), o.Id, volume.ToFloat()))
+		return false
+	}
+
+	// The counter order of the remainder completes the order
+	fill := *tracked
+	fill.Status = StatusFilled
+	return j.counter(&fill, o.Id, volume)
+}
+
+// counter places the counter order for the given executed volume of an
+// order. Every part of an order is countered once by its id.
+func (j *Job) counter(o *Order, id string, volume *values.Float) bool {
+	if !j.markFilled(id) {
+		log.Debug(fmt.Sprintf("%s ORDER ALREADY FILLED: %s", strings.ToUpper(j.Provider.Name), id))
+		return false
+	}
+
+	fill := o
+	var share *values.Float
+	if !volume.Eq(o.Volume) {
+		share = volume.Div(o.Volume)
+
+		status := StatusFilled
+		if id != o.Id {
+			status = StatusPartial
+		}
+		fill = &Order{
+			Id:     id,
+			Symbol: o.Symbol,
+			Volume: volume,
+			Price:  o.Price,
+			Total:  volume.Mul(o.Price),
+			Side:   o.Side,
+			Status: status,
+			Date:   o.Date,
+		}
+	}
+
+	if fill.Side == SideBuy {
+		j.placeSellOrder(fill)
+	} else if fill.Side == SideSell {
+		j.placeBuyOrder(fill, share)
+	}
+	return true
+}
+
+// counteredVolume returns the volume of an order which got countered by
+// partial fills.
+func (j *Job) counteredVolume(id string) *values.Float {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	if o, ok := j.orders[id]; ok && o.Countered != nil {
+		return o.Countered
+	}
+	return values.NewEmptyFloat()
+}
+
+// counterable reports whether an order of the given volume passes the lot
+// size and the minimum notional of the symbol.
+func (j *Job) counterable(volume *values.Float, price *values.Float) bool {
+	if !j.validateAmount(volume).Gt(values.ZeroFloat) {
+		return false
+	}
+	return !volume.Mul(price).Lt(j.getFilter().MinNotional)
+}
@@ -0,0 +1,104 @@
+package app
+
+import (
+	"../utils/values"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestPartialFillPolicies(t *testing.T) {
+	type step struct {
+		typ      EventType
+		executed string
+	}
+	partial := func(executed string) step { return step{EventPartial, executed} }
+	filled := step{EventFilled, "30"}
+	canceled := func(executed string) step { return step{EventCanceled, executed} }
+
+	// A buy order of 30 at 10 gets countered by sell orders at 11, the
+	// minimum notional is 50
+	tests := []struct {
+		name    string
+		policy  string
+		steps   []step
+		sells   []string
+		tracked bool
+	}{
+		{"aggregate filled", PartialAggregate, []step{partial("10"), partial("20"), filled}, []string{"30.00000000"}, false},
+		{"aggregate canceled", PartialAggregate, []step{partial("12"), canceled("12")}, []string{"12.00000000"}, false},
+		{"aggregate canceled unfilled", PartialAggregate, []step{canceled("0")}, []string{}, false},
+		{"each filled", PartialEach, []step{partial("10"), partial("20"), filled}, []string{"10.00000000", "10.00000000", "10.00000000"}, false},
+		{"each below minimum", PartialEach, []step{partial("10"), partial("12"), filled}, []string{"10.00000000", "20.00000000"}, false},
+		{"each remainder below minimum", PartialEach, []step{partial("26"), filled}, []string{"30.00000000"}, false},
+		{"each repeated update", PartialEach, []step{partial("10"), partial("10"), partial("5")}, []string{"10.00000000"}, true},
+		{"each canceled", PartialEach, []step{partial("10"), partial("15"), canceled("15")}, []string{"10.00000000", "5.00000000"}, false},
+		{"each canceled too small", PartialEach, []step{partial("10"), canceled("12")}, []string{"10.00000000"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "partial")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			j := newTestJob("0")
+			j.OrderDir = dir
+			j.Partial = tt.policy
+			f := DefaultFilter()
+			f.MinNotional = values.NewFloatFromString("50")
+			j.setFilter(f)
+			ex := NewPaperAccount(&Provider{
+				Name:     "test",
+				Exchange: "paper",
+				Balances: map[string]*values.Float{"DOGE": values.NewFloatFromString("100")},
+			})
+			j.Exchange = ex
+
+			j.AttachOrder(&Order{
+				Id:     "b1",
+				Symbol: j.Symbol,
+				Volume: values.NewFloatFromString("30"),
+				Price:  values.NewFloatFromString("10"),
+				Side:   SideBuy,
+				Status: StatusNew,
+			})
+
+			for _, s := range tt.steps {
+				o := &Order{
+					Id:       "b1",
+					Symbol:   j.Symbol,
+					Volume:   values.NewFloatFromString("30"),
+					Price:    values.NewFloatFromString("10"),
+					Side:     SideBuy,
+					Executed: values.NewFloatFromString(s.executed),
+				}
+				j.handleEvent(&Event{Type: s.typ, Symbol: j.Symbol, Order: o})
+			}
+
+			orders, _ := ex.GetOpenOrders(j.Symbol)
+			sells := make([]string, 0)
+			for _, o := range orders {
+				if o.Side != SideSell || o.Price.ToString() != "11.00000000" {
+					t.Errorf("counter order = %s @ %s", o.Side, o.Price.ToString())
+				}
+				sells = append(sells, o.Volume.ToString())
+			}
+			if len(sells) != len(tt.sells) {
+				t.Fatalf("sells = %v, want %v", sells, tt.sells)
+			}
+			for i := range sells {
+				if sells[i] != tt.sells[i] {
+					t.Errorf("sells = %v, want %v", sells, tt.sells)
+					break
+				}
+			}
+
+			if _, err := j.GetOrder("b1"); (err == nil) != tt.tracked {
+				t.Errorf("tracked = %v, want %v", err == nil, tt.tracked)
+			}
+		})
+	}
+}
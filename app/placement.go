@@ -0,0 +1,248 @@
+package app
+
+import (
+	"../utils/log"
+	"errors"
+	"fmt"
+	"github.com/adshao/go-binance/v2/common"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type errorClass string
+
+const (
+	errorUnknown   = errorClass("unknown")
+	errorRateLimit = errorClass("rate limit")
+	errorTimestamp = errorClass("timestamp")
+	errorTransient = errorClass("transient")
+	errorBalance   = errorClass("insufficient balance")
+	errorFilter    = errorClass("filter failure")
+	errorDuplicate = errorClass("duplicate")
+	errorBounds    = errorClass("out of bounds")
+	errorQueueFull = errorClass("queue full")
+)
+
+var (
+	// Unknown errors get retried this many times before the order gets parked
+	placementAttempts = 5
+	// Rate limits, timestamp and transient errors get retried for about 25
+	// minutes before the order gets parked
+	placementRetries = 12
+
+	placementBackoff    = time.Second
+	placementMaxBackoff = 5 * time.Minute
+
+	httpRateLimit   = regexp.MustCompile(`^(429|418) `)
+	httpServerError = regexp.MustCompile(`^5\d\d `)
+
+	// Error messages of all supported exchanges, matched in lower case
+	errorPatterns = []struct {
+		class    errorClass
+		patterns []string
+	}{
+		{errorDuplicate, []string{"duplicat"}},
+		{errorBalance, []string{"insufficient", "not enough"}},
+		{errorFilter, []string{"filter failure", "min_notional", "lot_size", "price_filter", "minimum not met", "too small", "invalid quantity", "invalid price", "precision", "decimal"}},
+		{errorTimestamp, []string{"timestamp", "recvwindow", "recv_window", "invalid nonce"}},
+		{errorRateLimit, []string{"too many", "rate limit"}},
+		{errorTransient, []string{"service unavailable", "eservice:", "internal error", "system busy", "timeout", "temporarily", "connection reset"}},
+	}
+)
+
+// classifyError returns the error class of a failed order placement.
+func classifyError(err error) errorClass {
+	var apiErr *common.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.Code {
+		case -1003, -1015:
+			return errorRateLimit
+		case -1021:
+			return errorTimestamp
+		case -1013:
+			return errorFilter
+		case -1000, -1001, -1006, -1007:
+			return errorTransient
+		case 0:
+			// The response body wasn't an api error, such as a 5xx html page
+			return errorTransient
+		}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return errorTransient
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, p := range errorPatterns {
+		for _, pattern := range p.patterns {
+			if strings.Contains(msg, pattern) {
+				return p.class
+			}
+		}
+	}
+	if httpRateLimit.MatchString(msg) {
+		return errorRateLimit
+	}
+	if httpServerError.MatchString(msg) {
+		return errorTransient
+	}
+	return errorUnknown
+}
+
+// retryable reports whether a placement which failed with the given error
+// class should be tried again.
+func (c errorClass) retryable(attempts int) bool {
+	switch c {
+	case errorRateLimit, errorTimestamp, errorTransient:
+		return attempts < placementRetries
+	case errorUnknown:
+		return attempts < placementAttempts
+	}
+	return false
+}
+
+// backoff returns the delay before the given attempt, doubled per attempt.
+func backoff(attempts int) time.Duration {
+	d := placementBackoff
+	for i := 1; i < attempts && d < placementMaxBackoff; i++ {
+		d *= 2
+	}
+	if d > placementMaxBackoff {
+		d = placementMaxBackoff
+	}
+	return d
+}
+
+// runQueue places the queued counter orders one after another. Once the job
+// got stopped, the orders which are already queued get placed before it
+// returns. The queue gets closed as soon as it's empty, later intents are
+// left inside the journal.
+func (j *Job) runQueue() {
+	for {
+		select {
+		case in := <-j.queue:
+			j.place(in)
+			j.work.Done()
+		case <-j.ctx.Done():
+			for {
+				select {
+				case in := <-j.queue:
+					j.place(in)
+					j.work.Done()
+				default:
+					// execute holds the lock while queueing, the queue
+					// can't fill up once it got closed
+					j.queueMx.Lock()
+					if len(j.queue) == 0 {
+						j.queueClosed = true
+						j.queueMx.Unlock()
+						return
+					}
+					j.queueMx.Unlock()
+				}
+			}
+		}
+	}
+}
+
+// place tries to place the counter order of an intent until it either got
+// placed or has to be parked.
+func (j *Job) place(in *Intent) {
+	r := in.Request
+	if err := j.checkBounds(r.Price); err != nil {
+		j.park(in, errorBounds, err)
+		return
+	}
+
+	for {
+		order, err := j.Exchange.PlaceOrder(r)
+		if err == nil {
+			j.complete(in, order)
+			return
+		}
+
+		in.Attempts++
+		class := classifyError(err)
+
+		log.Debug(j.Provider.Name, in.Fill.Id, r.Side, r.Price.ToString(), r.Amount.ToString(), r.Price.Mul(r.Amount).ToString())
+		log.Error(err)
+
+		if class == errorDuplicate {
+			// The counter order was placed already
+			log.Warn(fmt.Sprintf("%s ORDER ALREADY PLACED: %s", strings.ToUpper(j.Provider.Name), r.ClientId))
+			j.finish(in)
+			return
+		}
+		if !class.retryable(in.Attempts) {
+			j.park(in, class, err)
+			return
+		}
+		if s, ok := j.Exchange.(ClockSyncer); ok && class == errorTimestamp {
+			if err := s.SyncClock(); err != nil {
+				log.Error(err)
+			}
+		}
+
+		d := backoff(in.Attempts)
+		log.Warn(fmt.Sprintf("%s %s, retrying in %s..", strings.ToUpper(j.Provider.Name), class, d))
+		if !sleep(j.ctx, d) {
+			// The intent stays inside the journal and gets replayed on the next start
+			log.Warn(fmt.Sprintf("%s ORDER POSTPONED: %s", strings.ToUpper(j.Provider.Name), in.Fill.Id))
+			return
+		}
+	}
+}
+
+// park keeps a counter order which can't be placed right now inside the
+// journal. Parked orders get retried at every full hour and on startup. A
+// notification is sent when an order gets parked for a new reason, retries
+// failing for the same reason again stay silent.
+func (j *Job) park(in *Intent, class errorClass, err error) {
+	r := in.Request
+
+	changed := in.Reason != string(class)
+	in.Parked = true
+	in.Reason = string(class)
+	in.Error = err.Error()
+	j.journal.Add(in)
+
+	log.Warn(fmt.Sprintf("%s ORDER PARKED: %s %.8f @ %.8f (%s)", strings.ToUpper(j.Provider.Name), r.Side, r.Amount.ToFloat(), r.Price.ToFloat(), class))
+	if !changed {
+		return
+	}
+
+	text := fmt.Sprintf("#### %s %s order parked on %s\n", strings.ToUpper(r.Side), strings.ToUpper(j.Symbol), strings.ToUpper(j.Provider.Name))
+	text = text + `
+| Price | Amount | Reason | Error |
+|:------|:-------|:-------|:------|`
+	text = text + fmt.Sprintf("\n| %.8f | %.8f | %s | %s |", r.Price.ToFloat(), r.Amount.ToFloat(), class, in.Error)
+	j.Notify(text)
+}
+
+// parkedOrders returns all parked counter orders.
+func (j *Job) parkedOrders() []*Intent {
+	parked := make([]*Intent, 0)
+	for _, in := range j.journal.Pending() {
+		if in.Parked {
+			parked = append(parked, in)
+		}
+	}
+	return parked
+}
+
+// retryParked queues all parked counter orders again. Orders outside of the
+// price band wait for the market to come back.
+func (j *Job) retryParked() {
+	for _, in := range j.parkedOrders() {
+		if j.checkBounds(in.Request.Price) != nil {
+			continue
+		}
+		log.Info(fmt.Sprintf("%s RETRYING PARKED ORDER: %s", strings.ToUpper(j.Provider.Name), in.Fill.Id))
+		j.execute(in)
+	}
+}
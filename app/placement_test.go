@@ -0,0 +1,194 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/adshao/go-binance/v2/common"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err   error
+		class errorClass
+	}{
+		{&common.APIError{Code: -1003, Message: "Too much request weight used."}, errorRateLimit},
+		{&common.APIError{Code: -1021, Message: "Timestamp for this request is outside of the recvWindow."}, errorTimestamp},
+		{&common.APIError{Code: -1013, Message: "Filter failure: LOT_SIZE"}, errorFilter},
+		{&common.APIError{Code: -1001, Message: "Internal error; unable to process your request."}, errorTransient},
+		{&common.APIError{Code: 0, Message: "<html>502 Bad Gateway</html>"}, errorTransient},
+		{&common.APIError{Code: -2010, Message: "Account has insufficient balance for requested action."}, errorBalance},
+		{&common.APIError{Code: -2010, Message: "Duplicate order sent."}, errorDuplicate},
+		{fmt.Errorf("place order: %w", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}), errorTransient},
+		{io.ErrUnexpectedEOF, errorTransient},
+		{errors.New("EOrder:Insufficient funds"), errorBalance},
+		{errors.New("EGeneral:Invalid arguments:volume minimum not met"), errorFilter},
+		{errors.New("EAPI:Invalid nonce"), errorTimestamp},
+		{errors.New("EService:Unavailable"), errorTransient},
+		{errors.New("51008: Order failed. Insufficient balance."), errorBalance},
+		{errors.New("50011: Rate limit reached."), errorRateLimit},
+		{errors.New("duplicate client order id: abc"), errorDuplicate},
+		{errors.New("429 Too Many Requests"), errorRateLimit},
+		{errors.New("418 I'm a teapot"), errorRateLimit},
+		{errors.New("502 Bad Gateway"), errorTransient},
+		{errors.New("invalid api key"), errorUnknown},
+	}
+
+	for _, tt := range tests {
+		if class := classifyError(tt.err); class != tt.class {
+			t.Errorf("classifyError(%q) = %s, want %s", tt.err, class, tt.class)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		class     errorClass
+		attempts  int
+		retryable bool
+	}{
+		{errorRateLimit, placementRetries - 1, true},
+		{errorRateLimit, placementRetries, false},
+		{errorTimestamp, placementRetries - 1, true},
+		{errorTimestamp, placementRetries, false},
+		{errorTransient, placementRetries - 1, true},
+		{errorTransient, placementRetries, false},
+		{errorUnknown, placementAttempts - 1, true},
+		{errorUnknown, placementAttempts, false},
+		{errorBalance, 1, false},
+		{errorFilter, 1, false},
+		{errorBounds, 1, false},
+	}
+
+	for _, tt := range tests {
+		if r := tt.class.retryable(tt.attempts); r != tt.retryable {
+			t.Errorf("%s after %d attempts: retryable = %v, want %v", tt.class, tt.attempts, r, tt.retryable)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		delay    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{5, 16 * time.Second},
+		{9, 256 * time.Second},
+		{10, 5 * time.Minute},
+		{100, 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if d := backoff(tt.attempts); d != tt.delay {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempts, d, tt.delay)
+		}
+	}
+}
+
+func TestQueueClosed(t *testing.T) {
+	j := newTestJob("0")
+	j.Exchange = NewPaperAccount(&Provider{Name: "test", Exchange: "paper"})
+	ctx, cancel := context.WithCancel(context.Background())
+	j.ctx = ctx
+	j.queue = make(chan *Intent, 4)
+
+	// Queued before the job got stopped, gets placed while draining
+	j.execute(newTestIntent("1", time.Now()))
+	cancel()
+	j.runQueue()
+
+	// Queued after the queue got drained, stays inside the journal
+	j.execute(newTestIntent("2", time.Now()))
+
+	done := make(chan struct{})
+	go func() {
+		j.work.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("work left after the queue got closed")
+	}
+
+	pending := j.journal.Pending()
+	if len(pending) != 2 {
+		t.Fatalf("pending = %d, want 2", len(pending))
+	}
+	// The paper account has no balance, the first order got parked
+	if !pending[0].Parked || pending[1].Parked {
+		t.Errorf("parked = %v, %v, want true, false", pending[0].Parked, pending[1].Parked)
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	j := newTestJob("0")
+	j.Exchange = NewPaperAccount(&Provider{Name: "test", Exchange: "paper"})
+	j.ctx = context.Background()
+	j.queue = make(chan *Intent, 1)
+
+	// Nothing drains the queue, the second intent mustn't block the caller
+	done := make(chan struct{})
+	go func() {
+		j.execute(newTestIntent("1", time.Now()))
+		j.execute(newTestIntent("2", time.Now()))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("execute blocked on a full queue")
+	}
+
+	if len(j.queue) != 1 {
+		t.Errorf("queued = %d, want 1", len(j.queue))
+	}
+	if parked := j.parkedOrders(); len(parked) != 1 || parked[0].Reason != string(errorQueueFull) {
+		t.Errorf("parked = %v, want the second intent", parked)
+	}
+}
+
+// clockExchange rejects the timestamp of the first orders.
+type clockExchange struct {
+	*PaperExchange
+	rejects int
+	synced  int
+}
+
+func (e *clockExchange) PlaceOrder(r *OrderRequest) (*Order, error) {
+	if e.rejects > 0 {
+		e.rejects--
+		return nil, errors.New("10002: invalid request, please check your server timestamp or recv_window param")
+	}
+	return e.PaperExchange.PlaceOrder(r)
+}
+
+func (e *clockExchange) SyncClock() error {
+	e.synced++
+	return nil
+}
+
+func TestPlaceSyncsClock(t *testing.T) {
+	defer func(d time.Duration) { placementBackoff = d }(placementBackoff)
+	placementBackoff = time.Millisecond
+
+	j, paper := newBoundsJob(t, "100")
+	ex := &clockExchange{PaperExchange: paper, rejects: 2}
+	j.Exchange = ex
+	j.ctx = context.Background()
+
+	j.execute(newTestIntent("1", time.Now()))
+
+	if ex.synced != 2 {
+		t.Errorf("synced = %d, want 2", ex.synced)
+	}
+	if orders, _ := paper.GetOpenOrders(j.Symbol); len(orders) != 1 {
+		t.Errorf("open orders = %d, want 1", len(orders))
+	}
+}
@@ -4,197 +4,298 @@ import (
 	"../api/poloniex"
 	"../utils/log"
 	"../utils/values"
+	"context"
+	"errors"
 	"fmt"
-	"github.com/mitchellh/mapstructure"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
-func (j *Job) parsePolOpenOrders(orders []*poloniex.OpenOrder) {
+func init() {
+	RegisterExchange("poloniex", NewPoloniexExchange)
+}
+
+// PoloniexExchange implements neither OrderGetter nor ClientOrderGetter,
+// Poloniex only returns open orders. Missed fills get recovered from the
+// trade history, a replayed counter order which is still open gets rejected
+// due to its client order id. There is no Clock either, requests carry a
+// nonce which only has to increase.
+type PoloniexExchange struct {
+	provider *Provider
+	client   *poloniex.Config
+
+	// Poloniex trade updates only contain the order number and the remaining
+	// amount. All known orders are kept in order to emit complete events.
+	orders map[int64]*Order
+	// Poloniex only accepts numeric client order ids, the client ids of
+	// placed orders are kept by their numeric id.
+	clientIds map[int64]string
+	mx        sync.Mutex
+}
+
+func NewPoloniexExchange(p *Provider) (Exchange, error) {
+	client := p.NewPoloniexClient()
+	if client == nil {
+		return nil, errors.New(fmt.Sprintf("%s client could not be created", strings.ToUpper(p.Name)))
+	}
+
+	return &PoloniexExchange{
+		provider:  p,
+		client:    client,
+		orders:    make(map[int64]*Order),
+		clientIds: make(map[int64]string),
+		mx:        sync.Mutex{},
+	}, nil
+}
+
+func (e *PoloniexExchange) GetFilter(symbol string) (*Filter, error) {
+	if e.client.GetPair(symbol) == nil {
+		return nil, errors.New(fmt.Sprintf("unknown symbol: %s", symbol))
+	}
+	return DefaultFilter(), nil
+}
+
+func (e *PoloniexExchange) GetPrice(symbol string) (*values.Float, error) {
+	pairs, err := e.client.GetTicker()
+	if err != nil {
+		return nil, err
+	}
+	if pair, ok := pairs[symbol]; ok {
+		return values.NewFloat(&pair.Last.Float), nil
+	}
+	return nil, errors.New(fmt.Sprintf("unknown symbol: %s", symbol))
+}
+
+func (e *PoloniexExchange) GetBalances() (map[string]*values.Float, error) {
+	return e.client.GetBalances()
+}
+
+func (e *PoloniexExchange) GetOpenOrders(symbol string) ([]*Order, error) {
+	orders, err := e.client.GetOpenOrders(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Order, 0)
 	for _, o := range orders {
-		j.AttachPolOrder(o)
+		result = append(result, e.remember(symbol, o))
+	}
+	return result, nil
+}
+
+func (e *PoloniexExchange) PlaceOrder(r *OrderRequest) (*Order, error) {
+	pf, _ := r.Price.Float64()
+	amt, _ := r.Amount.Float64()
+
+	// The new order event might arrive before the order got placed
+	cid := e.clientOrderId(r.ClientId)
+
+	var to poloniex.TradeOrder
+	var err error
+	if r.Side == SideSell {
+		to, err = e.client.Sell(r.Symbol, pf, amt, cid)
+	} else {
+		to, err = e.client.Buy(r.Symbol, pf, amt, cid)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return e.remember(r.Symbol, &poloniex.OpenOrder{
+		OrderNumber:   to.Number,
+		Type:          r.Side,
+		Rate:          *r.Price,
+		Amount:        *r.Amount,
+		ClientOrderId: cid,
+	}), nil
+}
+
+func (e *PoloniexExchange) CancelOrder(symbol string, id string) error {
+	num, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return err
+	}
+	if err := e.client.CancelOrder(num); err != nil {
+		return err
+	}
+	e.forget(num)
+	return nil
+}
+
+func (e *PoloniexExchange) GetTrades(symbol string, since time.Time) ([]*Trade, error) {
+	trades, err := e.client.GetTradeHistory(symbol, since)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Trade, 0)
+	for _, t := range trades {
+		date, _ := time.Parse("2006-01-02 15:04:05", t.Date)
+		result = append(result, &Trade{
+			Id:      strconv.FormatInt(t.TradeID, 10),
+			OrderId: strconv.FormatInt(t.OrderNumber, 10),
+			Symbol:  symbol,
+			Side:    t.Type,
+			Price:   values.NewFloat(&t.Rate.Float),
+			Volume:  values.NewFloat(&t.Amount.Float),
+			Date:    date,
+		})
 	}
+	return result, nil
 }
 
-func (j *Job) parsePolTradeOrdersUpdate(arr []interface{}) (poloniex.OrderBookUpd, error) {
-	var tradeOrder poloniex.OrderBookUpd
-	toDecode := []interface{}{&tradeOrder.Price, &tradeOrder.Size}
-	obookDec, _ := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
-		Result:     &toDecode,
-		DecodeHook: orderBookDecodeHook,
-	})
-	return tradeOrder, obookDec.Decode(arr)
+// clientOrderId returns the numeric client order id of a client id and
+// remembers the client id. Client ids shorter than 15 characters aren't sent.
+func (e *PoloniexExchange) clientOrderId(clientId string) int64 {
+	if len(clientId) < 15 {
+		return 0
+	}
+	cid, err := strconv.ParseInt(clientId[:15], 16, 64)
+	if err != nil {
+		return 0
+	}
+
+	e.mx.Lock()
+	e.clientIds[cid] = clientId
+	e.mx.Unlock()
+
+	return cid
 }
 
-func (j *Job) AttachPolOrder(o *poloniex.OpenOrder) {
-	j.mx.Lock()
+func (e *PoloniexExchange) remember(symbol string, o *poloniex.OpenOrder) *Order {
 	if o.Total.ToFloat() <= 0 {
 		o.Total = *o.Amount.Mul(&o.Rate)
 	}
-	if _, ok := j.orders[o.OrderNumber]; !ok {
-		j.orders[o.OrderNumber] = &Order{
-			Id:     o.OrderNumber,
-			Volume: &o.Amount,
-			Price:  &o.Rate,
-			Total:  &o.Total,
-			Fee:    o.Total.Div(values.HundredFloat).Mul(&j.Fee),
-			Side:   o.Type,
-			Status: "",
-			Date:   time.Time{},
-		}
-		log.Success(fmt.Sprintf("%s ORDER REGISTERED: %d", strings.ToUpper(j.Provider.Name), o.OrderNumber))
+
+	order := &Order{
+		Id:     strconv.FormatInt(o.OrderNumber, 10),
+		Symbol: symbol,
+		Volume: values.NewFloat(&o.Amount.Float),
+		Price:  values.NewFloat(&o.Rate.Float),
+		Total:  values.NewFloat(&o.Total.Float),
+		Fee:    values.NewEmptyFloat(),
+		Side:   o.Type,
+		Status: StatusNew,
+		Date:   time.Now(),
 	}
-	j.mx.Unlock()
+
+	e.mx.Lock()
+	if o.ClientOrderId > 0 {
+		order.ClientId = e.clientIds[o.ClientOrderId]
+	}
+	if prev, ok := e.orders[o.OrderNumber]; ok && order.ClientId == "" {
+		order.ClientId = prev.ClientId
+	}
+	e.orders[o.OrderNumber] = order
+	e.mx.Unlock()
+
+	return order
 }
 
-func (j *Job) HandlePolTradeOrder(to *poloniex.TradeOrder) {
-	o, err := j.GetOrder(to.Number)
-	if err != nil {
-		log.Error(fmt.Sprintf("%s ORDER NOT RELATED: %d", strings.ToUpper(j.Provider.Name), to.Number))
+func (e *PoloniexExchange) forget(num int64) *Order {
+	e.mx.Lock()
+	o, ok := e.orders[num]
+	delete(e.orders, num)
+	if ok && len(o.ClientId) >= 15 {
+		cid, _ := strconv.ParseInt(o.ClientId[:15], 16, 64)
+		delete(e.clientIds, cid)
+	}
+	e.mx.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return o
+}
+
+func (e *PoloniexExchange) lookup(num int64) *Order {
+	e.mx.Lock()
+	o := e.orders[num]
+	e.mx.Unlock()
+	return o
+}
+
+func (e *PoloniexExchange) handleTradeOrder(to *poloniex.TradeOrder, handler EventHandler) {
+	o := e.lookup(to.Number)
+	if o == nil {
+		log.Debug(fmt.Sprintf("%s ORDER NOT RELATED: %d", strings.ToUpper(e.provider.Name), to.Number))
 		return
 	}
 
 	filled := to.Amount.Eq(values.ZeroFloat) || to.Amount.Lt(values.ZeroFloat)
 
 	if (to.Type == "f" || to.Type == "s") && filled {
-		// Order is fulfilled
-		// Order is known
-		if o.Side == "sell" {
-			// create a buy order
-			price := o.Price.Sub(j.getStep("buy"))
-
-			amount := j.Volume.Div(price)
-			total := price.Mul(amount)
-
-			diff := o.Total.Sub(total)
-
-			dif, _ := diff.Float64()
-			pf, _ := price.Float64()
-			amt, _ := amount.Float64()
-			tot, _ := total.Float64()
-
-			to, err := j.PoloniexClient.Buy(j.Symbol, pf, amt)
-			if err != nil {
-				log.Error(err)
-				log.Warn("Idle and try again..")
-				time.Sleep(time.Second)
-
-				to, err = j.PoloniexClient.Buy(j.Symbol, pf, amt)
-				if err != nil {
-					log.Debug(j.Provider, o.Id, "buy", pf, amount, tot)
-					log.Error(err)
-					return
-				}
-			}
-			log.Success(fmt.Sprintf("%s ORDER CREATED: %d", strings.ToUpper(j.Provider.Name), to.Number))
-
-
-			sellFee := o.Volume.Div(values.HundredFloat).Mul(&j.Fee)
-
-			go j.SaveOrder(&Order{
-				Id:     o.Id,
-				Volume: o.Volume,
-				Price:  o.Price,
-				Total:  o.Total,
-				Fee:    sellFee,
-				Side:   "sell",
-				Status: "filled",
-				Date:   time.Now(),
-			})
-
-			go j.NotifyOrder(pf, amt, tot, dif, "buy")
-		} else {
-			// create a sell order
-			price := o.Price.Add(j.getStep("sell"))
-
-			fee := o.Volume.Div(values.HundredFloat).Mul(&j.Fee)
-			amount := o.Volume.Sub(fee)
-			total := price.Mul(amount)
-			diff := total.Sub(o.Total)
-
-			pf, _ := price.Float64()
-			amt, _ := amount.Float64()
-			tot, _ := total.Float64()
-			dif, _ := diff.Float64()
-
-			to, err := j.PoloniexClient.Sell(j.Symbol, pf, amt)
-			if err != nil {
-				log.Error(err)
-				log.Warn("Idle and try again..")
-				time.Sleep(time.Second)
-
-				to, err = j.PoloniexClient.Sell(j.Symbol, pf, amt)
-				if err != nil {
-					log.Error(err)
-					log.Debug(j.Provider, o.Id, "sell", pf, amount, tot)
-					return
-				}
-			}
-
-			log.Success(fmt.Sprintf("%s ORDER CREATED: %d", strings.ToUpper(j.Provider.Name), to.Number))
-			buyFee := o.Volume.Div(values.HundredFloat).Mul(&j.Fee)
-
-			go j.SaveOrder(&Order{
-				Id:     o.Id,
-				Volume: o.Volume,
-				Price:  o.Price,
-				Total:  o.Total,
-				Fee:    buyFee,
-				Side:   "buy",
-				Status: "filled",
-				Date:   time.Now(),
-			})
-
-			go j.NotifyOrder(pf, amt, tot, dif, "sell")
-		}
+		o := e.forget(to.Number)
+		o.Status = StatusFilled
+		o.Date = time.Now()
+		handler(&Event{Type: EventFilled, Symbol: o.Symbol, Order: o})
 	} else if to.Type == "c" && filled {
-		j.DetachOrder(o.Id)
+		o := e.forget(to.Number)
+		o.Status = StatusCanceled
+		handler(&Event{Type: EventCanceled, Symbol: o.Symbol, Order: o})
+	} else if to.Type == "f" || to.Type == "s" {
+		// The amount of a trade is the remaining volume of its order
+		e.mx.Lock()
+		o.setExecuted(o.Volume.Sub(&to.Amount))
+		partial := *o
+		e.mx.Unlock()
+		partial.Status = StatusPartial
+		handler(&Event{Type: EventPartial, Symbol: partial.Symbol, Order: &partial})
 	} else {
-		log.Info(fmt.Sprintf("%s ORDER UPDATE: %s %d @ %s - %.8f", strings.ToUpper(j.Provider.Name), j.Symbol, to.Number, to.Type, to.Amount.ToFloat()))
+		log.Info(fmt.Sprintf("%s ORDER UPDATE: %s %d @ %s - %.8f", strings.ToUpper(e.provider.Name), o.Symbol, to.Number, to.Type, to.Amount.ToFloat()))
+	}
+}
+
+// symbol returns the currency pair name ("BTC_DOGE") of a pair id.
+func (e *PoloniexExchange) symbol(id int) string {
+	for name, pair := range e.client.Pairs {
+		if pair.Id == id {
+			return name
+		}
 	}
+	return ""
 }
 
-func (j *Job) handleAccountUpdates(upd poloniex.AccountUpd, pair *poloniex.Pair) {
+func (e *PoloniexExchange) handleAccountUpdates(upd poloniex.AccountUpd, handler EventHandler) {
 	for _, no := range upd.NewOrders {
-		if no.Symbol == pair.Id {
-			j.AttachPolOrder(&no)
+		if symbol := e.symbol(no.Symbol); symbol != "" {
+			handler(&Event{Type: EventNew, Symbol: symbol, Order: e.remember(symbol, &no)})
 		}
 	}
 
 	for _, to := range upd.TradeOrders {
-		j.HandlePolTradeOrder(&to)
+		e.handleTradeOrder(&to, handler)
 	}
 }
 
-func (j *Job) WatchPolMarket() {
+func (e *PoloniexExchange) Watch(ctx context.Context, handler EventHandler) {
 	AcUpdChan := make(chan poloniex.AccountUpd, 128)
 	stopChan := make(chan bool)
 
-	pair := j.PoloniexClient.GetPair(j.Symbol)
-	if pair == nil {
-		return
-	}
-
 	go func() {
 		for upd := range AcUpdChan {
-			go j.handleAccountUpdates(upd, pair)
+			if upd.Subscribed {
+				handler(&Event{Type: EventConnected})
+			}
+			e.handleAccountUpdates(upd, handler)
 		}
 	}()
-
 	go func() {
-		for {
-			log.Success(fmt.Sprintf("Subscribing to %s account update events..", strings.ToUpper(j.Provider.Name)))
-			if err := j.PoloniexClient.SubscribeAccount(AcUpdChan, stopChan); err != nil {
-				log.Error("client: sub error: %v", err)
-				time.Sleep(time.Second)
-			}
-		}
+		<-ctx.Done()
+		close(stopChan)
 	}()
 
-	for {
-		select {
-		case <-stopChan:
-			return
+	for ctx.Err() == nil {
+		log.Success(fmt.Sprintf("Subscribing to %s account update events..", strings.ToUpper(e.provider.Name)))
+		if err := e.client.SubscribeAccount(AcUpdChan, stopChan); err != nil {
+			log.Error("client: sub error: %v", err)
+			sleep(ctx, time.Second)
 		}
 	}
+
+	if err := e.client.Close(); err != nil {
+		log.Error(err)
+	}
 }
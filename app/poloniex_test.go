@@ -0,0 +1,53 @@
+package app
+
+import (
+	"../api/poloniex"
+	"../utils/values"
+	"testing"
+)
+
+func TestPoloniexClientId(t *testing.T) {
+	clientId := counterOrderId("1")
+	tests := []struct {
+		name     string
+		placed   string
+		cid      int64
+		clientId string
+	}{
+		{"placed", clientId, 0, clientId},
+		{"unknown client id", clientId, 0x123, ""},
+		{"without client id", "", 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &PoloniexExchange{
+				provider:  &Provider{Name: "poloniex-test"},
+				client:    &poloniex.Config{Pairs: map[string]*poloniex.Pair{"BTC_DOGE": {Id: 148}}},
+				orders:    make(map[int64]*Order),
+				clientIds: make(map[int64]string),
+			}
+			cid := e.clientOrderId(tt.placed)
+			if tt.cid != 0 {
+				cid = tt.cid
+			}
+
+			events := make([]*Event, 0)
+			e.handleAccountUpdates(poloniex.AccountUpd{NewOrders: []poloniex.OpenOrder{{
+				OrderNumber:   6083059,
+				Symbol:        148,
+				Type:          SideBuy,
+				Rate:          *values.NewFloatFromString("0.00000300"),
+				Amount:        *values.NewFloatFromString("30"),
+				ClientOrderId: cid,
+			}}}, func(evt *Event) { events = append(events, evt) })
+
+			if len(events) != 1 || events[0].Type != EventNew {
+				t.Fatalf("events = %d", len(events))
+			}
+			if o := events[0].Order; o.Id != "6083059" || o.ClientId != tt.clientId || o.Symbol != "BTC_DOGE" {
+				t.Errorf("order = %s %s %s", o.Id, o.ClientId, o.Symbol)
+			}
+		})
+	}
+}
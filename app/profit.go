@@ -0,0 +1,63 @@
+package app
+
+import (
+	"../utils/values"
+)
+
+const (
+	// ProfitQuote sells the whole bought volume, the profit ends up in the
+	// quote asset.
+	ProfitQuote = "quote"
+	// ProfitBase sells just enough of the bought volume to recover the buy
+	// total and the fees, the profit ends up in the base asset.
+	ProfitBase = "base"
+)
+
+// baseSellAmount returns the amount of a filled buy order which has to be sold
+// at the given price in order to recover the buy total including the fees.
+// It's rounded up to the lot size, the rest of the bought volume is kept. The
+// whole volume gets sold if the kept part would be too small.
+func (j *Job) baseSellAmount(o *Order, price *values.Float, available *values.Float) (*values.Float, bool) {
+	if j.ProfitAsset != ProfitBase {
+		return nil, false
+	}
+
+	net := price.Mul(values.HundredFloat.Sub(&j.Fee)).Div(values.HundredFloat)
+	amount := o.Volume.Mul(o.Price).Div(net)
+
+	rounded := j.validateAmount(amount)
+	if rounded.Lt(amount) {
+		rounded = rounded.Add(j.getFilter().StepSize)
+	}
+	if !rounded.Lt(j.validateAmount(available)) || !j.counterable(rounded, price) {
+		return nil, false
+	}
+	return rounded, true
+}
+
+// sellProfit estimates the profit of a filled sell order, whose buy order
+// got filled one step below. The profit is given in the profit asset of the
+// job.
+func (j *Job) sellProfit(o *Order) *values.Float {
+	sellTotal := o.Volume.Mul(o.Price)
+
+	buyRate := o.Price.Sub(j.getStep(SideSell))
+	if pct := j.getStepPercent(SideSell); pct.Gt(values.ZeroFloat) {
+		buyRate = o.Price.Mul(values.HundredFloat).Div(values.HundredFloat.Add(pct))
+	}
+
+	sellFee := sellTotal.Div(values.HundredFloat).Mul(&j.Fee)
+
+	if j.ProfitAsset == ProfitBase {
+		// The sell total recovered the buy total, the rest of the bought
+		// volume got kept
+		buyAmount := sellTotal.Sub(sellFee).Div(buyRate)
+		buyFee := buyAmount.Div(values.HundredFloat).Mul(&j.Fee)
+		return buyAmount.Sub(buyFee).Sub(o.Volume)
+	}
+
+	buyTotal := o.Volume.Mul(buyRate)
+	buyFee := buyTotal.Div(values.HundredFloat).Mul(&j.Fee)
+
+	return sellTotal.Sub(buyTotal).Sub(sellFee).Sub(buyFee)
+}
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"../utils/values"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBaseSellAmount(t *testing.T) {
+	// A buy of 10 at 10 gets sold at 11
+	tests := []struct {
+		name        string
+		profit      string
+		fee         string
+		stepSize    string
+		minNotional string
+		available   string
+		amount      string
+	}{
+		{"quote", ProfitQuote, "0", "0.00000001", "0", "10", ""},
+		{"base", ProfitBase, "0", "0.00000001", "0", "10", "9.09090910"},
+		{"base with fee", ProfitBase, "1", "0.00000001", "0", "10", "9.18273646"},
+		// Rounded up to the lot size nothing would be kept
+		{"lot size", ProfitBase, "0", "1", "0", "10", ""},
+		{"lot size kept", ProfitBase, "0", "0.1", "0", "10", "9.10000000"},
+		{"too little available", ProfitBase, "0", "0.00000001", "0", "9.09", ""},
+		{"below the minimum notional", ProfitBase, "0", "0.00000001", "101", "10", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := newTestJob(tt.fee)
+			j.ProfitAsset = tt.profit
+			f := DefaultFilter()
+			f.StepSize = values.NewFloatFromString(tt.stepSize)
+			f.MinNotional = values.NewFloatFromString(tt.minNotional)
+			j.setFilter(f)
+
+			o := &Order{Side: SideBuy, Volume: values.NewFloatFromString("10"), Price: values.NewFloatFromString("10")}
+			amount, ok := j.baseSellAmount(o, values.NewFloatFromString("11"), values.NewFloatFromString(tt.available))
+			if ok != (tt.amount != "") {
+				t.Fatalf("ok = %v", ok)
+			}
+			if ok && amount.ToString() != tt.amount {
+				t.Errorf("amount = %s, want %s", amount.ToString(), tt.amount)
+			}
+		})
+	}
+}
+
+func TestSellProfit(t *testing.T) {
+	tests := []struct {
+		name   string
+		profit string
+		fee    string
+		pct    bool
+		volume string
+		want   string
+	}{
+		{"quote", ProfitQuote, "0", false, "10", "10.00000000"},
+		{"quote with fee", ProfitQuote, "1", false, "10", "7.90000000"},
+		{"quote percent step", ProfitQuote, "0", true, "10", "10.00000000"},
+		// The rest of the 10 bought is kept
+		{"base", ProfitBase, "0", false, "9.0909091", "0.90909091"},
+		{"base with fee", ProfitBase, "1", false, "9.18273646", "0.71726354"},
+		{"base percent step", ProfitBase, "0", true, "9.0909091", "0.90909091"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := newTestJob(tt.fee)
+			j.ProfitAsset = tt.profit
+			if tt.pct {
+				// 10% above 10 is 11 as well
+				j.Step = *values.NewEmptyFloat()
+				j.StepPercent = *values.NewFloatFromString("10")
+			}
+
+			o := &Order{Side: SideSell, Volume: values.NewFloatFromString(tt.volume), Price: values.NewFloatFromString("11")}
+			if got := fmt.Sprintf("%.8f", j.sellProfit(o).ToFloat()); got != tt.want {
+				t.Errorf("profit = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlaceSellOrderBase(t *testing.T) {
+	j, ex := newBoundsJob(t, "100")
+	j.ProfitAsset = ProfitBase
+
+	j.placeSellOrder(&Order{Id: "b1", Symbol: j.Symbol, Side: SideBuy, Volume: values.NewFloatFromString("10"), Price: values.NewFloatFromString("10"), Status: StatusFilled, Date: time.Now()})
+
+	orders, _ := ex.GetOpenOrders(j.Symbol)
+	if len(orders) != 1 {
+		t.Fatalf("orders = %d, want 1", len(orders))
+	}
+	if o := orders[0]; o.Side != SideSell || o.Volume.ToString() != "9.09090910" || o.Price.ToString() != "11.00000000" {
+		t.Errorf("sell order = %s %s @ %s", o.Side, o.Volume.ToString(), o.Price.ToString())
+	}
+}
+
+func TestSavedOrderTotal(t *testing.T) {
+	// The saved fill carries the total of its counter order
+	tests := []struct {
+		name  string
+		side  string
+		total string
+	}{
+		{"buy", SideBuy, "110.00000000"},
+		// 100 BTC buy 11.11111111 DOGE at 9
+		{"sell", SideSell, "99.99999999"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j, ex := newBoundsJob(t, "100")
+			ex.Balances["BTC"] = values.NewFloatFromString("1000")
+
+			j.counter(&Order{Id: "f1", Symbol: j.Symbol, Side: tt.side, Volume: values.NewFloatFromString("10"), Price: values.NewFloatFromString("10"), Status: StatusFilled, Date: time.Now()}, "f1", values.NewFloatFromString("10"))
+
+			orders := j.loadOrders(j.CurrentOrderDir())
+			if len(orders) != 1 {
+				t.Fatalf("saved orders = %d, want 1", len(orders))
+			}
+			if total := orders[0].Total.ToString(); total != tt.total {
+				t.Errorf("total = %s, want %s", total, tt.total)
+			}
+		})
+	}
+}
@@ -1,23 +1,89 @@
 package app
 
 import (
+	"../api/bybit"
+	"../api/coinbase"
+	"../api/kraken"
+	"../api/kucoin"
+	"../api/okx"
 	"../api/poloniex"
+	"../utils/limiter"
 	"../utils/log"
-	"context"
+	"../utils/values"
+	"errors"
+	"fmt"
 	"github.com/adshao/go-binance/v2"
+	"net/http"
+	"strings"
 	"time"
 )
 
+// testnets contains the REST and websocket endpoints of every exchange which
+// offers a spot testnet. OKX demo trading uses the production REST host.
+var testnets = map[string][2]string{
+	"binance": {"https://testnet.binance.vision", "wss://testnet.binance.vision/ws"},
+	"bybit":   {"https://api-testnet.bybit.com", "wss://stream-testnet.bybit.com/v5/private"},
+	"okx":     {"https://www.okx.com", "wss://wspap.okx.com:8443/ws/v5/private"},
+}
+
 type Provider struct {
 	Name     string `json:"name"`
 	Exchange string `json:"exchange"`
 	Key      string `json:"key"`
 	Secret   string `json:"secret"`
+
+	RestEndpoint      string `json:"rest-endpoint"`
+	WebsocketEndpoint string `json:"wss-endpoint"`
+	Testnet           bool   `json:"testnet"`
+
+	// KuCoin and OKX only
+	Passphrase string `json:"passphrase"`
+
+	// Binance and Bybit only, receive window of signed requests in ms
+	RecvWindow int64 `json:"recv-window"`
+
+	// Paper trading only
+	Feed     string                   `json:"feed"`
+	Fee      values.Float             `json:"fee,string"`
+	Balances map[string]*values.Float `json:"balances"`
+	State    string                   `json:"state"`
+}
+
+// endpoints returns the REST and websocket endpoints the exchange client
+// should use instead of the given defaults.
+func (p *Provider) endpoints(rest string, wss string) (string, string, error) {
+	if p.Testnet {
+		t, ok := testnets[strings.ToLower(p.Exchange)]
+		if !ok {
+			return "", "", errors.New(fmt.Sprintf("%s doesn't offer a testnet", p.Exchange))
+		}
+		rest, wss = t[0], t[1]
+	}
+	if p.RestEndpoint != "" {
+		rest = p.RestEndpoint
+	}
+	if p.WebsocketEndpoint != "" {
+		wss = p.WebsocketEndpoint
+	}
+	return rest, wss, nil
+}
+
+// account identifies the exchange account of a provider. Providers sharing
+// an api key share its rate limits as well.
+func (p *Provider) account(rest string) string {
+	return fmt.Sprintf("%s:%s:%s", strings.ToLower(p.Exchange), rest, p.Key)
 }
 
 func (p *Provider) NewPoloniexClient() *poloniex.Config {
 	if p.Key != p.Secret {
 		client := poloniex.NewPoloniexApi(p.Key, p.Secret)
+		rest, wss, err := p.endpoints(client.RestEndpoint, client.WebsocketEndpoint)
+		if err != nil {
+			log.Error(err)
+			return nil
+		}
+		client.SetEndpoints(rest, wss)
+		client.Limiter = limiter.Shared(p.account(rest), client.Limiter)
 		if err := client.Setup(); err != nil {
 			log.Error(err)
 			return nil
@@ -27,25 +93,119 @@ func (p *Provider) NewPoloniexClient() *poloniex.Config {
 	return nil
 }
 
-func (p *Provider) NewBinanceClient() *binance.Client {
+func (p *Provider) NewKrakenClient() *kraken.Config {
+	if p.Key != p.Secret {
+		client := kraken.NewKrakenApi(p.Key, p.Secret)
+		rest, wss, err := p.endpoints(client.RestEndpoint, client.WebsocketEndpoint)
+		if err != nil {
+			log.Error(err)
+			return nil
+		}
+		client.RestEndpoint, client.WebsocketEndpoint = rest, wss
+		client.Limiter = limiter.Shared(p.account(rest), client.Limiter)
+		if err := client.Setup(); err != nil {
+			log.Error(err)
+			return nil
+		}
+		return client
+	}
+	return nil
+}
 
+func (p *Provider) NewKucoinClient() *kucoin.Config {
 	if p.Key != p.Secret {
-		client := binance.NewClient(p.Key, p.Secret)
+		client := kucoin.NewKucoinApi(p.Key, p.Secret, p.Passphrase)
+		rest, _, err := p.endpoints(client.RestEndpoint, "")
+		if err != nil {
+			log.Error(err)
+			return nil
+		}
+		client.RestEndpoint = rest
+		client.Limiter = limiter.Shared(p.account(rest), client.Limiter)
+		if err := client.Setup(); err != nil {
+			log.Error(err)
+			return nil
+		}
+		return client
+	}
+	return nil
+}
 
-		t, err := client.NewServerTimeService().Do(context.Background())
+func (p *Provider) NewCoinbaseClient() *coinbase.Config {
+	if p.Key != p.Secret {
+		client := coinbase.NewCoinbaseApi(p.Key, p.Secret)
+		rest, wss, err := p.endpoints(client.RestEndpoint, client.WebsocketEndpoint)
 		if err != nil {
 			log.Error(err)
-		} else {
-			nt := time.Now().Unix() * 1000
+			return nil
+		}
+		client.RestEndpoint, client.WebsocketEndpoint = rest, wss
+		client.Limiter = limiter.Shared(p.account(rest), client.Limiter)
+		if err := client.Setup(); err != nil {
+			log.Error(err)
+			return nil
+		}
+		return client
+	}
+	return nil
+}
+
+func (p *Provider) NewOkxClient() *okx.Config {
+	if p.Key != p.Secret {
+		client := okx.NewOkxApi(p.Key, p.Secret, p.Passphrase)
+		rest, wss, err := p.endpoints(client.RestEndpoint, client.WebsocketEndpoint)
+		if err != nil {
+			log.Error(err)
+			return nil
+		}
+		client.RestEndpoint, client.WebsocketEndpoint = rest, wss
+		client.Simulated = p.Testnet
+		client.Limiter = limiter.Shared(p.account(rest), client.Limiter)
+		if err := client.Setup(); err != nil {
+			log.Error(err)
+			return nil
+		}
+		return client
+	}
+	return nil
+}
 
-			if nt > t {
-				client.TimeOffset = t - nt
-			} else {
-				client.TimeOffset = nt - t
-			}
+func (p *Provider) NewBybitClient() *bybit.Config {
+	if p.Key != p.Secret {
+		client := bybit.NewBybitApi(p.Key, p.Secret)
+		rest, wss, err := p.endpoints(client.RestEndpoint, client.WebsocketEndpoint)
+		if err != nil {
+			log.Error(err)
+			return nil
+		}
+		if p.RecvWindow > 0 {
+			client.RecvWindow = time.Duration(p.RecvWindow) * time.Millisecond
+		}
+		client.RestEndpoint, client.WebsocketEndpoint = rest, wss
+		client.Limiter = limiter.Shared(p.account(rest), client.Limiter)
+		if err := client.Setup(); err != nil {
+			log.Error(err)
+			return nil
+		}
+		return client
+	}
+	return nil
+}
+
+func (p *Provider) NewBinanceClient() *binance.Client {
 
-			return client
+	if p.Key != p.Secret {
+		client := binance.NewClient(p.Key, p.Secret)
+		rest, _, err := p.endpoints(client.BaseURL, binance.BaseWsMainURL)
+		if err != nil {
+			log.Error(err)
+			return nil
 		}
+		client.BaseURL = rest
+		l := limiter.Shared(p.account(rest), limiter.New(binanceLimits))
+		client.HTTPClient = &http.Client{Transport: l.Transport(nil, binanceWeight)}
+
+		return client
 	}
 
 	return nil
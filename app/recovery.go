@@ -0,0 +1,226 @@
+package app
+
+import (
+	"../utils/config"
+	"../utils/filesystem"
+	"../utils/log"
+	"../utils/values"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	// Time to wait after a (re)connect before the missed fills get recovered.
+	// Events which were already queued get delivered in the meantime.
+	recoveryDelay = 5 * time.Second
+
+	// Fills older than this can't be recovered from the trade history, since
+	// most exchanges limit its range.
+	recoveryWindow = 7 * 24 * time.Hour
+)
+
+// Recover compares the tracked orders with the open orders of the exchange.
+// Every tracked order which isn't open anymore got either filled or canceled
+// while no connection was established. The final status of such an order is
+// requested from the exchange: filled orders get countered as if the fill had
+// been received live, canceled orders get removed. Orders whose status is
+// unknown stay tracked.
+func (j *Job) Recover() {
+	j.mx.Lock()
+	if j.recovering {
+		j.mx.Unlock()
+		return
+	}
+	j.recovering = true
+	j.mx.Unlock()
+
+	defer func() {
+		j.mx.Lock()
+		j.recovering = false
+		j.mx.Unlock()
+	}()
+
+	if !sleep(j.ctx, recoveryDelay) {
+		return
+	}
+
+	tracked := j.trackedOrders()
+	if len(tracked) == 0 {
+		return
+	}
+
+	open, err := j.Exchange.GetOpenOrders(j.Symbol)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	for _, o := range open {
+		delete(tracked, o.Id)
+	}
+	if len(tracked) == 0 {
+		return
+	}
+
+	getter, ok := j.Exchange.(OrderGetter)
+	if !ok {
+		j.recoverTrades(tracked)
+		return
+	}
+
+	for id, o := range tracked {
+		// A live event might have handled the order in the meantime
+		if _, err := j.GetOrder(id); err != nil {
+			continue
+		}
+
+		current, err := getter.GetOrder(j.Symbol, id)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+
+		switch current.Status {
+		case StatusFilled:
+			log.Warn(fmt.Sprintf("%s ORDER RECOVERED: %s", strings.ToUpper(j.Provider.Name), id))
+			o.Status = StatusFilled
+			j.handleEvent(&Event{Type: EventFilled, Symbol: j.Symbol, Order: o})
+		case StatusCanceled:
+			canceled := *o
+			canceled.Status = StatusCanceled
+			if current.Executed != nil && current.Executed.Sign() > 0 {
+				// The executed volume of a partial fill gets countered
+				canceled.setExecuted(current.Executed)
+			}
+			j.handleEvent(&Event{Type: EventCanceled, Symbol: j.Symbol, Order: &canceled})
+		}
+	}
+}
+
+// recoverTrades recovers the fills of exchanges which don't return the status
+// of a closed order. Orders whose trades add up to their volume got filled,
+// the status of all other orders is unknown and they stay tracked.
+func (j *Job) recoverTrades(tracked map[string]*Order) {
+	since := time.Now()
+	for _, o := range tracked {
+		if o.Date.Before(since) {
+			since = o.Date
+		}
+	}
+	if limit := time.Now().Add(-recoveryWindow); since.Before(limit) {
+		since = limit
+	}
+
+	trades, err := j.Exchange.GetTrades(j.Symbol, since.Add(-time.Minute))
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	executed := make(map[string]*values.Float)
+	for _, t := range trades {
+		if v, ok := executed[t.OrderId]; ok {
+			executed[t.OrderId] = v.Add(t.Volume)
+		} else {
+			executed[t.OrderId] = t.Volume
+		}
+	}
+
+	for id, o := range tracked {
+		if _, err := j.GetOrder(id); err != nil {
+			continue
+		}
+
+		if v, ok := executed[id]; ok && !v.Lt(o.Volume) {
+			log.Warn(fmt.Sprintf("%s ORDER RECOVERED: %s", strings.ToUpper(j.Provider.Name), id))
+			o.Status = StatusFilled
+			j.handleEvent(&Event{Type: EventFilled, Symbol: j.Symbol, Order: o})
+		} else {
+			log.Warn(fmt.Sprintf("%s ORDER STATUS UNKNOWN: %s", strings.ToUpper(j.Provider.Name), id))
+		}
+	}
+}
+
+// markFilled remembers a filled order and reports whether it was unknown.
+// A fill can be delivered live as well as by a recovery, but must be
+// countered only once.
+func (j *Job) markFilled(id string) bool {
+	if j.simulate {
+		return true
+	}
+	now := time.Now()
+
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	for fid, t := range j.filled {
+		if now.Sub(t) > recoveryWindow {
+			delete(j.filled, fid)
+		}
+	}
+
+	if _, ok := j.filled[id]; ok {
+		return false
+	}
+	j.filled[id] = now
+	return true
+}
+
+func (j *Job) trackedOrders() map[string]*Order {
+	j.mx.Lock()
+	orders := make(map[string]*Order)
+	for id, o := range j.orders {
+		orders[id] = o
+	}
+	j.mx.Unlock()
+
+	return orders
+}
+
+func (j *Job) openOrdersFile() string {
+	return path.Join(j.OrderDir, "open", j.Id+".json")
+}
+
+// saveOpenOrders persists the tracked orders, which allows to recover fills
+// that happened while the bot wasn't running.
+func (j *Job) saveOpenOrders() {
+	if j.simulate {
+		return
+	}
+
+	j.saveMx.Lock()
+	defer j.saveMx.Unlock()
+
+	orders := j.trackedOrders()
+
+	d := path.Dir(j.openOrdersFile())
+	filesystem.CreateDirectory(d)
+
+	c := config.NewConfig()
+	c.RootDir = d
+	c.File = j.openOrdersFile()
+	c.Silent = true
+	c.SetContext(&orders)
+
+	_, _ = c.Save()
+}
+
+// loadOpenOrders attaches the orders tracked by a previous run.
+func (j *Job) loadOpenOrders() {
+	if j.simulate {
+		return
+	}
+
+	orders := make(map[string]*Order)
+
+	c := config.NewConfig()
+	c.File = j.openOrdersFile()
+	c.Silent = true
+	c.SetContext(&orders)
+	c.Load(j.openOrdersFile())
+
+	for _, o := range orders {
+		j.AttachOrder(o)
+	}
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"../utils/values"
+	"errors"
+	"testing"
+	"time"
+)
+
+// recoveryExchange is a paper account which reports the trades of orders
+// that got closed while no connection was established.
+type recoveryExchange struct {
+	*PaperExchange
+	trades []*Trade
+}
+
+func (e *recoveryExchange) GetTrades(symbol string, since time.Time) ([]*Trade, error) {
+	return e.trades, nil
+}
+
+// statusExchange additionally returns the final status of closed orders.
+type statusExchange struct {
+	*recoveryExchange
+	orders map[string]*Order
+}
+
+func (e *statusExchange) GetOrder(symbol string, id string) (*Order, error) {
+	if o, ok := e.orders[id]; ok {
+		return o, nil
+	}
+	return nil, errors.New("order not found")
+}
+
+func TestRecover(t *testing.T) {
+	defer func(d time.Duration) { recoveryDelay = d }(recoveryDelay)
+	recoveryDelay = 0
+
+	// A buy order of 30 DOGE at 10 got closed while the job was offline
+	tests := []struct {
+		name      string
+		getter    bool
+		status    string
+		executed  string
+		traded    []string
+		tracked   bool
+		countered string
+	}{
+		{"filled", true, StatusFilled, "30", nil, false, "30.00000000"},
+		{"canceled", true, StatusCanceled, "0", nil, false, ""},
+		{"partially filled and canceled", true, StatusCanceled, "10", nil, false, "10.00000000"},
+		{"still open", true, StatusPartial, "10", nil, true, ""},
+		{"status unknown", true, "", "", nil, true, ""},
+		{"trades filled", false, "", "", []string{"10", "20"}, false, "30.00000000"},
+		{"trades partial", false, "", "", []string{"10"}, true, ""},
+		{"no trades", false, "", "", nil, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j, paper := newBoundsJob(t, "100")
+			ex := &recoveryExchange{PaperExchange: paper}
+			for i, v := range tt.traded {
+				ex.trades = append(ex.trades, &Trade{Id: string(rune('a' + i)), OrderId: "1", Symbol: "DOGEBTC", Side: SideBuy, Price: values.NewFloatFromString("10"), Volume: values.NewFloatFromString(v)})
+			}
+			j.Exchange = ex
+			if tt.getter {
+				orders := make(map[string]*Order)
+				if tt.status != "" {
+					o := &Order{Id: "1", Symbol: "DOGEBTC", Volume: values.NewFloatFromString("30"), Price: values.NewFloatFromString("10"), Side: SideBuy, Status: tt.status}
+					o.setExecuted(values.NewFloatFromString(tt.executed))
+					orders["1"] = o
+				}
+				j.Exchange = &statusExchange{recoveryExchange: ex, orders: orders}
+			}
+
+			j.AttachOrder(&Order{Id: "1", Symbol: "DOGEBTC", Volume: values.NewFloatFromString("30"), Price: values.NewFloatFromString("10"), Side: SideBuy, Status: StatusNew, Date: time.Now()})
+			j.Recover()
+			j.work.Wait()
+
+			if _, err := j.GetOrder("1"); (err == nil) != tt.tracked {
+				t.Errorf("tracked = %v, want %v", err == nil, tt.tracked)
+			}
+			countered := ""
+			if orders, _ := paper.GetOpenOrders("DOGEBTC"); len(orders) == 1 {
+				countered = orders[0].Volume.ToString()
+			} else if len(orders) > 1 {
+				t.Fatalf("counter orders = %d", len(orders))
+			}
+			if countered != tt.countered {
+				t.Errorf("countered = %q, want %q", countered, tt.countered)
+			}
+		})
+	}
+}
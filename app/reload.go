@@ -0,0 +1,108 @@
+package app
+
+import (
+	"../utils/log"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Reload applies changes of the app config and the job directory without a
+// restart. New jobs get started, removed or disabled jobs get stopped and
+// changed jobs get restarted with their new parameters. Jobs of a changed
+// provider get restarted as well, notifiers get replaced in place. Neither
+// file is watched, changes only apply once Reload gets called.
+func (a *App) Reload() {
+	a.reloadMx.Lock()
+	defer a.reloadMx.Unlock()
+
+	a.mx.Lock()
+	ctx := a.ctx
+	current := a.config
+	running := a.jobs
+	a.mx.Unlock()
+
+	if ctx == nil || ctx.Err() != nil {
+		return
+	}
+	log.Info(fmt.Sprintf("Reloading %s..", current.File))
+
+	c := DefaultConfig()
+	if !c.Load(current.File) {
+		log.Error(fmt.Sprintf("%s couldn't be loaded, keeping the current configuration", current.File))
+		return
+	}
+	c.File = current.File
+	c.Build = current.Build
+	c.LogOutput = current.LogOutput
+
+	for _, n := range c.Notifier {
+		n.Init()
+	}
+
+	loaded, broken := c.loadJobs()
+
+	jobs := make(map[string]*Job)
+	stopped := make([]*Job, 0)
+	started := make([]*Job, 0)
+
+	for file, j := range running {
+		if _, ok := broken[file]; ok {
+			// A file which is being written keeps its job running
+			j.setNotifiers(c.getNotifiers(j.NotifierIds))
+			jobs[file] = j
+			continue
+		}
+
+		nj, ok := loaded[file]
+		if ok && nj.source == j.source && reflect.DeepEqual(current.getProvider(j.ProviderId), c.getProvider(nj.ProviderId)) {
+			j.setNotifiers(c.getNotifiers(j.NotifierIds))
+			jobs[file] = j
+			continue
+		}
+
+		if j.Enabled {
+			stopped = append(stopped, j)
+		}
+		if ok {
+			jobs[file] = nj
+			if nj.Enabled {
+				started = append(started, nj)
+			}
+		}
+	}
+	for file, nj := range loaded {
+		if _, ok := running[file]; ok {
+			continue
+		}
+		jobs[file] = nj
+		if nj.Enabled {
+			started = append(started, nj)
+		}
+	}
+
+	// The replacement of a job takes over its journal, which is why the old
+	// job has to finish its pending counter orders first.
+	sctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.ShutdownTimeout)*time.Second)
+	defer cancel()
+
+	for _, j := range stopped {
+		if err := j.Stop(sctx); err != nil {
+			log.Error(fmt.Sprintf("%s %s %s", strings.ToUpper(j.Provider.Name), strings.ToUpper(j.Symbol), err))
+		}
+		log.Warn(fmt.Sprintf("%s %s JOB STOPPED", strings.ToUpper(j.Provider.Name), strings.ToUpper(j.Symbol)))
+	}
+
+	a.mx.Lock()
+	a.config = c
+	a.jobs = jobs
+	for _, j := range started {
+		j.Start(ctx)
+		log.Success(fmt.Sprintf("%s %s JOB STARTED", strings.ToUpper(j.Provider.Name), strings.ToUpper(j.Symbol)))
+	}
+	a.mx.Unlock()
+
+	log.Success(fmt.Sprintf("Reloaded %d jobs, %d stopped and %d started", len(jobs), len(stopped), len(started)))
+}
@@ -0,0 +1,50 @@
+package app
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"testing"
+)
+
+func TestReloadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := DefaultConfig()
+	c.File = path.Join(dir, "app.json")
+	c.JobDir = path.Join(dir, "jobs")
+	if err := ioutil.WriteFile(c.File, []byte(`{"shutdown-timeout": 5}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a := NewApp(c)
+	a.ctx = context.Background()
+
+	// The config gets read while reloads replace it
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			a.Reload()
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if a.getConfig() == nil {
+			t.Fatal("config missing")
+		}
+	}
+	wg.Wait()
+
+	if a.getConfig() == c {
+		t.Error("config not replaced")
+	}
+	if a.getConfig().File != c.File {
+		t.Errorf("file = %s, want %s", a.getConfig().File, c.File)
+	}
+}
@@ -3,15 +3,34 @@ package main
 import (
 	"./app"
 	"./utils/log"
+	"bufio"
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 )
 
 var buildNumber string
 var buildVersion string
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "backtest":
+			backtest(os.Args[2:])
+			return
+		case "optimize":
+			optimize(os.Args[2:])
+			return
+		case "grid":
+			grid(os.Args[2:])
+			return
+		}
+	}
+
 	ac := app.DefaultConfig()
 	lc := log.DefaultConfig()
 
@@ -40,6 +59,157 @@ func main() {
 
 	_ = os.Setenv("TZ", ac.Timezone)
 
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		s := <-sig
+		// A second signal kills the process right away
+		signal.Stop(sig)
+		log.Warn(fmt.Sprintf("Received %s, shutting down..", s))
+		cancel()
+	}()
+
 	a := app.NewApp(ac)
-	a.Start()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			a.Reload()
+		}
+	}()
+
+	if err := a.Start(ctx); err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+}
+
+func backtest(args []string) {
+	bc := app.DefaultBacktestConfig()
+
+	fs := flag.NewFlagSet("backtest", flag.ExitOnError)
+	bc.AddFlags(fs)
+	_ = fs.Parse(args)
+
+	b, err := app.NewBacktestFromConfig(bc)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	r, err := b.Run()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	fmt.Print(r.String())
+}
+
+func optimize(args []string) {
+	oc := app.DefaultOptimizeConfig()
+
+	fs := flag.NewFlagSet("optimize", flag.ExitOnError)
+	oc.AddFlags(fs)
+	_ = fs.Parse(args)
+
+	o, err := app.NewOptimizer(oc)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	r, err := o.Run()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	fmt.Print(r.String(oc.Top))
+}
+
+func grid(args []string) {
+	if len(args) == 0 || (args[0] != "check" && args[0] != "place" && args[0] != "cancel") {
+		fmt.Println("Usage: sstb grid check --job <job file> [--apply]")
+		fmt.Println("       sstb grid place --job <job file> --low <price> --high <price> [--price <price>] [--yes]")
+		fmt.Println("       sstb grid cancel --job <job file> [--low <price>] [--high <price>] [--yes]")
+		os.Exit(1)
+	}
+
+	gc := app.DefaultGridConfig()
+
+	fs := flag.NewFlagSet("grid "+args[0], flag.ExitOnError)
+	gc.AddFlags(fs)
+	_ = fs.Parse(args[1:])
+
+	low, high, price, err := gc.Range()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if args[0] == "place" && (low == nil || high == nil) {
+		fmt.Println("the low and the high price are required")
+		os.Exit(1)
+	}
+
+	j, err := app.NewGridJob(gc)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "check":
+		r, err := j.Check(gc.Apply)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Print(r.String())
+	case "place":
+		p, err := j.PlanGrid(price, low, high)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Print(p.String())
+		if len(p.Orders) == 0 || !p.Sufficient() {
+			os.Exit(1)
+		}
+		if !gc.Yes && !confirm(fmt.Sprintf("Place %d orders?", len(p.Orders))) {
+			return
+		}
+
+		j.PlaceGrid(p)
+		fmt.Printf("%d orders placed, %d failed\n", p.Placed, p.Failed)
+	case "cancel":
+		c, err := j.PlanCancel(low, high)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Print(c.String())
+		if len(c.Orders) == 0 {
+			return
+		}
+		if !gc.Yes && !confirm(fmt.Sprintf("Cancel %d orders?", len(c.Orders))) {
+			return
+		}
+
+		j.CancelGrid(c)
+		fmt.Printf("%d orders canceled, %d failed\n", c.Canceled, c.Failed)
+	}
+}
+
+// confirm asks a yes or no question on the terminal.
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
 }
@@ -0,0 +1,240 @@
+package limiter
+
+import (
+	"../log"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	limiters   = make(map[string]*Limiter)
+	limitersMx = sync.Mutex{}
+)
+
+// Config describes the request limits of an exchange account.
+type Config struct {
+	// Name of the exchange used inside log messages
+	Name string
+	// Minimum time between two requests
+	Interval time.Duration
+
+	// Request weight allowed per window, 0 disables the weight limit
+	Weight       int
+	WeightWindow time.Duration
+	// Header containing the weight the exchange counted for the window
+	WeightHeader string
+
+	// Orders allowed per window, 0 disables the order limit
+	Orders      int
+	OrderWindow time.Duration
+	OrderHeader string
+
+	// Backoff if a rate limit response doesn't tell how long to wait
+	Backoff time.Duration
+}
+
+// Limiter throttles the requests of an exchange account. It counts the
+// weight of every request within a fixed window and blocks as soon as the
+// limit would be exceeded. Exchanges reporting the used weight are followed.
+type Limiter struct {
+	Config Config
+
+	next        time.Time
+	weight      int
+	weightReset time.Time
+	orders      int
+	orderReset  time.Time
+	until       time.Time
+	mx          sync.Mutex
+}
+
+// Weigher returns the weight of a request and whether it places an order.
+type Weigher func(r *http.Request) (int, bool)
+
+func New(c Config) *Limiter {
+	if c.Backoff == 0 {
+		c.Backoff = time.Minute
+	}
+	return &Limiter{
+		Config: c,
+		mx:     sync.Mutex{},
+	}
+}
+
+// Shared returns the limiter of the given account. The given limiter becomes
+// the limiter of the account if it doesn't have one yet. Every client of an
+// account has to use the same limiter, since the exchange counts requests per
+// account and IP instead of per connection.
+func Shared(account string, l *Limiter) *Limiter {
+	limitersMx.Lock()
+	defer limitersMx.Unlock()
+
+	if s, ok := limiters[account]; ok {
+		return s
+	}
+	limiters[account] = l
+	return l
+}
+
+// reservedKey marks the context of a request which waited already.
+type reservedKey struct{}
+
+// Wait blocks until a request of the given weight can be sent or the given
+// context expires.
+func (l *Limiter) Wait(ctx context.Context, weight int, order bool) error {
+	for {
+		d := l.reserve(weight, order)
+		if d <= 0 {
+			return nil
+		}
+
+		t := time.NewTimer(d)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// Reserved returns a context for a request which waited for the limiter
+// already, the transport sends it without waiting once more.
+func Reserved(ctx context.Context) context.Context {
+	return context.WithValue(ctx, reservedKey{}, true)
+}
+
+// reserve counts the request and returns zero if it can be sent right away.
+// Otherwise nothing is counted and the time to wait is returned.
+func (l *Limiter) reserve(weight int, order bool) time.Duration {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	now := time.Now()
+	if now.Before(l.until) {
+		return l.until.Sub(now)
+	}
+	if now.Before(l.next) {
+		return l.next.Sub(now)
+	}
+
+	if l.Config.Weight > 0 {
+		if !now.Before(l.weightReset) {
+			l.weight = 0
+			l.weightReset = window(now, l.Config.WeightWindow)
+		}
+		if l.weight > 0 && l.weight+weight > l.Config.Weight {
+			return l.weightReset.Sub(now)
+		}
+	}
+	if order && l.Config.Orders > 0 {
+		if !now.Before(l.orderReset) {
+			l.orders = 0
+			l.orderReset = window(now, l.Config.OrderWindow)
+		}
+		if l.orders >= l.Config.Orders {
+			return l.orderReset.Sub(now)
+		}
+	}
+
+	l.weight += weight
+	if order {
+		l.orders++
+	}
+	l.next = now.Add(l.Config.Interval)
+	return 0
+}
+
+// Observe follows the usage reported by the exchange and pauses all requests
+// of the account if a rate limit got exceeded. A 418 is the answer to
+// requests which were sent after a 429 and comes with an IP ban.
+func (l *Limiter) Observe(res *http.Response) {
+	if res == nil {
+		return
+	}
+
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	now := time.Now()
+	if v, ok := header(res, l.Config.WeightHeader); ok {
+		if !now.Before(l.weightReset) {
+			l.weightReset = window(now, l.Config.WeightWindow)
+		}
+		l.weight = v
+	}
+	if v, ok := header(res, l.Config.OrderHeader); ok {
+		if !now.Before(l.orderReset) {
+			l.orderReset = window(now, l.Config.OrderWindow)
+		}
+		l.orders = v
+	}
+
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusTeapot {
+		d := l.Config.Backoff
+		if v, ok := header(res, "Retry-After"); ok {
+			d = time.Duration(v) * time.Second
+		}
+		if until := now.Add(d); until.After(l.until) {
+			l.until = until
+			log.Warn(fmt.Sprintf("%s RATE LIMIT EXCEEDED: %s - pausing all requests for %s", strings.ToUpper(l.Config.Name), res.Status, d))
+		}
+	}
+}
+
+// Transport wraps an http transport in order to throttle every request sent
+// through it.
+func (l *Limiter) Transport(base http.RoundTripper, weigh Weigher) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base, limiter: l, weigh: weigh}
+}
+
+type transport struct {
+	base    http.RoundTripper
+	limiter *Limiter
+	weigh   Weigher
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Context().Value(reservedKey{}) == nil {
+		weight, order := 1, false
+		if t.weigh != nil {
+			weight, order = t.weigh(r)
+		}
+		if err := t.limiter.Wait(r.Context(), weight, order); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := t.base.RoundTrip(r)
+	if err == nil {
+		t.limiter.Observe(res)
+	}
+	return res, err
+}
+
+// window returns the end of the fixed window the given time belongs to.
+func window(now time.Time, d time.Duration) time.Time {
+	if d <= 0 {
+		d = time.Minute
+	}
+	return now.Truncate(d).Add(d)
+}
+
+func header(res *http.Response, name string) (int, bool) {
+	if name == "" {
+		return 0, false
+	}
+	v, err := strconv.Atoi(res.Header.Get(name))
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
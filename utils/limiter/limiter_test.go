@@ -0,0 +1,194 @@
+package limiter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReserveWeight(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights []int
+		waits   []bool
+	}{
+		{"within limit", []int{6, 4}, []bool{false, false}},
+		{"exceeded", []int{6, 4, 1}, []bool{false, false, true}},
+		// A request heavier than the limit is sent within an empty window
+		{"heavy request", []int{20, 1}, []bool{false, true}},
+		{"rejected request isn't counted", []int{6, 5, 4}, []bool{false, true, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(Config{Weight: 10, WeightWindow: time.Hour})
+			for i, w := range tt.weights {
+				d := l.reserve(w, false)
+				if (d > 0) != tt.waits[i] {
+					t.Errorf("request %d: wait = %s", i, d)
+				}
+				if d > time.Hour {
+					t.Errorf("request %d: wait = %s, longer than the window", i, d)
+				}
+			}
+		})
+	}
+}
+
+func TestReserveWindowReset(t *testing.T) {
+	l := New(Config{Weight: 10, WeightWindow: time.Hour, Orders: 1, OrderWindow: time.Hour})
+	if d := l.reserve(10, true); d != 0 {
+		t.Fatalf("wait = %s", d)
+	}
+	if d := l.reserve(1, true); d <= 0 {
+		t.Fatalf("wait = %s, want a full window", d)
+	}
+
+	// Both windows passed
+	l.weightReset = time.Now().Add(-time.Second)
+	l.orderReset = time.Now().Add(-time.Second)
+	if d := l.reserve(1, true); d != 0 {
+		t.Errorf("wait = %s after the windows passed", d)
+	}
+	if l.weight != 1 || l.orders != 1 {
+		t.Errorf("weight = %d, orders = %d, want 1, 1", l.weight, l.orders)
+	}
+}
+
+func TestReserveOrders(t *testing.T) {
+	l := New(Config{Orders: 2, OrderWindow: time.Hour})
+	for i := 0; i < 2; i++ {
+		if d := l.reserve(1, true); d != 0 {
+			t.Fatalf("order %d: wait = %s", i, d)
+		}
+	}
+	if d := l.reserve(1, true); d <= 0 {
+		t.Errorf("third order: wait = %s", d)
+	}
+	// Other requests aren't limited by the order count
+	if d := l.reserve(1, false); d != 0 {
+		t.Errorf("request: wait = %s", d)
+	}
+}
+
+func TestReserveInterval(t *testing.T) {
+	l := New(Config{Interval: time.Hour})
+	if d := l.reserve(1, false); d != 0 {
+		t.Fatalf("wait = %s", d)
+	}
+	if d := l.reserve(1, false); d <= time.Hour-time.Minute {
+		t.Errorf("wait = %s, want the interval", d)
+	}
+}
+
+func TestObserve(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		header map[string]string
+		min    time.Duration
+		max    time.Duration
+	}{
+		{"ok", http.StatusOK, nil, 0, 0},
+		{"retry after", http.StatusTooManyRequests, map[string]string{"Retry-After": "30"}, 29 * time.Second, 30 * time.Second},
+		{"backoff", http.StatusTooManyRequests, nil, 119 * time.Second, 120 * time.Second},
+		{"banned", http.StatusTeapot, map[string]string{"Retry-After": "600"}, 599 * time.Second, 600 * time.Second},
+		{"invalid retry after", http.StatusTooManyRequests, map[string]string{"Retry-After": "soon"}, 119 * time.Second, 120 * time.Second},
+		// The window is used up according to the exchange
+		{"used weight", http.StatusOK, map[string]string{"X-Used-Weight": "1200"}, time.Nanosecond, time.Hour},
+		{"used weight below limit", http.StatusOK, map[string]string{"X-Used-Weight": "1000"}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(Config{Name: "test", Weight: 1200, WeightWindow: time.Hour, WeightHeader: "X-Used-Weight", Backoff: 2 * time.Minute})
+			res := &http.Response{StatusCode: tt.status, Status: http.StatusText(tt.status), Header: http.Header{}}
+			for k, v := range tt.header {
+				res.Header.Set(k, v)
+			}
+
+			l.Observe(res)
+			if d := l.reserve(1, false); d < tt.min || d > tt.max {
+				t.Errorf("wait = %s, want %s - %s", d, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Used-Weight", "7")
+		w.Header().Set("X-Order-Count", "3")
+	}))
+	defer srv.Close()
+
+	l := New(Config{Weight: 100, WeightWindow: time.Hour, WeightHeader: "X-Used-Weight", Orders: 10, OrderWindow: time.Hour, OrderHeader: "X-Order-Count"})
+	weighed := 0
+	client := &http.Client{Transport: l.Transport(nil, func(r *http.Request) (int, bool) {
+		weighed++
+		return 5, r.Method == http.MethodPost
+	})}
+
+	res, err := client.Post(srv.URL, "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+
+	// The counts reported by the exchange replace the own counts
+	if weighed != 1 || l.weight != 7 || l.orders != 3 {
+		t.Errorf("weighed = %d, weight = %d, orders = %d, want 1, 7, 3", weighed, l.weight, l.orders)
+	}
+}
+
+func TestShared(t *testing.T) {
+	a := Shared("test-account", New(Config{}))
+	if b := Shared("test-account", New(Config{})); b != a {
+		t.Error("the account got a second limiter")
+	}
+	if c := Shared("test-other", New(Config{})); c == a {
+		t.Error("accounts share a limiter")
+	}
+}
+
+func TestWaitContext(t *testing.T) {
+	l := New(Config{Weight: 10, WeightWindow: time.Hour})
+	if err := l.Wait(context.Background(), 10, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// The window is used up, the wait ends with the context
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx, 1, false); err != context.DeadlineExceeded {
+		t.Errorf("error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if l.weight != 10 {
+		t.Errorf("weight = %d, want 10", l.weight)
+	}
+}
+
+func TestTransportReserved(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	l := New(Config{Weight: 100, WeightWindow: time.Hour})
+	client := &http.Client{Transport: l.Transport(nil, func(r *http.Request) (int, bool) {
+		return 5, false
+	})}
+
+	// A request which waited already isn't counted twice
+	for _, ctx := range []context.Context{context.Background(), Reserved(context.Background())} {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+	}
+	if l.weight != 5 {
+		t.Errorf("weight = %d, want 5", l.weight)
+	}
+}
@@ -116,3 +116,19 @@ func (f *Float) Div(other *Float) *Float {
 	}
 	return f.Quo(other)
 }
+
+// Truncate rounds the value down to the nearest multiple of step.
+func (f *Float) Truncate(step *Float) *Float {
+	if step.Eq(ZeroFloat) {
+		return NewFloat(&f.Float)
+	}
+	v, ok := new(big.Rat).SetString(f.Text('f', 16))
+	s, sok := new(big.Rat).SetString(step.Text('f', 16))
+	if !ok || !sok || s.Sign() == 0 {
+		return NewFloat(&f.Float)
+	}
+	q := new(big.Int).Quo(new(big.Int).Mul(v.Num(), s.Denom()), new(big.Int).Mul(v.Denom(), s.Num()))
+	r := new(big.Rat).Mul(new(big.Rat).SetInt(q), s)
+	fl, _ := new(big.Float).SetString(r.FloatString(16))
+	return NewFloat(fl)
+}